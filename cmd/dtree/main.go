@@ -1,22 +1,31 @@
 // Package main implements a small CLI for the dtree library
-// providing train, predict, and visualize commands.
+// providing train, predict, visualize and related commands.
 package main
 
 import (
 	"bufio"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/kerneldump/dtree/dtree"
 )
 
-// main dispatches to subcommands: train, predict, visualize.
+// main dispatches to subcommands: train, predict, visualize, print, info,
+// importance, leaves, convert, serve.
 func main() {
 	// Recover from panics to provide a clean error message
 	defer func() {
@@ -40,6 +49,22 @@ func main() {
 		predictCmd(args)
 	case "visualize":
 		visualizeCmd(args)
+	case "print":
+		printCmd(args)
+	case "info":
+		infoCmd(args)
+	case "importance":
+		importanceCmd(args)
+	case "leaves":
+		leavesCmd(args)
+	case "convert":
+		convertCmd(args)
+	case "serve":
+		serveCmd(args)
+	case "validate":
+		validateCmd(args)
+	case "compare":
+		compareCmd(args)
 	case "help", "-h", "--help":
 		usage()
 	default:
@@ -52,54 +77,208 @@ func main() {
 // usage prints a short command reference.
 func usage() {
 	fmt.Println("dtree commands:")
-	fmt.Println("  train     --in data.csv --out model.json --label label --format csv")
-	fmt.Println("  predict   --in data.csv --model model.json --out preds.jsonl [--csv] [--proba]")
-	fmt.Println("  visualize --model model.json --out tree.html [--dot tree.dot]")
+	fmt.Println("  train     --in data.csv --out model.json --label label --format csv [--criterion entropy|gini]")
+	fmt.Println("  predict   --in data.csv --model model.json --out preds.jsonl [--csv] [--proba] [--strict]")
+	fmt.Println("  visualize --model model.json --out tree.html [--dot tree.dot] [--svg tree.svg] [--render png|svg|pdf]")
+	fmt.Println("  print     --model model.json")
+	fmt.Println("  info      --model model.json [--json]")
+	fmt.Println("  importance --model model.json [--data data.csv --label label] [--json]")
+	fmt.Println("  leaves    --model model.json [--out leaves.csv]")
+	fmt.Println("  convert   --in model.json --out model.gob|model.json.gz|tree.dot|tree.svg")
+	fmt.Println("  serve     --model model.json [--addr :8080]")
+	fmt.Println("  validate  --model model.json [--strict]")
+	fmt.Println("  compare   --a a.json --b b.json --in data.csv [--label label]")
+}
+
+// trainOptions holds the parsed arguments of the train command.
+type trainOptions struct {
+	in     string
+	out    string
+	report string // optional path for the trainReport
+	read   readOptions
+	cfg    dtree.Config
 }
 
 // trainCmd trains a decision tree from CSV or JSONL and writes a JSON model.
 func trainCmd(args []string) {
-	fs := flag.NewFlagSet("train", flag.ExitOnError)
-	// --in: path to CSV/JSONL; --format: csv|jsonl
-	in := fs.String("in", "", "input file (csv or jsonl)")
+	opts, err := parseTrainFlags(flag.NewFlagSet("train", flag.ExitOnError), args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := runTrain(opts, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// runTrain reads the training data, trains and saves the model, writes the
+// report if one was requested and prints model statistics to w.
+func runTrain(opts trainOptions, w io.Writer) error {
+	set, err := readTrainingSet(opts.in, opts.read, opts.cfg.CategoryAttr, opts.cfg.WeightAttr)
+	if err != nil {
+		return fmt.Errorf("failed to read training data: %w", err)
+	}
+	start := time.Now()
+	model, err := dtree.Train(set, opts.cfg)
+	if err != nil {
+		return fmt.Errorf("training failed: %w", err)
+	}
+	elapsed := time.Since(start)
+	if err := model.SaveJSON(opts.out); err != nil {
+		return fmt.Errorf("failed to save model: %w", err)
+	}
+	if opts.report != "" {
+		if err := writeTrainReport(opts.report, model, len(set), elapsed); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+
+	if model.Metadata != nil {
+		for _, warn := range model.Metadata.Warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", warn)
+		}
+	}
+
+	// Print success message and model statistics
+	fmt.Fprintf(w, "Model trained successfully and saved to %s\n", opts.out)
+	printStats(w, model.Stats())
+	return nil
+}
+
+// trainReport is the --report record of a training run, written next to the
+// model so the run can be reproduced and compared.
+type trainReport struct {
+	// Config is the resolved config the model was trained with.
+	Config       dtree.Config       `json:"config"`
+	Stats        dtree.ModelStats   `json:"stats"`
+	TrainingRows int                `json:"trainingRows"`
+	Importance   map[string]float64 `json:"importance"`
+	TrainSeconds float64            `json:"trainSeconds"`
+}
+
+// writeTrainReport writes model's trainReport to path as indented JSON.
+func writeTrainReport(path string, model *dtree.Model, rows int, elapsed time.Duration) error {
+	report := trainReport{
+		Config:       model.Config,
+		Stats:        model.Stats(),
+		TrainingRows: rows,
+		Importance:   model.FeatureImportance(),
+		TrainSeconds: elapsed.Seconds(),
+	}
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// parseTrainFlags parses train arguments and validates the resulting config
+// before any data is read.
+func parseTrainFlags(fs *flag.FlagSet, args []string) (trainOptions, error) {
+	// --in: path to CSV/JSONL; --format: csv|tsv|jsonl
+	in := fs.String("in", "", "input file (csv, tsv or jsonl)")
 	out := fs.String("out", "model.json", "output model JSON file")
-	format := fs.String("format", "csv", "input format: csv|jsonl")
+	report := fs.String("report", "", "also write a JSON report of the config, stats, importance and timing")
+	rf := addReadFlags(fs)
 	// --label: target column name
 	label := fs.String("label", "label", "label column name")
+	// --weight-col: per-row sample weights, never used as a feature
+	weightCol := fs.String("weight-col", "", "column holding each row's non-negative sample weight")
 	// Optional stopping criteria
 	maxDepth := fs.Int("maxDepth", 0, "max depth (0=unlimited)")
 	minSamples := fs.Int("minSamples", 0, "min samples per node (0=none)")
-	fs.Parse(args)
+	minSamplesLeaf := fs.Int("minSamplesLeaf", 0, "min samples per child of a split (0=none)")
+	chiSquare := fs.Float64("chiSquarePValue", 0, "keep only splits significant at this chi-square p-value (0=off)")
+	// Split search
+	criterion := fs.String("criterion", "entropy", "split criterion: entropy|gini")
+	maxFeatures := fs.Int("maxFeatures", 0, "attributes sampled per node (0=all)")
+	maxThresholds := fs.Int("maxThresholds", 0, "quantile pivots tried per numeric attribute (0=all)")
+	maxCategories := fs.Int("maxCategories", 0, "skip categorical attributes with more distinct values (0=no limit)")
+	histogramBins := fs.Int("histogramBins", 0, "pre-bin numeric attributes into this many bins for split search (0=exact)")
+	seed := fs.Int64("seed", 0, "random seed for randomized options")
+	multiway := fs.Bool("multiway", false, "split categorical attributes into one child per value")
+	subset := fs.Bool("subset", false, "also split categorical attributes on groups of values")
+	oblique := fs.Bool("oblique", false, "also try splits on linear combinations of two numeric attributes")
+	// --missing: how predictions route items lacking a split attribute
+	missing := fs.String("missing", "majority", "missing-value strategy: majority|match|nomatch|fail")
+	unseen := fs.Bool("unseen-as-missing", false, "route categorical values a node never saw in training by --missing")
+	verbose := fs.Bool("verbose", false, "log each node's split or stopping reason to stderr")
+	// Sampling, for quick models from large files
+	limit := fs.Int("limit", 0, "train on at most this many rows, sampled uniformly with --seed (0=all)")
+	sampleFrac := fs.Float64("sample-frac", 1, "train on a random fraction of the rows, in (0,1], sampled with --seed")
+	if err := fs.Parse(args); err != nil {
+		return trainOptions{}, err
+	}
 
 	if *in == "" {
-		fmt.Fprintln(os.Stderr, "--in is required")
-		os.Exit(1)
+		return trainOptions{}, errors.New("--in is required")
 	}
-	set, err := readTrainingSet(*in, *format, *label)
+	read, err := rf.options()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to read training data: %v\n", err)
-		os.Exit(1)
+		return trainOptions{}, err
 	}
-	cfg := dtree.Config{CategoryAttr: *label, Criterion: "entropy", MaxDepth: *maxDepth, MinSamples: *minSamples}
-	model, err := dtree.Train(set, cfg)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "training failed: %v\n", err)
-		os.Exit(1)
+	if *limit < 0 {
+		return trainOptions{}, fmt.Errorf("--limit must not be negative, got %d", *limit)
 	}
-	if err := model.SaveJSON(*out); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to save model: %v\n", err)
-		os.Exit(1)
+	if *sampleFrac <= 0 || *sampleFrac > 1 {
+		return trainOptions{}, fmt.Errorf("--sample-frac must be in (0,1], got %v", *sampleFrac)
+	}
+	read.sample = sampleOptions{limit: *limit, frac: *sampleFrac, seed: *seed}
+	opts := trainOptions{
+		in:     *in,
+		out:    *out,
+		report: *report,
+		read:   read,
+		cfg: dtree.Config{
+			CategoryAttr:       *label,
+			WeightAttr:         *weightCol,
+			Criterion:          *criterion,
+			MaxDepth:           *maxDepth,
+			MinSamples:         *minSamples,
+			MinSamplesLeaf:     *minSamplesLeaf,
+			ChiSquarePValue:    *chiSquare,
+			MaxFeatures:        *maxFeatures,
+			MaxThresholds:      *maxThresholds,
+			MaxCategories:      *maxCategories,
+			HistogramBins:      *histogramBins,
+			Seed:               *seed,
+			MultiwaySplits:     *multiway,
+			SubsetSplits:       *subset,
+			AllowObliqueSplits: *oblique,
+			MissingStrategy:    *missing,
+			UnseenAsMissing:    *unseen,
+			Verbose:            *verbose,
+			IgnoredAttributes:  read.ignored(),
+		},
+	}
+	if err := opts.cfg.Validate(); err != nil {
+		return trainOptions{}, fmt.Errorf("invalid training options: %w", err)
+	}
+	return opts, nil
+}
+
+// printStats writes a human-readable summary of model statistics.
+func printStats(w io.Writer, stats dtree.ModelStats) {
+	fmt.Fprintf(w, "Model statistics:\n")
+	fmt.Fprintf(w, "  Tree depth: %d\n", stats.TreeDepth)
+	fmt.Fprintf(w, "  Total nodes: %d\n", stats.TotalNodes)
+	fmt.Fprintf(w, "  Leaf nodes: %d\n", stats.LeafNodes)
+	fmt.Fprintf(w, "  Internal nodes: %d\n", stats.InternalNodes)
+	fmt.Fprintf(w, "  Classes: %d\n", len(stats.Classes))
+	if len(stats.NodesPerDepth) > 0 {
+		fmt.Fprintf(w, "  Nodes per depth: %s\n", joinInts(stats.NodesPerDepth))
+		fmt.Fprintf(w, "  Leaves per depth: %s\n", joinInts(stats.LeavesPerDepth))
 	}
+}
 
-	// Print success message and model statistics
-	fmt.Printf("Model trained successfully and saved to %s\n", *out)
-	stats := model.Stats()
-	fmt.Printf("Model statistics:\n")
-	fmt.Printf("  Tree depth: %d\n", stats.TreeDepth)
-	fmt.Printf("  Total nodes: %d\n", stats.TotalNodes)
-	fmt.Printf("  Leaf nodes: %d\n", stats.LeafNodes)
-	fmt.Printf("  Internal nodes: %d\n", stats.InternalNodes)
-	fmt.Printf("  Classes: %d\n", len(stats.Classes))
+// joinInts formats counts as a space-separated list.
+func joinInts(ns []int) string {
+	parts := make([]string, len(ns))
+	for i, n := range ns {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, " ")
 }
 
 // predictCmd reads data and a JSON model, then outputs predictions.
@@ -107,30 +286,47 @@ func trainCmd(args []string) {
 func predictCmd(args []string) {
 	fs := flag.NewFlagSet("predict", flag.ExitOnError)
 	// --in/--format: input data; --model: trained model path
-	in := fs.String("in", "", "input file (csv or jsonl)")
+	in := fs.String("in", "", "input file (csv, tsv or jsonl)")
 	modelPath := fs.String("model", "", "model JSON file")
 	out := fs.String("out", "", "output file (default stdout)")
-	format := fs.String("format", "csv", "input format: csv|jsonl")
+	rf := addReadFlags(fs)
 	// --csv: output as CSV; --proba: include class probabilities
 	asCSV := fs.Bool("csv", false, "output CSV mirroring input")
 	proba := fs.Bool("proba", false, "include probabilities in output")
-	// --label for CSV header passthrough
-	label := fs.String("label", "label", "label column name (for CSV header passthrough)")
+	// --strict: fail on rows missing an attribute the tree splits on
+	strict := fs.Bool("strict", false, "error on rows missing a split attribute")
+	// --leaf-strategy proportional draws each class from the leaf counts
+	leafStrategy := fs.String("leaf-strategy", "", "leaf prediction: majority|proportional (default: the model's)")
+	seed := fs.Int64("seed", 0, "seed for --leaf-strategy proportional draws (default: the model's)")
+	// --label is accepted for compatibility; CSV headers are mirrored as read
+	fs.String("label", "label", "label column name (for CSV header passthrough)")
 	fs.Parse(args)
 
 	if *in == "" || *modelPath == "" {
 		fmt.Fprintln(os.Stderr, "--in and --model are required")
 		os.Exit(1)
 	}
+	read, err := rf.options()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	model, err := dtree.LoadJSON(*modelPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load model: %v\n", err)
 		os.Exit(1)
 	}
-
-	items, headers, err := readItems(*in, *format, *label)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to read input data: %v\n", err)
+	if *strict {
+		model.Config.StrictPredict = true
+	}
+	if *leafStrategy != "" {
+		model.Config.LeafStrategy = *leafStrategy
+	}
+	if *seed != 0 {
+		model.Config.Seed = *seed
+	}
+	if err := model.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid prediction options: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -145,8 +341,53 @@ func predictCmd(args []string) {
 		w = f
 	}
 
+	// JSONL in and out needs no lookahead, so stream it row by row; type
+	// inference, schemas and CSV output need the whole file.
+	if read.format == "jsonl" && !*asCSV && read.inferRows == 0 && len(read.types) == 0 {
+		if err := streamPredictions(model, *in, w, *proba, *out != ""); err != nil {
+			fmt.Fprintf(os.Stderr, "prediction failed: %v\n", err)
+			os.Exit(1)
+		}
+		if *out != "" {
+			fmt.Printf("Predictions written to %s\n", *out)
+		}
+		return
+	}
+
+	// CSV in and out predicts from the raw records, parsing only the cells
+	// the tree visits.
+	if read.format == "csv" && *asCSV && read.inferRows == 0 {
+		if err := predictCSVRows(model, *in, w, read, *proba, *out != ""); err != nil {
+			fmt.Fprintf(os.Stderr, "prediction failed: %v\n", err)
+			os.Exit(1)
+		}
+		if *out != "" {
+			fmt.Printf("Predictions written to %s\n", *out)
+		}
+		return
+	}
+
+	items, headers, err := readItems(*in, read)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read input data: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Show progress only when stdout is free for it.
+	var progress func(done, total int)
+	if *out != "" {
+		progress = progressLine(os.Stderr)
+	}
+	preds, err := model.PredictBatchProgress(items, progress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "prediction failed on row %d: %v\n", len(preds)+1, err)
+		os.Exit(1)
+	}
+
 	if *asCSV {
 		cw := csv.NewWriter(w)
+		// mirror the input delimiter so output lines up with the source file
+		cw.Comma = read.delimiter
 		// write header + prediction (and optional proba)
 		hdr := append([]string{}, headers...)
 		hdr = append(hdr, "prediction")
@@ -155,18 +396,13 @@ func predictCmd(args []string) {
 		}
 		cw.Write(hdr)
 		for i, it := range items {
-			pred, err := model.Predict(it)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "prediction failed on row %d: %v\n", i+1, err)
-				os.Exit(1)
-			}
 			rec := make([]string, 0, len(headers)+2)
 			for _, h := range headers {
 				rec = append(rec, fmt.Sprintf("%v", it[h]))
 			}
-			rec = append(rec, pred)
+			rec = append(rec, preds[i])
 			if *proba {
-				pb, err := model.PredictProba(it)
+				pb, err := model.PredictProbaOrdered(it)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "probability prediction failed on row %d: %v\n", i+1, err)
 					os.Exit(1)
@@ -191,14 +427,9 @@ func predictCmd(args []string) {
 	bw := bufio.NewWriter(w)
 	enc := json.NewEncoder(bw)
 	for i, it := range items {
-		pred, err := model.Predict(it)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "prediction failed on row %d: %v\n", i+1, err)
-			os.Exit(1)
-		}
-		out := map[string]interface{}{"input": it, "prediction": pred}
+		out := map[string]interface{}{"input": it, "prediction": preds[i]}
 		if *proba {
-			pb, err := model.PredictProba(it)
+			pb, err := model.PredictProbaOrdered(it)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "probability prediction failed on row %d: %v\n", i+1, err)
 				os.Exit(1)
@@ -216,12 +447,14 @@ func predictCmd(args []string) {
 	}
 }
 
-// visualizeCmd renders the model to HTML, and optionally Graphviz DOT.
+// visualizeCmd renders the model to HTML, and optionally Graphviz DOT and SVG.
 func visualizeCmd(args []string) {
 	fs := flag.NewFlagSet("visualize", flag.ExitOnError)
 	modelPath := fs.String("model", "", "model JSON file")
 	outHTML := fs.String("out", "tree.html", "output HTML file")
 	outDOT := fs.String("dot", "", "optional DOT output file")
+	outSVG := fs.String("svg", "", "optional SVG output file")
+	render := fs.String("render", "", "also render the tree with Graphviz dot: png|svg|pdf, written next to --out")
 	fs.Parse(args)
 
 	if *modelPath == "" {
@@ -246,13 +479,812 @@ func visualizeCmd(args []string) {
 		}
 		fmt.Printf("DOT file written to %s\n", *outDOT)
 	}
+
+	if *outSVG != "" {
+		svg, err := model.ToSVG()
+		if err == nil {
+			err = os.WriteFile(*outSVG, []byte(svg), 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write SVG file: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("SVG file written to %s\n", *outSVG)
+	}
+
+	if *render != "" {
+		image := strings.TrimSuffix(*outHTML, filepath.Ext(*outHTML)) + "." + *render
+		if err := model.RenderDOT(image, *render); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to render tree: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Rendered tree written to %s\n", image)
+	}
+}
+
+// printCmd writes a text rendering of the tree to stdout.
+func printCmd(args []string) {
+	fs := flag.NewFlagSet("print", flag.ExitOnError)
+	modelPath := fs.String("model", "", "model JSON file")
+	fs.Parse(args)
+
+	if *modelPath == "" {
+		fmt.Fprintln(os.Stderr, "--model is required")
+		os.Exit(1)
+	}
+	model, err := dtree.LoadJSON(*modelPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load model: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(model.ToText())
+}
+
+// leavesCmd writes one CSV row per leaf: its path, class and class counts.
+func leavesCmd(args []string) {
+	fs := flag.NewFlagSet("leaves", flag.ExitOnError)
+	modelPath := fs.String("model", "", "model JSON file")
+	out := fs.String("out", "", "output CSV file (default: stdout)")
+	fs.Parse(args)
+
+	if *modelPath == "" {
+		fmt.Fprintln(os.Stderr, "--model is required")
+		os.Exit(1)
+	}
+	model, err := dtree.LoadJSON(*modelPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load model: %v\n", err)
+		os.Exit(1)
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := model.LeavesCSV(w); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write leaves: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// convertCmd converts a model between file formats chosen by extension.
+func convertCmd(args []string) {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	in := fs.String("in", "", "input model (.json, .json.gz or .gob)")
+	out := fs.String("out", "", "output file (.json, .json.gz, .gob, .dot or .svg)")
+	fs.Parse(args)
+
+	if *in == "" || *out == "" {
+		fmt.Fprintln(os.Stderr, "--in and --out are required")
+		os.Exit(1)
+	}
+	if err := convertModel(*in, *out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Converted %s to %s\n", *in, *out)
+}
+
+// Model file formats, detected from the file extension by modelFormat.
+const (
+	formatJSON = "json"
+	formatGob  = "gob"
+	formatDOT  = "dot"
+	formatSVG  = "svg"
+	formatPMML = "pmml"
+)
+
+// lossyFormats are renderings the tree cannot be recovered from.
+var lossyFormats = map[string]bool{formatDOT: true, formatSVG: true, formatPMML: true}
+
+// modelFormat returns the format of path from its extension, treating
+// ".json.gz" as JSON, or "" if the extension is not recognized.
+func modelFormat(path string) string {
+	p := strings.ToLower(path)
+	if strings.HasSuffix(p, ".json.gz") {
+		return formatJSON
+	}
+	switch ext := filepath.Ext(p); ext {
+	case ".json", ".gob", ".dot", ".svg", ".pmml":
+		return ext[1:]
+	}
+	return ""
+}
+
+// convertModel loads the model at in and writes it to out, in the formats
+// given by their extensions. Lossy formats cannot be read back.
+func convertModel(in, out string) error {
+	from, to := modelFormat(in), modelFormat(out)
+	switch {
+	case from == "":
+		return fmt.Errorf("unknown input format for %s", in)
+	case to == "":
+		return fmt.Errorf("unknown output format for %s", out)
+	case lossyFormats[from]:
+		return fmt.Errorf("cannot convert from %s: the tree structure cannot be recovered from it", from)
+	case to == formatPMML:
+		return errors.New("PMML output is not supported")
+	}
+
+	var model *dtree.Model
+	var err error
+	if from == formatGob {
+		model, err = dtree.LoadGob(in)
+	} else {
+		model, err = dtree.LoadJSON(in)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load model: %w", err)
+	}
+
+	switch to {
+	case formatJSON:
+		err = model.SaveJSON(out)
+	case formatGob:
+		err = model.SaveGob(out)
+	case formatDOT:
+		err = os.WriteFile(out, []byte(model.ToDOT()), 0644)
+	case formatSVG:
+		var svg string
+		if svg, err = model.ToSVG(); err == nil {
+			err = os.WriteFile(out, []byte(svg), 0644)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	return nil
+}
+
+// modelInfo is the machine-readable summary emitted by `info --json`.
+type modelInfo struct {
+	Config   dtree.Config     `json:"config"`
+	Stats    dtree.ModelStats `json:"stats"`
+	Metadata *dtree.Metadata  `json:"metadata,omitempty"`
+}
+
+// infoCmd loads a model and prints its configuration and statistics.
+func infoCmd(args []string) {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	modelPath := fs.String("model", "", "model JSON file")
+	asJSON := fs.Bool("json", false, "output as JSON")
+	fs.Parse(args)
+
+	if *modelPath == "" {
+		fmt.Fprintln(os.Stderr, "--model is required")
+		os.Exit(1)
+	}
+	model, err := dtree.LoadJSON(*modelPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load model: %v\n", err)
+		os.Exit(1)
+	}
+	if err := writeInfo(os.Stdout, model, *asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write info: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// validateCmd checks a model file and prints its statistics, exiting non-zero
+// if it is invalid.
+func validateCmd(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	modelPath := fs.String("model", "", "model JSON file")
+	// --strict: also reject suspicious but loadable trees
+	strict := fs.Bool("strict", false, "also flag empty-category and zero-count leaves")
+	fs.Parse(args)
+
+	if *modelPath == "" {
+		fmt.Fprintln(os.Stderr, "--model is required")
+		os.Exit(1)
+	}
+	if err := validateModel(*modelPath, os.Stdout, *strict); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid model: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// validateModel decodes the model at path with dtree.DecodeJSON, which
+// validates it, and writes "valid" and its statistics to w. With strict,
+// suspicious leaves are written to w as warnings and fail validation.
+func validateModel(path string, w io.Writer, strict bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	model, err := dtree.DecodeJSON(f)
+	if err != nil {
+		return err
+	}
+	if strict {
+		issues := suspiciousNodes(model.Root, "root", nil)
+		for _, issue := range issues {
+			fmt.Fprintf(w, "warning: %s\n", issue)
+		}
+		if len(issues) > 0 {
+			return fmt.Errorf("%d suspicious nodes", len(issues))
+		}
+	}
+	fmt.Fprintln(w, "valid")
+	printStats(w, model.Stats())
+	return nil
+}
+
+// compareCmd evaluates two models on the same labelled data and prints
+// their scores side by side with the rows they disagree on.
+func compareCmd(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	pathA := fs.String("a", "", "first model JSON file")
+	pathB := fs.String("b", "", "second model JSON file")
+	in := fs.String("in", "", "labelled data file (csv, tsv or jsonl)")
+	rf := addReadFlags(fs)
+	label := fs.String("label", "", "label column (default: each model's label)")
+	fs.Parse(args)
+
+	if *pathA == "" || *pathB == "" || *in == "" {
+		fmt.Fprintln(os.Stderr, "--a, --b and --in are required")
+		os.Exit(1)
+	}
+	read, err := rf.options()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := compareModels(*pathA, *pathB, *in, read, *label, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "compare failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// compareModels evaluates the models at pathA and pathB on the data at in
+// and writes accuracy and F1 for each, then how many rows they predict
+// differently, split McNemar-style into the rows only A gets right and
+// those only B gets right. A non-empty label overrides both models' label.
+func compareModels(pathA, pathB, in string, read readOptions, label string, w io.Writer) error {
+	var models [2]*dtree.Model
+	for i, path := range []string{pathA, pathB} {
+		m, err := dtree.LoadJSON(path)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", path, err)
+		}
+		if label != "" {
+			m.Config.CategoryAttr = label
+		}
+		models[i] = m
+	}
+	a, b := models[0], models[1]
+	set, err := readTrainingSet(in, read, a.Config.CategoryAttr, "")
+	if err != nil {
+		return fmt.Errorf("failed to read data: %w", err)
+	}
+	var reports [2]dtree.EvalReport
+	for i, m := range models {
+		if reports[i], err = m.Evaluate(set); err != nil {
+			return fmt.Errorf("model %c: %w", 'A'+i, err)
+		}
+	}
+
+	// onlyA and onlyB count rows exactly one model predicts correctly.
+	disagree, onlyA, onlyB := 0, 0, 0
+	for i, item := range set {
+		pa, err := a.Predict(item)
+		if err != nil {
+			return fmt.Errorf("model A, row %d: %w", i+1, err)
+		}
+		pb, err := b.Predict(item)
+		if err != nil {
+			return fmt.Errorf("model B, row %d: %w", i+1, err)
+		}
+		if pa == pb {
+			continue
+		}
+		disagree++
+		switch actual := fmt.Sprint(item[a.Config.CategoryAttr]); actual {
+		case pa:
+			onlyA++
+		case pb:
+			onlyB++
+		}
+	}
+
+	fmt.Fprintf(w, "%-12s %9s %9s\n", "", "A", "B")
+	fmt.Fprintf(w, "%-12s %9.4f %9.4f\n", "accuracy", reports[0].Accuracy, reports[1].Accuracy)
+	fmt.Fprintf(w, "%-12s %9.4f %9.4f\n", "macro F1", reports[0].MacroAvg().F1, reports[1].MacroAvg().F1)
+	fmt.Fprintf(w, "%-12s %9.4f %9.4f\n", "weighted F1", reports[0].WeightedAvg().F1, reports[1].WeightedAvg().F1)
+	fmt.Fprintf(w, "\nDisagreements: %d of %d rows\n", disagree, len(set))
+	fmt.Fprintf(w, "  only A correct: %d\n", onlyA)
+	fmt.Fprintf(w, "  only B correct: %d\n", onlyB)
+	if n := onlyA + onlyB; n > 0 {
+		// McNemar's statistic with continuity correction, 1 degree of freedom.
+		d := math.Abs(float64(onlyA-onlyB)) - 1
+		if d < 0 {
+			d = 0
+		}
+		fmt.Fprintf(w, "  McNemar chi-square: %.4f\n", d*d/float64(n))
+	}
+	return nil
+}
+
+// suspiciousNodes appends to out a description of each leaf under n that
+// Validate allows but that cannot come from training: an empty category, no
+// class counts, or a category its counts never saw. path names n, with
+// "/yes", "/no" or "/<value>" added per level.
+func suspiciousNodes(n *dtree.TreeItem, path string, out []string) []string {
+	if n == nil {
+		return out
+	}
+	if n.Match == nil && n.NoMatch == nil && len(n.Children) == 0 {
+		total := 0
+		for _, c := range n.ClassCounts {
+			total += c
+		}
+		switch {
+		case n.Category == "":
+			out = append(out, path+": leaf has an empty category")
+		case total == 0:
+			out = append(out, path+": leaf has zero class counts")
+		case n.ClassCounts[n.Category] == 0:
+			out = append(out, fmt.Sprintf("%s: leaf predicts %q, which its class counts never saw", path, n.Category))
+		}
+		return out
+	}
+	out = suspiciousNodes(n.Match, path+"/yes", out)
+	out = suspiciousNodes(n.NoMatch, path+"/no", out)
+	keys := make([]string, 0, len(n.Children))
+	for k := range n.Children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		out = suspiciousNodes(n.Children[k], path+"/"+k, out)
+	}
+	return suspiciousNodes(n.Missing, path+"/(missing)", out)
+}
+
+// importanceCmd prints feature importances: impurity-based from the model
+// alone, or permutation importance when --data is given.
+func importanceCmd(args []string) {
+	fs := flag.NewFlagSet("importance", flag.ExitOnError)
+	modelPath := fs.String("model", "", "model JSON file")
+	data := fs.String("data", "", "optional labelled data for permutation importance")
+	rf := addReadFlags(fs)
+	label := fs.String("label", "", "label column in --data (default: the model's label)")
+	repeats := fs.Int("repeats", 5, "shuffles per feature for permutation importance")
+	seed := fs.Int64("seed", 0, "random seed for permutation importance")
+	asJSON := fs.Bool("json", false, "output as JSON")
+	fs.Parse(args)
+
+	if *modelPath == "" {
+		fmt.Fprintln(os.Stderr, "--model is required")
+		os.Exit(1)
+	}
+	model, err := dtree.LoadJSON(*modelPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load model: %v\n", err)
+		os.Exit(1)
+	}
+
+	method, imp := "impurity", model.FeatureImportance()
+	if *data != "" {
+		if *label != "" && *label != model.Config.CategoryAttr {
+			fmt.Fprintf(os.Stderr, "--label %q does not match the model's label %q\n", *label, model.Config.CategoryAttr)
+			os.Exit(1)
+		}
+		read, err := rf.options()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		set, err := readTrainingSet(*data, read, model.Config.CategoryAttr, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read data: %v\n", err)
+			os.Exit(1)
+		}
+		method = "permutation"
+		imp, err = model.PermutationImportance(set, *repeats, *seed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "permutation importance failed: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := writeImportance(os.Stdout, method, imp, *asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write importance: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// featureScore is one row of importance output.
+type featureScore struct {
+	Feature    string  `json:"feature"`
+	Importance float64 `json:"importance"`
+}
+
+// writeImportance writes importances sorted descending (ties by name), as a
+// table or as JSON.
+func writeImportance(w io.Writer, method string, imp map[string]float64, asJSON bool) error {
+	scores := make([]featureScore, 0, len(imp))
+	for f, v := range imp {
+		scores = append(scores, featureScore{Feature: f, Importance: v})
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Importance != scores[j].Importance {
+			return scores[i].Importance > scores[j].Importance
+		}
+		return scores[i].Feature < scores[j].Feature
+	})
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Method   string         `json:"method"`
+			Features []featureScore `json:"features"`
+		}{method, scores})
+	}
+	width := len("Feature")
+	for _, s := range scores {
+		if len(s.Feature) > width {
+			width = len(s.Feature)
+		}
+	}
+	fmt.Fprintf(w, "%-*s  Importance (%s)\n", width, "Feature", method)
+	for _, s := range scores {
+		fmt.Fprintf(w, "%-*s  %.4f\n", width, s.Feature, s.Importance)
+	}
+	return nil
+}
+
+// writeInfo writes model configuration and statistics as text or JSON.
+func writeInfo(w io.Writer, model *dtree.Model, asJSON bool) error {
+	info := modelInfo{Config: model.Config, Stats: model.Stats(), Metadata: model.Metadata}
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	fmt.Fprintf(w, "Model configuration:\n")
+	fmt.Fprintf(w, "  Label: %s\n", info.Config.CategoryAttr)
+	fmt.Fprintf(w, "  Criterion: %s\n", info.Config.Criterion)
+	fmt.Fprintf(w, "  Max depth: %d\n", info.Config.MaxDepth)
+	fmt.Fprintf(w, "  Min samples: %d\n", info.Config.MinSamples)
+	if len(info.Config.IgnoredAttributes) > 0 {
+		fmt.Fprintf(w, "  Ignored: %s\n", strings.Join(info.Config.IgnoredAttributes, ", "))
+	}
+	printStats(w, info.Stats)
+	fmt.Fprintf(w, "  Class labels: %s\n", strings.Join(info.Stats.Classes, ", "))
+	if meta := info.Metadata; meta != nil {
+		fmt.Fprintf(w, "Training metadata:\n")
+		fmt.Fprintf(w, "  Trained at: %s\n", meta.TrainedAt.Format(time.RFC3339))
+		fmt.Fprintf(w, "  Library version: %s\n", meta.LibVersion)
+		fmt.Fprintf(w, "  Samples: %d\n", meta.NumSamples)
+		fmt.Fprintf(w, "  Features: %s\n", strings.Join(meta.FeatureNames, ", "))
+		for _, warn := range meta.Warnings {
+			fmt.Fprintf(w, "  Warning: %s\n", warn)
+		}
+	}
+	return nil
+}
+
+// predictCSVRows predicts the CSV file at path, which may be
+// gzip-compressed, row by row with a dtree.PreparedModel and writes the input
+// columns, minus schema-ignored ones, followed by the prediction and, with
+// proba, the probabilities as JSON. Input cells are copied verbatim. A running
+// row count is shown on stderr when progress is set.
+func predictCSVRows(model *dtree.Model, path string, w io.Writer, read readOptions, proba, progress bool) error {
+	r, closer, err := openInput(path)
+	if err != nil {
+		return fmt.Errorf("cannot open file: %w", err)
+	}
+	defer closer.Close()
+	cr := csv.NewReader(r)
+	cr.Comma = read.delimiter
+	cr.TrimLeadingSpace = !unicode.IsSpace(cr.Comma)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("cannot read CSV header: %w", err)
+	}
+	header = append([]string(nil), header...)
+	pm, err := model.PrepareCSV(header, dtree.CSVOptions{NA: read.na, Types: read.types})
+	if err != nil {
+		return err
+	}
+	var keep []int
+	var outHdr []string
+	for i, h := range header {
+		if read.types[h] != dtree.ColumnIgnore {
+			keep = append(keep, i)
+			outHdr = append(outHdr, h)
+		}
+	}
+	outHdr = append(outHdr, "prediction")
+	if proba {
+		outHdr = append(outHdr, "proba")
+	}
+
+	cw := csv.NewWriter(w)
+	// mirror the input delimiter so output lines up with the source file
+	cw.Comma = read.delimiter
+	cw.Write(outHdr)
+	out := make([]string, 0, len(outHdr))
+	rows := 0
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading CSV row %d: %w", rows+2, err)
+		}
+		rows++
+		pred, err := pm.PredictRow(rec)
+		if err != nil {
+			return fmt.Errorf("row %d: %w", rows+1, err)
+		}
+		out = out[:0]
+		for _, i := range keep {
+			out = append(out, rec[i])
+		}
+		out = append(out, pred)
+		if proba {
+			pb, err := pm.PredictProbaOrderedRow(rec)
+			if err != nil {
+				return fmt.Errorf("row %d: %w", rows+1, err)
+			}
+			b, _ := json.Marshal(pb)
+			out = append(out, string(b))
+		}
+		cw.Write(out)
+		if progress && rows%1000 == 0 {
+			fmt.Fprintf(os.Stderr, "\rPredicting: %d rows", rows)
+		}
+	}
+	if progress {
+		fmt.Fprintf(os.Stderr, "\rPredicting: %d rows\n", rows)
+	}
+	if rows == 0 {
+		return errors.New("CSV file is empty (no data rows)")
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// streamPredictions predicts the JSONL file at path, which may be
+// gzip-compressed, with Model.PredictStream, showing a running row count on
+// stderr when progress is set.
+func streamPredictions(model *dtree.Model, path string, w io.Writer, proba, progress bool) error {
+	r, closer, err := openInput(path)
+	if err != nil {
+		return fmt.Errorf("cannot open file: %w", err)
+	}
+	defer closer.Close()
+	opts := dtree.StreamOptions{OrderedProba: proba}
+	if progress {
+		opts.Progress = func(done int) { fmt.Fprintf(os.Stderr, "\rPredicting: %d rows", done) }
+		defer fmt.Fprintln(os.Stderr)
+	}
+	return model.PredictStream(r, w, opts)
+}
+
+// progressLine returns a PredictBatchProgress callback that redraws a
+// percentage line on w and ends it with a newline once all rows are done.
+func progressLine(w io.Writer) func(done, total int) {
+	return func(done, total int) {
+		fmt.Fprintf(w, "\rPredicting: %3d%% (%d/%d rows)", done*100/total, done, total)
+		if done == total {
+			fmt.Fprintln(w)
+		}
+	}
 }
 
 // IO helpers
 
-// readTrainingSet loads and validates a dataset for training.
-func readTrainingSet(path, format, label string) (dtree.TrainingSet, error) {
-	items, _, err := readItems(path, format, label)
+// readOptions controls how input files are parsed.
+type readOptions struct {
+	// format is "csv" or "jsonl"; "tsv" is normalized to "csv" with a tab delimiter.
+	format string
+	// delimiter separates CSV fields.
+	delimiter rune
+	// na holds extra CSV cell values read as missing (nil), besides the empty string.
+	na []string
+	// inferRows, when positive, enables type inference over that many leading
+	// rows followed by coercion of every row; see dtree.InferColumnTypes.
+	inferRows int
+	// sample, when set, keeps only a random subset of the rows.
+	sample sampleOptions
+	// types maps columns to dtree.Column* types from --schema; listed
+	// columns skip type detection and ignored ones are dropped.
+	types map[string]string
+}
+
+// ignored returns the sorted columns the schema marks as ignore.
+func (o readOptions) ignored() []string {
+	var cols []string
+	for col, typ := range o.types {
+		if typ == dtree.ColumnIgnore {
+			cols = append(cols, col)
+		}
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+// loadSchema reads a --schema file: a JSON object mapping column names to
+// string, number, bool or ignore.
+func loadSchema(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read schema: %w", err)
+	}
+	var types map[string]string
+	if err := json.Unmarshal(data, &types); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", path, err)
+	}
+	for col, typ := range types {
+		switch typ {
+		case dtree.ColumnString, dtree.ColumnNumber, dtree.ColumnBool, dtree.ColumnIgnore:
+		default:
+			return nil, fmt.Errorf("schema column %q has unknown type %q (must be string, number, bool or ignore)", col, typ)
+		}
+	}
+	return types, nil
+}
+
+// sampleOptions selects a random subset of the input rows as they are read.
+type sampleOptions struct {
+	// limit, when positive, keeps at most that many rows.
+	limit int
+	// frac, when below 1, keeps each row with that probability.
+	frac float64
+	seed int64
+}
+
+func (o sampleOptions) enabled() bool {
+	return o.limit > 0 || (o.frac > 0 && o.frac < 1)
+}
+
+// sampler collects rows for sampleOptions in one pass: rows are first kept
+// with probability frac, then reservoir-sampled down to limit, so memory stays
+// bounded by limit. Kept rows are returned in input order.
+type sampler struct {
+	opts  sampleOptions
+	rng   *rand.Rand
+	total int // rows offered
+	seen  int // rows that passed the frac filter
+	kept  []sampledItem
+}
+
+type sampledItem struct {
+	index int
+	item  dtree.TrainingItem
+}
+
+func newSampler(opts sampleOptions) *sampler {
+	return &sampler{opts: opts, rng: rand.New(rand.NewSource(opts.seed))}
+}
+
+func (s *sampler) add(index int, it dtree.TrainingItem) {
+	s.total++
+	if s.opts.frac > 0 && s.opts.frac < 1 && s.rng.Float64() >= s.opts.frac {
+		return
+	}
+	s.seen++
+	if s.opts.limit <= 0 || len(s.kept) < s.opts.limit {
+		s.kept = append(s.kept, sampledItem{index, it})
+		return
+	}
+	if j := s.rng.Intn(s.seen); j < s.opts.limit {
+		s.kept[j] = sampledItem{index, it}
+	}
+}
+
+// items returns the kept rows. It fails if rows were read but none was kept.
+func (s *sampler) items() ([]dtree.TrainingItem, error) {
+	if s.total > 0 && len(s.kept) == 0 {
+		return nil, fmt.Errorf("sampling kept none of the %d rows", s.total)
+	}
+	sort.Slice(s.kept, func(i, j int) bool { return s.kept[i].index < s.kept[j].index })
+	out := make([]dtree.TrainingItem, len(s.kept))
+	for i, k := range s.kept {
+		out[i] = k.item
+	}
+	return out, nil
+}
+
+// listFlag is a string list flag that may be repeated or given comma-separated.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, strings.Split(v, ",")...)
+	return nil
+}
+
+// readFlags holds the input-parsing flags shared by commands that read data.
+type readFlags struct {
+	format     *string
+	delimiter  *string
+	na         listFlag
+	inferTypes *bool
+	inferRows  *int
+	schema     *string
+}
+
+// addReadFlags registers --format, --delimiter, --na, --infer-types,
+// --infer-rows and --schema on fs.
+func addReadFlags(fs *flag.FlagSet) *readFlags {
+	rf := &readFlags{
+		format:     fs.String("format", "csv", "input format: csv|tsv|jsonl"),
+		delimiter:  fs.String("delimiter", "", "CSV field delimiter, a single character (default ',' or tab for tsv)"),
+		inferTypes: fs.Bool("infer-types", false, "infer a type per column from the first rows and coerce all values to it (e.g. \"3\" to 3)"),
+		inferRows:  fs.Int("infer-rows", 1000, "rows scanned by --infer-types"),
+		schema:     fs.String("schema", "", "JSON file mapping columns to string|number|bool|ignore; other columns are auto-detected"),
+	}
+	fs.Var(&rf.na, "na", "CSV value treated as missing, in addition to empty cells; repeatable or comma-separated (e.g. NA,N/A,?)")
+	return rf
+}
+
+// options resolves the parsed flags into readOptions.
+func (rf *readFlags) options() (readOptions, error) {
+	opts, err := newReadOptions(*rf.format, *rf.delimiter)
+	if err != nil {
+		return readOptions{}, err
+	}
+	opts.na = rf.na
+	if *rf.inferTypes {
+		if *rf.inferRows <= 0 {
+			return readOptions{}, fmt.Errorf("--infer-rows must be positive, got %d", *rf.inferRows)
+		}
+		opts.inferRows = *rf.inferRows
+	}
+	if *rf.schema != "" {
+		if opts.types, err = loadSchema(*rf.schema); err != nil {
+			return readOptions{}, err
+		}
+	}
+	return opts, nil
+}
+
+// newReadOptions resolves the --format and --delimiter flags.
+// An empty delimiter selects ',' (or tab for tsv); "\t" and "tab" name a tab.
+func newReadOptions(format, delimiter string) (readOptions, error) {
+	opts := readOptions{format: strings.ToLower(format), delimiter: ','}
+	switch opts.format {
+	case "csv", "jsonl":
+	case "tsv":
+		opts.format = "csv"
+		opts.delimiter = '\t'
+	default:
+		return readOptions{}, fmt.Errorf("unknown format: %s (must be 'csv', 'tsv' or 'jsonl')", format)
+	}
+	switch delimiter {
+	case "":
+	case "\\t", "tab":
+		opts.delimiter = '\t'
+	default:
+		r := []rune(delimiter)
+		if len(r) != 1 {
+			return readOptions{}, fmt.Errorf("--delimiter must be a single character, got %q", delimiter)
+		}
+		if r[0] == '"' || r[0] == '\r' || r[0] == '\n' || r[0] == utf8.RuneError {
+			return readOptions{}, fmt.Errorf("--delimiter %q cannot be used as a CSV separator", delimiter)
+		}
+		opts.delimiter = r[0]
+	}
+	return opts, nil
+}
+
+// readTrainingSet loads and validates a dataset for training. If weight is
+// set, every row must hold a finite, non-negative number in that column.
+func readTrainingSet(path string, opts readOptions, label, weight string) (dtree.TrainingSet, error) {
+	items, _, err := readItems(path, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -262,44 +1294,105 @@ func readTrainingSet(path, format, label string) (dtree.TrainingSet, error) {
 			return nil, fmt.Errorf("missing label '%s' in row %d", label, i+1)
 		}
 	}
+	if weight != "" {
+		for i, it := range items {
+			v, ok := it[weight]
+			if !ok {
+				return nil, fmt.Errorf("missing weight column '%s' in row %d", weight, i+1)
+			}
+			if w, ok := v.(float64); !ok || w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+				return nil, fmt.Errorf("weight %v in row %d is not a non-negative number", v, i+1)
+			}
+		}
+	}
 	return dtree.TrainingSet(items), nil
 }
 
 // readItems loads rows from CSV (using header) or JSONL.
 // Returns a slice of items and the header order (for CSV output mirroring).
-func readItems(path, format, label string) ([]dtree.TrainingItem, []string, error) {
+// Columns in opts.types get their schema type and ignored columns are
+// dropped, from the header too. With opts.inferRows set, the other values
+// are coerced to the inferred column types and mixed-type columns are
+// reported on stderr.
+func readItems(path string, opts readOptions) ([]dtree.TrainingItem, []string, error) {
+	items, hdr, err := loadItems(path, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(opts.types) > 0 {
+		if opts.format == "jsonl" {
+			if err := dtree.CoerceTypes(items, opts.types); err != nil {
+				return nil, nil, fmt.Errorf("schema: %w", err)
+			}
+		}
+		kept := hdr[:0]
+		for _, h := range hdr {
+			if opts.types[h] != dtree.ColumnIgnore {
+				kept = append(kept, h)
+			}
+		}
+		hdr = kept
+	}
+	if opts.inferRows == 0 {
+		return items, hdr, nil
+	}
+	types, warnings := dtree.InferColumnTypes(items, opts.inferRows)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+	for col := range opts.types {
+		delete(types, col)
+	}
+	if err := dtree.CoerceTypes(items, types); err != nil {
+		return nil, nil, fmt.Errorf("type inference: %w", err)
+	}
+	return items, hdr, nil
+}
+
+// openInput opens path for reading, transparently decompressing gzip input,
+// which is detected from its magic bytes so any file name works.
+func openInput(path string) (io.Reader, io.Closer, error) {
 	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := dtree.MaybeGunzip(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return r, f, nil
+}
+
+// loadItems parses path as-is, without type coercion. Gzip-compressed files
+// are decompressed first, so line numbers in errors refer to the
+// decompressed text.
+func loadItems(path string, opts readOptions) ([]dtree.TrainingItem, []string, error) {
+	f, closer, err := openInput(path)
 	if err != nil {
 		return nil, nil, fmt.Errorf("cannot open file: %w", err)
 	}
-	defer f.Close()
-	switch strings.ToLower(format) {
+	defer closer.Close()
+	switch opts.format {
 	case "csv":
-		r := csv.NewReader(f)
-		r.TrimLeadingSpace = true
-		header, err := r.Read()
-		if err != nil {
-			return nil, nil, fmt.Errorf("cannot read CSV header: %w", err)
-		}
 		var items []dtree.TrainingItem
-		rowNum := 2 // Start at 2 (1 is header)
-		for {
-			rec, err := r.Read()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return nil, nil, fmt.Errorf("error reading CSV row %d: %w", rowNum, err)
-			}
-			if len(rec) != len(header) {
-				return nil, nil, fmt.Errorf("row %d has %d columns but header has %d", rowNum, len(rec), len(header))
+		s := newSampler(opts.sample)
+		csvOpts := dtree.CSVOptions{Comma: opts.delimiter, NA: opts.na, Types: opts.types}
+		header, err := dtree.ReadCSVFunc(f, csvOpts, func(i int, it dtree.TrainingItem) error {
+			if opts.sample.enabled() {
+				s.add(i, it)
+			} else {
+				items = append(items, it)
 			}
-			it := dtree.TrainingItem{}
-			for i, h := range header {
-				it[h] = parseCSVValue(rec[i])
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if opts.sample.enabled() {
+			if items, err = s.items(); err != nil {
+				return nil, nil, err
 			}
-			items = append(items, it)
-			rowNum++
 		}
 		if len(items) == 0 {
 			return nil, nil, fmt.Errorf("CSV file is empty (no data rows)")
@@ -307,48 +1400,31 @@ func readItems(path, format, label string) ([]dtree.TrainingItem, []string, erro
 		return items, header, nil
 	case "jsonl":
 		var items []dtree.TrainingItem
-		sc := bufio.NewScanner(f)
-		lineNum := 1
-		for sc.Scan() {
-			var m map[string]interface{}
-			if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
-				return nil, nil, fmt.Errorf("invalid JSON on line %d: %w", lineNum, err)
+		s := newSampler(opts.sample)
+		hdr, err := dtree.ReadJSONLFunc(f, func(i int, it dtree.TrainingItem) error {
+			if opts.sample.enabled() {
+				s.add(i, it)
+			} else {
+				items = append(items, it)
 			}
-			items = append(items, dtree.TrainingItem(m))
-			lineNum++
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
 		}
-		if err := sc.Err(); err != nil {
-			return nil, nil, fmt.Errorf("error reading JSONL: %w", err)
+		if opts.sample.enabled() {
+			if items, err = s.items(); err != nil {
+				return nil, nil, err
+			}
 		}
 		if len(items) == 0 {
 			return nil, nil, fmt.Errorf("JSONL file is empty")
 		}
-		// collect headers from first item (best-effort)
-		hdr := []string{}
-		if len(items) > 0 {
-			for k := range items[0] {
-				hdr = append(hdr, k)
-			}
+		for _, w := range dtree.MissingKeys(items, hdr) {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", w)
 		}
 		return items, hdr, nil
 	default:
-		return nil, nil, fmt.Errorf("unknown format: %s (must be 'csv' or 'jsonl')", format)
-	}
-}
-
-// parseCSVValue converts CSV cell strings to float64, bool, or leaves as string.
-func parseCSVValue(s string) interface{} {
-	if s == "" {
-		return nil
-	}
-	if f, err := strconv.ParseFloat(s, 64); err == nil {
-		return f
-	}
-	if s == "true" {
-		return true
-	}
-	if s == "false" {
-		return false
+		return nil, nil, fmt.Errorf("unknown format: %s (must be 'csv', 'tsv' or 'jsonl')", opts.format)
 	}
-	return s
 }
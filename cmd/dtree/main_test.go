@@ -0,0 +1,733 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kerneldump/dtree/dtree"
+)
+
+// saveTestModel trains a small PlayTennis-style model and saves it under t.TempDir.
+func saveTestModel(t *testing.T) string {
+	t.Helper()
+	set := dtree.TrainingSet{
+		{"Outlook": "sunny", "Humidity": 85.0, "Play": "no"},
+		{"Outlook": "sunny", "Humidity": 90.0, "Play": "no"},
+		{"Outlook": "overcast", "Humidity": 86.0, "Play": "yes"},
+		{"Outlook": "rain", "Humidity": 96.0, "Play": "yes"},
+		{"Outlook": "rain", "Humidity": 70.0, "Play": "no"},
+	}
+	model, err := dtree.Train(set, dtree.Config{CategoryAttr: "Play", MaxDepth: 4})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := model.SaveJSON(path); err != nil {
+		t.Fatalf("failed to save model: %v", err)
+	}
+	return path
+}
+
+func TestWriteInfo_JSON(t *testing.T) {
+	model, err := dtree.LoadJSON(saveTestModel(t))
+	if err != nil {
+		t.Fatalf("failed to load model: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := writeInfo(&buf, model, true); err != nil {
+		t.Fatalf("writeInfo failed: %v", err)
+	}
+
+	var got struct {
+		Config   map[string]interface{} `json:"config"`
+		Stats    map[string]interface{} `json:"stats"`
+		Metadata map[string]interface{} `json:"metadata"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("info output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if got.Config["categoryAttr"] != "Play" || got.Config["maxDepth"] != 4.0 {
+		t.Errorf("unexpected config: %v", got.Config)
+	}
+	for _, key := range []string{"treeDepth", "totalNodes", "leafNodes", "internalNodes", "classes"} {
+		if _, ok := got.Stats[key]; !ok {
+			t.Errorf("stats missing %q: %v", key, got.Stats)
+		}
+	}
+	if int(got.Stats["totalNodes"].(float64)) != model.Stats().TotalNodes {
+		t.Errorf("totalNodes mismatch: %v", got.Stats["totalNodes"])
+	}
+	if got.Metadata["numSamples"] != 5.0 {
+		t.Errorf("unexpected metadata: %v", got.Metadata)
+	}
+}
+
+func TestWriteInfo_Text(t *testing.T) {
+	model, err := dtree.LoadJSON(saveTestModel(t))
+	if err != nil {
+		t.Fatalf("failed to load model: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := writeInfo(&buf, model, false); err != nil {
+		t.Fatalf("writeInfo failed: %v", err)
+	}
+	for _, want := range []string{"Label: Play", "Total nodes:", "Class labels: no, yes"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("text output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestParseTrainFlags(t *testing.T) {
+	fs := flag.NewFlagSet("train", flag.ContinueOnError)
+	opts, err := parseTrainFlags(fs, []string{
+		"--in", "data.csv", "--label", "Play",
+		"--criterion", "gini", "--maxFeatures", "2", "--minSamplesLeaf", "3", "--seed", "42",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := opts.cfg
+	if cfg.CategoryAttr != "Play" || cfg.Criterion != "gini" || cfg.MaxFeatures != 2 ||
+		cfg.MinSamplesLeaf != 3 || cfg.Seed != 42 {
+		t.Fatalf("flags not wired into config: %+v", cfg)
+	}
+	if opts.in != "data.csv" || opts.out != "model.json" || opts.read.format != "csv" || opts.read.delimiter != ',' {
+		t.Fatalf("unexpected io options: %+v", opts)
+	}
+}
+
+func TestParseTrainFlags_Errors(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--label", "Play"}, "--in is required"},
+		{[]string{"--in", "d.csv", "--criterion", "variance"}, "entropy, gini"},
+		{[]string{"--in", "d.csv", "--maxFeatures", "-1"}, "MaxFeatures"},
+		{[]string{"--in", "d.csv", "--minSamplesLeaf", "-2"}, "MinSamplesLeaf"},
+		{[]string{"--in", "d.csv", "--limit", "-1"}, "--limit"},
+		{[]string{"--in", "d.csv", "--sample-frac", "0"}, "--sample-frac"},
+		{[]string{"--in", "d.csv", "--sample-frac", "1.5"}, "--sample-frac"},
+	}
+	for _, tc := range cases {
+		fs := flag.NewFlagSet("train", flag.ContinueOnError)
+		_, err := parseTrainFlags(fs, tc.args)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("args %v: expected error containing %q, got %v", tc.args, tc.want, err)
+		}
+	}
+}
+
+// writeFile writes content to name under dir and returns the path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestReadItems_TSVMatchesCSV(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := writeFile(t, dir, "data.csv", "Outlook,Humidity,Play\nsunny,85,no\n\"rain, heavy\",70,yes\n")
+	tsvPath := writeFile(t, dir, "data.tsv", "Outlook\tHumidity\tPlay\nsunny\t85\tno\nrain, heavy\t70\tyes\n")
+	semiPath := writeFile(t, dir, "data.txt", "Outlook;Humidity;Play\nsunny;85;no\nrain, heavy;70;yes\n")
+
+	csvOpts, _ := newReadOptions("csv", "")
+	want, wantHdr, err := readItems(csvPath, csvOpts)
+	if err != nil {
+		t.Fatalf("reading CSV failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		path, format, delimiter string
+	}{
+		{tsvPath, "tsv", ""},
+		{tsvPath, "csv", "\\t"},
+		{semiPath, "csv", ";"},
+	} {
+		opts, err := newReadOptions(tc.format, tc.delimiter)
+		if err != nil {
+			t.Fatalf("format %s delimiter %q: %v", tc.format, tc.delimiter, err)
+		}
+		got, hdr, err := readItems(tc.path, opts)
+		if err != nil {
+			t.Fatalf("reading %s failed: %v", tc.path, err)
+		}
+		if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(hdr, wantHdr) {
+			t.Errorf("%s (%s, %q): got %v %v, want %v %v", tc.path, tc.format, tc.delimiter, hdr, got, wantHdr, want)
+		}
+	}
+}
+
+// writeGzip writes content gzip-compressed to dir/name.
+func writeGzip(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(content))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return writeFile(t, dir, name, buf.String())
+}
+
+func TestReadItems_Gzip(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct{ format, name, content string }{
+		{"csv", "data.csv", "Outlook,Humidity,Play\nsunny,85,no\nrain,70,yes\n"},
+		{"jsonl", "data.jsonl", `{"Outlook":"sunny","Humidity":85,"Play":"no"}` + "\n" + `{"Outlook":"rain","Humidity":70,"Play":"yes"}` + "\n"},
+	} {
+		opts, _ := newReadOptions(tc.format, "")
+		want, _, err := readItems(writeFile(t, dir, tc.name, tc.content), opts)
+		if err != nil {
+			t.Fatalf("reading plain %s failed: %v", tc.format, err)
+		}
+		for _, name := range []string{tc.name + ".gz", "renamed-" + tc.name} {
+			got, _, err := readItems(writeGzip(t, dir, name, tc.content), opts)
+			if err != nil {
+				t.Fatalf("reading %s failed: %v", name, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: got %v, want %v", name, got, want)
+			}
+		}
+	}
+
+	opts, _ := newReadOptions("jsonl", "")
+	_, _, err := readItems(writeGzip(t, dir, "bad.jsonl.gz", "{\"a\":1}\n{oops\n"), opts)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error on line 2 of the decompressed text, got %v", err)
+	}
+}
+
+func TestReadTrainingSet_Sampling(t *testing.T) {
+	dir := t.TempDir()
+	var b strings.Builder
+	b.WriteString("x,label\n")
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&b, "%d,%s\n", i, []string{"a", "b"}[i%2])
+	}
+	path := writeFile(t, dir, "big.csv", b.String())
+
+	read := func(args ...string) dtree.TrainingSet {
+		t.Helper()
+		opts, err := parseTrainFlags(flag.NewFlagSet("train", flag.ContinueOnError), append([]string{"--in", path}, args...))
+		if err != nil {
+			t.Fatalf("parseTrainFlags(%v): %v", args, err)
+		}
+		set, err := readTrainingSet(opts.in, opts.read, opts.cfg.CategoryAttr, opts.cfg.WeightAttr)
+		if err != nil {
+			t.Fatalf("readTrainingSet(%v): %v", args, err)
+		}
+		return set
+	}
+
+	first := read("--limit", "50", "--seed", "7")
+	if len(first) != 50 {
+		t.Fatalf("--limit 50 read %d rows", len(first))
+	}
+	if again := read("--limit", "50", "--seed", "7"); !reflect.DeepEqual(first, again) {
+		t.Error("same --seed should sample the same rows")
+	}
+	if other := read("--limit", "50", "--seed", "8"); reflect.DeepEqual(first, other) {
+		t.Error("different --seed should sample different rows")
+	}
+	for i := 1; i < len(first); i++ {
+		if first[i]["x"].(float64) <= first[i-1]["x"].(float64) {
+			t.Fatal("sampled rows should keep input order")
+		}
+	}
+	if all := read("--limit", "1000"); len(all) != 500 {
+		t.Errorf("--limit above the row count read %d rows, want 500", len(all))
+	}
+	if frac := read("--sample-frac", "0.2", "--seed", "3"); len(frac) < 60 || len(frac) > 140 {
+		t.Errorf("--sample-frac 0.2 read %d of 500 rows", len(frac))
+	}
+	if both := read("--sample-frac", "0.5", "--limit", "10"); len(both) != 10 {
+		t.Errorf("--sample-frac with --limit read %d rows, want 10", len(both))
+	}
+}
+
+func TestTrainReport(t *testing.T) {
+	dir := t.TempDir()
+	data := writeFile(t, dir, "data.csv", "Outlook,Humidity,Play\nsunny,85,no\nsunny,90,no\novercast,86,yes\nrain,96,yes\nrain,70,no\n")
+	modelPath := filepath.Join(dir, "model.json")
+	reportPath := filepath.Join(dir, "report.json")
+	opts, err := parseTrainFlags(flag.NewFlagSet("train", flag.ContinueOnError),
+		[]string{"--in", data, "--label", "Play", "--out", modelPath, "--report", reportPath})
+	if err != nil {
+		t.Fatalf("parseTrainFlags: %v", err)
+	}
+	var out bytes.Buffer
+	if err := runTrain(opts, &out); err != nil {
+		t.Fatalf("runTrain failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Model trained successfully") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+
+	b, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	for _, key := range []string{"config", "stats", "trainingRows", "importance", "trainSeconds"} {
+		if _, ok := raw[key]; !ok {
+			t.Errorf("report lacks %q", key)
+		}
+	}
+	var report trainReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.TrainingRows != 5 || report.Config.CategoryAttr != "Play" || report.Config.Criterion != dtree.CriterionEntropy {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if report.Stats.TotalNodes == 0 {
+		t.Error("report has no model statistics")
+	}
+}
+
+func TestTrainWeightColumn(t *testing.T) {
+	dir := t.TempDir()
+	// x cannot separate the classes; upweighting "b" flips the leaf.
+	data := writeFile(t, dir, "data.csv", "x,w,label\n1,1,a\n1,1,a\n1,1,a\n1,5,b\n1,5,b\n")
+	train := func(args ...string) *dtree.Model {
+		t.Helper()
+		opts, err := parseTrainFlags(flag.NewFlagSet("train", flag.ContinueOnError), append([]string{"--in", data}, args...))
+		if err != nil {
+			t.Fatalf("parseTrainFlags(%v): %v", args, err)
+		}
+		set, err := readTrainingSet(opts.in, opts.read, opts.cfg.CategoryAttr, opts.cfg.WeightAttr)
+		if err != nil {
+			t.Fatalf("readTrainingSet(%v): %v", args, err)
+		}
+		model, err := dtree.Train(set, opts.cfg)
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		return model
+	}
+
+	schema := writeFile(t, dir, "schema.json", `{"w": "ignore"}`)
+	if got, _ := train("--schema", schema).Predict(dtree.TrainingItem{"x": 1.0}); got != "a" {
+		t.Errorf("unweighted model predicts %q, want a", got)
+	}
+	weighted := train("--weight-col", "w")
+	if got, _ := weighted.Predict(dtree.TrainingItem{"x": 1.0}); got != "b" {
+		t.Errorf("weighted model predicts %q, want b", got)
+	}
+	if weighted.Metadata == nil || !reflect.DeepEqual(weighted.Metadata.FeatureNames, []string{"x"}) {
+		t.Errorf("weight column should not be a feature: %v", weighted.Metadata)
+	}
+
+	for _, tc := range []struct{ content, want string }{
+		{"x,w,label\n1,1,a\n1,-2,b\n", "weight -2 in row 2 is not a non-negative number"},
+		{"x,w,label\n1,1,a\n1,heavy,b\n", "weight heavy in row 2 is not a non-negative number"},
+		{"x,label\n1,a\n", "missing weight column 'w' in row 1"},
+	} {
+		path := writeFile(t, dir, "bad.csv", tc.content)
+		opts, err := parseTrainFlags(flag.NewFlagSet("train", flag.ContinueOnError), []string{"--in", path, "--weight-col", "w"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readTrainingSet(opts.in, opts.read, opts.cfg.CategoryAttr, opts.cfg.WeightAttr); err == nil || err.Error() != tc.want {
+			t.Errorf("%q: got error %v, want %q", tc.content, err, tc.want)
+		}
+	}
+}
+
+func TestTrainWithSchema(t *testing.T) {
+	dir := t.TempDir()
+	var b strings.Builder
+	b.WriteString("id,zip,label\n")
+	for i, zip := range []string{"02134", "10001", "60601", "94105", "02134", "10001", "60601", "94105"} {
+		label := "west"
+		if zip == "02134" || zip == "10001" {
+			label = "east"
+		}
+		fmt.Fprintf(&b, "%d,%s,%s\n", i, zip, label)
+	}
+	data := writeFile(t, dir, "zips.csv", b.String())
+	schema := writeFile(t, dir, "schema.json", `{"zip": "string", "id": "ignore"}`)
+
+	train := func(args ...string) *dtree.Model {
+		t.Helper()
+		opts, err := parseTrainFlags(flag.NewFlagSet("train", flag.ContinueOnError), append([]string{"--in", data}, args...))
+		if err != nil {
+			t.Fatalf("parseTrainFlags(%v): %v", args, err)
+		}
+		set, err := readTrainingSet(opts.in, opts.read, opts.cfg.CategoryAttr, opts.cfg.WeightAttr)
+		if err != nil {
+			t.Fatalf("readTrainingSet(%v): %v", args, err)
+		}
+		model, err := dtree.Train(set, opts.cfg)
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		return model
+	}
+
+	if auto := train(); auto.Root.PredicateName != ">=" {
+		t.Fatalf("auto-detected zip should split numerically, got %s %v", auto.Root.PredicateName, auto.Root.Pivot)
+	}
+	model := train("--schema", schema)
+	if model.Root.Attribute != "zip" || model.Root.PredicateName != "==" {
+		t.Fatalf("zip should split categorically with the schema, got %s %s %v",
+			model.Root.Attribute, model.Root.PredicateName, model.Root.Pivot)
+	}
+	if pivot, ok := model.Root.Pivot.(string); !ok || len(pivot) != 5 {
+		t.Errorf("pivot = %#v, want a zip code with leading zeros kept", model.Root.Pivot)
+	}
+	if !reflect.DeepEqual(model.Config.IgnoredAttributes, []string{"id"}) {
+		t.Errorf("IgnoredAttributes = %v, want [id]", model.Config.IgnoredAttributes)
+	}
+	if !reflect.DeepEqual(model.Metadata.FeatureNames, []string{"zip"}) {
+		t.Errorf("FeatureNames = %v, want [zip]", model.Metadata.FeatureNames)
+	}
+
+	bad := writeFile(t, dir, "bad.json", `{"zip": "date"}`)
+	if _, err := parseTrainFlags(flag.NewFlagSet("train", flag.ContinueOnError), []string{"--in", data, "--schema", bad}); err == nil ||
+		!strings.Contains(err.Error(), "unknown type") {
+		t.Errorf("expected an unknown type error, got %v", err)
+	}
+}
+
+func TestReadItems_TSVKeepsEmptyFields(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "data.tsv", "a\tb\tc\n1\t\tx\n")
+	opts, _ := newReadOptions("tsv", "")
+	items, _, err := readItems(path, opts)
+	if err != nil {
+		t.Fatalf("reading TSV failed: %v", err)
+	}
+	if v, ok := items[0]["b"]; !ok || v != nil || items[0]["c"] != "x" {
+		t.Fatalf("empty field not preserved: %v", items[0])
+	}
+}
+
+func TestNewReadOptions_InvalidDelimiter(t *testing.T) {
+	for _, d := range []string{";;", "ab", "\"", "\n"} {
+		if _, err := newReadOptions("csv", d); err == nil {
+			t.Errorf("expected error for delimiter %q", d)
+		}
+	}
+	if _, err := newReadOptions("xml", ""); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestReadItems_NAFlag(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "data.csv", "x,y,label\nNA,\"NA\",a\n?,2,b\n1,N/A,a\n")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	rf := addReadFlags(fs)
+	if err := fs.Parse([]string{"--na", "NA", "--na", "?,N/A"}); err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	opts, err := rf.options()
+	if err != nil {
+		t.Fatalf("options failed: %v", err)
+	}
+	items, _, err := readItems(path, opts)
+	if err != nil {
+		t.Fatalf("reading CSV failed: %v", err)
+	}
+	// Quoted "NA" is indistinguishable from bare NA once parsed and is missing too.
+	want := []dtree.TrainingItem{
+		{"x": nil, "y": nil, "label": "a"},
+		{"x": nil, "y": 2.0, "label": "b"},
+		{"x": 1.0, "y": nil, "label": "a"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("got %v, want %v", items, want)
+	}
+}
+
+func TestReadItems_InferTypes(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "data.jsonl",
+		`{"x": "3", "color": "red", "label": "a"}
+{"x": 3, "color": "blue", "label": "b"}
+{"x": 4.5, "color": 7, "label": "a"}
+`)
+	readWith := func(args ...string) ([]dtree.TrainingItem, error) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		rf := addReadFlags(fs)
+		if err := fs.Parse(append([]string{"--format", "jsonl"}, args...)); err != nil {
+			t.Fatalf("parse failed: %v", err)
+		}
+		opts, err := rf.options()
+		if err != nil {
+			return nil, err
+		}
+		items, _, err := readItems(path, opts)
+		return items, err
+	}
+
+	raw, err := readWith()
+	if err != nil {
+		t.Fatalf("reading JSONL failed: %v", err)
+	}
+	if raw[0]["x"] != "3" {
+		t.Fatalf("without --infer-types values should be untouched, got %#v", raw[0]["x"])
+	}
+
+	items, err := readWith("--infer-types")
+	if err != nil {
+		t.Fatalf("reading JSONL failed: %v", err)
+	}
+	want := []dtree.TrainingItem{
+		{"x": 3.0, "color": "red", "label": "a"},
+		{"x": 3.0, "color": "blue", "label": "b"},
+		{"x": 4.5, "color": "7", "label": "a"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("got %v, want %v", items, want)
+	}
+
+	// A numeric column that turns out not to be numeric past the sample fails.
+	if _, err := readWith("--infer-types", "--infer-rows", "1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path = writeFile(t, t.TempDir(), "bad.jsonl", "{\"x\": \"3\"}\n{\"x\": \"three\"}\n")
+	if _, err := readWith("--infer-types", "--infer-rows", "1"); err == nil {
+		t.Fatal("expected error for a value that does not match the inferred type")
+	}
+	if _, err := readWith("--infer-types", "--infer-rows", "0"); err == nil {
+		t.Fatal("expected error for non-positive --infer-rows")
+	}
+}
+
+func TestPredictCSVRows_MatchesPredict(t *testing.T) {
+	model, err := dtree.LoadJSON(saveTestModel(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := writeFile(t, dir, "in.csv", "Outlook,Humidity,Note\nsunny,85,a\nrain,,b\novercast,96,\"c, d\"\nrain,70,e\n")
+	read, _ := newReadOptions("csv", "")
+	items, _, err := readItems(path, read)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := predictCSVRows(model, path, &buf, read, true, false); err != nil {
+		t.Fatalf("predictCSVRows failed: %v", err)
+	}
+	got, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Outlook", "Humidity", "Note", "prediction", "proba"}; !reflect.DeepEqual(got[0], want) {
+		t.Fatalf("header = %v, want %v", got[0], want)
+	}
+	if len(got) != len(items)+1 {
+		t.Fatalf("got %d rows, want %d", len(got)-1, len(items))
+	}
+	if got[3][2] != "c, d" || got[2][1] != "" {
+		t.Errorf("input cells not copied verbatim: %v", got[1:])
+	}
+	for i, it := range items {
+		pred, _ := model.Predict(it)
+		pb, _ := model.PredictProbaOrdered(it)
+		b, _ := json.Marshal(pb)
+		if row := got[i+1]; row[3] != pred || row[4] != string(b) {
+			t.Errorf("row %d = %v, want prediction %q proba %s", i+1, row, pred, b)
+		}
+	}
+
+	bad := writeFile(t, dir, "short.csv", "Outlook,Humidity\nsunny\n")
+	if err := predictCSVRows(model, bad, &bytes.Buffer{}, read, false, false); err == nil || !strings.Contains(err.Error(), "row 2") {
+		t.Errorf("expected a row 2 error for a short row, got %v", err)
+	}
+}
+
+func TestCompareModels(t *testing.T) {
+	dir := t.TempDir()
+	data := writeFile(t, dir, "data.csv", "Outlook,Humidity,Play\nsunny,85,no\nsunny,90,no\novercast,86,yes\nrain,96,yes\nrain,70,no\n")
+	read, _ := newReadOptions("csv", "")
+	good := saveTestModel(t)
+
+	var out bytes.Buffer
+	if err := compareModels(good, good, data, read, "", &out); err != nil {
+		t.Fatalf("compareModels failed: %v", err)
+	}
+	for _, want := range []string{"accuracy", "macro F1", "Disagreements: 0 of 5 rows", "only A correct: 0"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("self-comparison output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "McNemar") {
+		t.Errorf("no McNemar statistic expected without disagreements:\n%s", out.String())
+	}
+
+	// A baseline always predicts the majority class, "no".
+	set, err := readTrainingSet(data, read, "Play", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := dtree.TrainBaseline(set, dtree.Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	worse := filepath.Join(dir, "baseline.json")
+	if err := baseline.SaveJSON(worse); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := compareModels(good, worse, data, read, "Play", &out); err != nil {
+		t.Fatalf("compareModels failed: %v", err)
+	}
+	for _, want := range []string{"Disagreements: 2 of 5 rows", "only A correct: 2", "only B correct: 0", "McNemar chi-square: 0.5000"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestValidateModel(t *testing.T) {
+	var out bytes.Buffer
+	if err := validateModel(saveTestModel(t), &out, true); err != nil {
+		t.Fatalf("valid model rejected: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "valid\n") || !strings.Contains(out.String(), "Total nodes:") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	dir := t.TempDir()
+	corrupt := writeFile(t, dir, "corrupt.json", `{"root":{"attribute":"x","predicateName":">=","pivot":1,"classCounts":{"a":1},"match":{"category":"a","classCounts":{"a":1}}},"config":{"categoryAttr":"label"}}`)
+	if err := validateModel(corrupt, &bytes.Buffer{}, false); err == nil || !strings.Contains(err.Error(), "missing one or both children") {
+		t.Errorf("expected a missing-children error, got %v", err)
+	}
+	if err := validateModel(writeFile(t, dir, "trunc.json", `{"root":`), &bytes.Buffer{}, false); err == nil {
+		t.Error("expected error for truncated JSON")
+	}
+
+	odd := writeFile(t, dir, "odd.json", `{"root":{"attribute":"x","predicateName":">=","pivot":1,"classCounts":{"a":2},`+
+		`"match":{"category":"a","classCounts":{}},"noMatch":{"category":"","classCounts":{"a":2}}},"config":{"categoryAttr":"label"}}`)
+	if err := validateModel(odd, &bytes.Buffer{}, false); err != nil {
+		t.Fatalf("suspicious model should pass without --strict: %v", err)
+	}
+	out.Reset()
+	err := validateModel(odd, &out, true)
+	if err == nil {
+		t.Fatal("expected --strict to reject suspicious leaves")
+	}
+	for _, want := range []string{"root/yes: leaf has zero class counts", "root/no: leaf has an empty category"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	var buf bytes.Buffer
+	cb := progressLine(&buf)
+	cb(1, 4)
+	cb(4, 4)
+	if got, want := buf.String(), "\rPredicting:  25% (1/4 rows)\rPredicting: 100% (4/4 rows)\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestWriteImportance(t *testing.T) {
+	model, err := dtree.LoadJSON(saveTestModel(t))
+	if err != nil {
+		t.Fatalf("failed to load model: %v", err)
+	}
+	top := model.TopFeatures(1)
+	if len(top) != 1 {
+		t.Fatalf("expected a top feature, got %v", top)
+	}
+
+	var buf bytes.Buffer
+	if err := writeImportance(&buf, "impurity", model.FeatureImportance(), false); err != nil {
+		t.Fatalf("writeImportance failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) < 2 || !strings.Contains(lines[0], "Importance (impurity)") || !strings.HasPrefix(lines[1], top[0]+" ") {
+		t.Fatalf("expected %q in the first row:\n%s", top[0], buf.String())
+	}
+
+	buf.Reset()
+	imp := map[string]float64{"a": 0.1, "b": 0.5, "c": -0.02}
+	if err := writeImportance(&buf, "permutation", imp, true); err != nil {
+		t.Fatalf("writeImportance failed: %v", err)
+	}
+	var got struct {
+		Method   string `json:"method"`
+		Features []struct {
+			Feature    string  `json:"feature"`
+			Importance float64 `json:"importance"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if got.Method != "permutation" || len(got.Features) != 3 || got.Features[0].Feature != "b" || got.Features[2].Feature != "c" {
+		t.Fatalf("unexpected output: %+v", got)
+	}
+}
+
+func TestConvertModel_JSONGobJSON(t *testing.T) {
+	src := saveTestModel(t)
+	dir := t.TempDir()
+	gobPath := filepath.Join(dir, "model.gob")
+	back := filepath.Join(dir, "back.json.gz")
+	if err := convertModel(src, gobPath); err != nil {
+		t.Fatalf("json to gob failed: %v", err)
+	}
+	if err := convertModel(gobPath, back); err != nil {
+		t.Fatalf("gob to json failed: %v", err)
+	}
+	want, err := dtree.LoadJSON(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := dtree.LoadJSON(back)
+	if err != nil {
+		t.Fatalf("converted model does not load: %v", err)
+	}
+	if !want.Equal(got) {
+		t.Fatalf("conversion changed the model: %v", want.Diff(got))
+	}
+}
+
+func TestConvertModel_Errors(t *testing.T) {
+	src := saveTestModel(t)
+	dir := t.TempDir()
+	dot := filepath.Join(dir, "tree.dot")
+	if err := convertModel(src, dot); err != nil {
+		t.Fatalf("json to dot failed: %v", err)
+	}
+	for _, tc := range []struct{ in, out, want string }{
+		{dot, filepath.Join(dir, "model.json"), "cannot be recovered"},
+		{src, filepath.Join(dir, "model.pmml"), "not supported"},
+		{src, filepath.Join(dir, "model.txt"), "unknown output format"},
+		{filepath.Join(dir, "model.yaml"), filepath.Join(dir, "model.json"), "unknown input format"},
+	} {
+		err := convertModel(tc.in, tc.out)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("convertModel(%s, %s) = %v, want error containing %q", filepath.Base(tc.in), filepath.Base(tc.out), err, tc.want)
+		}
+	}
+	for path, want := range map[string]string{"a.JSON.GZ": "json", "a.gob": "gob", "a.svg": "svg", "a.gob.gz": ""} {
+		if got := modelFormat(path); got != want {
+			t.Errorf("modelFormat(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/kerneldump/dtree/dtree"
+)
+
+// serveCmd loads a model once and serves predictions over HTTP.
+func serveCmd(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	modelPath := fs.String("model", "", "model JSON file")
+	addr := fs.String("addr", ":8080", "listen address")
+	fs.Parse(args)
+
+	if *modelPath == "" {
+		fmt.Fprintln(os.Stderr, "--model is required")
+		os.Exit(1)
+	}
+	model, err := dtree.LoadJSON(*modelPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load model: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Serving %s on %s (POST /predict, GET /healthz)\n", *modelPath, *addr)
+	if err := http.ListenAndServe(*addr, newServer(model)); err != nil {
+		fmt.Fprintf(os.Stderr, "server failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// predictResponse is the body returned by POST /predict.
+type predictResponse struct {
+	Prediction string             `json:"prediction"`
+	Proba      map[string]float64 `json:"proba"`
+}
+
+// newServer returns the HTTP handler for the serve command.
+func newServer(model *dtree.Model) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("/predict", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, "use POST")
+			return
+		}
+		var item dtree.TrainingItem
+		if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+		pred, err := model.Predict(item)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		proba, err := model.PredictProba(item)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, predictResponse{Prediction: pred, Proba: proba})
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kerneldump/dtree/dtree"
+)
+
+func TestServer_Predict(t *testing.T) {
+	model, err := dtree.LoadJSON(saveTestModel(t))
+	if err != nil {
+		t.Fatalf("failed to load model: %v", err)
+	}
+	srv := httptest.NewServer(newServer(model))
+	defer srv.Close()
+
+	body := `{"Outlook": "rain", "Humidity": 96}`
+	resp, err := http.Post(srv.URL+"/predict", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var got predictResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("invalid response JSON: %v", err)
+	}
+
+	item := dtree.TrainingItem{"Outlook": "rain", "Humidity": 96.0}
+	wantPred, _ := model.Predict(item)
+	wantProba, _ := model.PredictProba(item)
+	if got.Prediction != wantPred {
+		t.Errorf("expected prediction %q, got %q", wantPred, got.Prediction)
+	}
+	for k, v := range wantProba {
+		if got.Proba[k] != v {
+			t.Errorf("proba[%s]: expected %v, got %v", k, v, got.Proba[k])
+		}
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	model, err := dtree.LoadJSON(saveTestModel(t))
+	if err != nil {
+		t.Fatalf("failed to load model: %v", err)
+	}
+	h := newServer(model)
+
+	cases := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/predict", `{"Outlook":`, http.StatusBadRequest},
+		{http.MethodPost, "/predict", `null`, http.StatusUnprocessableEntity},
+		{http.MethodGet, "/predict", ``, http.StatusMethodNotAllowed},
+		{http.MethodGet, "/healthz", ``, http.StatusOK},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s %q: expected %d, got %d (%s)", tc.method, tc.path, tc.body, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+}
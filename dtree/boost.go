@@ -0,0 +1,203 @@
+package dtree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// boostWeightAttr is the attribute TrainAdaBoost uses to pass sample weights
+// to Train when cfg.WeightAttr is not set.
+const boostWeightAttr = "__boost_weight"
+
+// BoostedModel is an AdaBoost ensemble: each tree votes for its predicted
+// class with its weight in Alphas.
+type BoostedModel struct {
+	Trees   []*Model  `json:"trees"`
+	Alphas  []float64 `json:"alphas"`
+	Classes []string  `json:"classes"`
+	Config  Config    `json:"config"`
+}
+
+// TrainAdaBoost trains up to rounds trees with discrete AdaBoost, reweighting
+// the samples after each round so the next tree focuses on the items the
+// previous ones got wrong. Trees are stumps unless cfg.MaxDepth asks for
+// deeper ones. If cfg.WeightAttr is set, its values are the initial weights.
+// Only binary classification is supported. Boosting stops early once a tree
+// classifies every sample correctly or does no better than chance.
+func TrainAdaBoost(set TrainingSet, cfg Config, rounds int) (*BoostedModel, error) {
+	if len(set) == 0 {
+		return nil, errors.New("training set cannot be empty")
+	}
+	if rounds <= 0 {
+		return nil, errors.New("rounds must be positive")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	counts := counterUniqueValues(set, cfg.CategoryAttr)
+	if len(counts) != 2 {
+		return nil, fmt.Errorf("AdaBoost supports binary classification only, found %d classes", len(counts))
+	}
+	classes := make([]string, 0, 2)
+	for c := range counts {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+
+	if cfg.MaxDepth == 0 {
+		cfg.MaxDepth = 1
+	}
+	treeCfg := cfg
+	if treeCfg.WeightAttr == "" {
+		treeCfg.WeightAttr = boostWeightAttr
+	} else if err := checkWeights(set, cfg.WeightAttr); err != nil {
+		return nil, err
+	}
+
+	// Work on copies so the caller's items never see the weight attribute.
+	weighted := make(TrainingSet, len(set))
+	weights := make([]float64, len(set))
+	b := &builder{cfg: treeCfg}
+	for i, item := range set {
+		weights[i] = b.weight(item)
+		cp := make(TrainingItem, len(item)+1)
+		for k, v := range item {
+			cp[k] = v
+		}
+		weighted[i] = cp
+	}
+	normalizeWeights(weights)
+
+	boosted := &BoostedModel{Classes: classes, Config: cfg}
+	wrong := make([]bool, len(set))
+	for r := 0; r < rounds; r++ {
+		for i, item := range weighted {
+			item[treeCfg.WeightAttr] = weights[i]
+		}
+		tree, err := Train(weighted, treeCfg)
+		if err != nil {
+			return nil, err
+		}
+		errRate := 0.0
+		for i, item := range set {
+			pred, err := tree.Predict(item)
+			if err != nil {
+				return nil, err
+			}
+			wrong[i] = pred != valueKey(item[cfg.CategoryAttr])
+			if wrong[i] {
+				errRate += weights[i]
+			}
+		}
+		if errRate >= 0.5 {
+			break // no better than chance; further rounds cannot help
+		}
+		// Clamp so a perfect tree gets a large but finite vote.
+		alpha := 0.5 * math.Log((1-errRate)/math.Max(errRate, 1e-10))
+		boosted.Trees = append(boosted.Trees, tree)
+		boosted.Alphas = append(boosted.Alphas, alpha)
+		if errRate == 0 {
+			break
+		}
+		for i := range weights {
+			if wrong[i] {
+				weights[i] *= math.Exp(alpha)
+			} else {
+				weights[i] *= math.Exp(-alpha)
+			}
+		}
+		normalizeWeights(weights)
+	}
+	if len(boosted.Trees) == 0 {
+		return nil, errors.New("no tree did better than chance on the training set")
+	}
+	return boosted, nil
+}
+
+func normalizeWeights(w []float64) {
+	total := 0.0
+	for _, v := range w {
+		total += v
+	}
+	if total == 0 {
+		for i := range w {
+			w[i] = 1 / float64(len(w))
+		}
+		return
+	}
+	for i := range w {
+		w[i] /= total
+	}
+}
+
+// Predict returns the class with the largest total vote weight.
+func (bm *BoostedModel) Predict(item TrainingItem) (string, error) {
+	if bm == nil || len(bm.Trees) == 0 {
+		return "", errors.New("boosted model has no trees")
+	}
+	votes := make(map[string]float64, len(bm.Classes))
+	for i, tree := range bm.Trees {
+		pred, err := tree.Predict(item)
+		if err != nil {
+			return "", err
+		}
+		votes[pred] += bm.Alphas[i]
+	}
+	return heaviestClass(votes), nil
+}
+
+// SaveJSON writes the boosted model to a JSON file, gzip-compressed when the
+// path ends in ".gz".
+func (bm *BoostedModel) SaveJSON(path string) error {
+	return writeJSONFile(path, bm)
+}
+
+// LoadBoostedJSON reads a boosted model from a JSON file and validates it.
+func LoadBoostedJSON(path string) (*BoostedModel, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return DecodeBoostedJSON(f)
+}
+
+// DecodeBoostedJSON decodes a boosted model from any reader and validates it.
+func DecodeBoostedJSON(r io.Reader) (*BoostedModel, error) {
+	r, err := MaybeGunzip(r)
+	if err != nil {
+		return nil, err
+	}
+	var bm BoostedModel
+	if err := json.NewDecoder(r).Decode(&bm); err != nil {
+		return nil, err
+	}
+	if err := bm.Validate(); err != nil {
+		return nil, err
+	}
+	return &bm, nil
+}
+
+// Validate checks that the ensemble and each of its trees are usable.
+func (bm *BoostedModel) Validate() error {
+	if bm == nil {
+		return errors.New("boosted model is nil")
+	}
+	if len(bm.Trees) == 0 {
+		return errors.New("boosted model has no trees")
+	}
+	if len(bm.Alphas) != len(bm.Trees) {
+		return errors.New("boosted model has mismatched trees and alphas")
+	}
+	for i, tree := range bm.Trees {
+		if err := tree.Validate(); err != nil {
+			return fmt.Errorf("tree %d: %w", i, err)
+		}
+	}
+	return nil
+}
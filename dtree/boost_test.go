@@ -0,0 +1,132 @@
+package dtree
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// diagonalSet labels points by x+y >= 1 and flips a few labels as noise.
+// A single axis-aligned stump cannot follow the diagonal boundary.
+func diagonalSet(n int, seed int64) TrainingSet {
+	rng := rand.New(rand.NewSource(seed))
+	ts := make(TrainingSet, n)
+	for i := range ts {
+		x, y := rng.Float64(), rng.Float64()
+		label := "neg"
+		if x+y >= 1 {
+			label = "pos"
+		}
+		if rng.Float64() < 0.05 {
+			if label == "pos" {
+				label = "neg"
+			} else {
+				label = "pos"
+			}
+		}
+		ts[i] = TrainingItem{"x": x, "y": y, "label": label}
+	}
+	return ts
+}
+
+func accuracy(t *testing.T, predict func(TrainingItem) (string, error), set TrainingSet) float64 {
+	t.Helper()
+	correct := 0
+	for _, item := range set {
+		pred, err := predict(item)
+		if err != nil {
+			t.Fatalf("predict failed: %v", err)
+		}
+		if pred == item["label"] {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(set))
+}
+
+func TestTrainAdaBoost_BeatsStump(t *testing.T) {
+	train, test := diagonalSet(300, 1), diagonalSet(300, 2)
+	cfg := Config{CategoryAttr: "label"}
+
+	stump, err := Train(train, Config{CategoryAttr: "label", MaxDepth: 1})
+	if err != nil {
+		t.Fatalf("stump training failed: %v", err)
+	}
+	boosted, err := TrainAdaBoost(train, cfg, 30)
+	if err != nil {
+		t.Fatalf("boosting failed: %v", err)
+	}
+	if len(boosted.Trees) != len(boosted.Alphas) || len(boosted.Trees) < 2 {
+		t.Fatalf("expected several trees with alphas, got %d/%d", len(boosted.Trees), len(boosted.Alphas))
+	}
+
+	stumpAcc := accuracy(t, stump.Predict, test)
+	boostAcc := accuracy(t, boosted.Predict, test)
+	if boostAcc <= stumpAcc {
+		t.Fatalf("expected boosting to beat a single stump: %.3f <= %.3f", boostAcc, stumpAcc)
+	}
+	for _, item := range train {
+		if _, ok := item[boostWeightAttr]; ok {
+			t.Fatal("training items must not be modified")
+		}
+	}
+}
+
+func TestTrainAdaBoost_MultiClassError(t *testing.T) {
+	ts := TrainingSet{
+		TrainingItem{"x": 1.0, "label": "a"},
+		TrainingItem{"x": 2.0, "label": "b"},
+		TrainingItem{"x": 3.0, "label": "c"},
+	}
+	if _, err := TrainAdaBoost(ts, Config{CategoryAttr: "label"}, 5); err == nil {
+		t.Fatal("expected error for multi-class labels")
+	}
+}
+
+func TestBoostedModel_SaveLoad(t *testing.T) {
+	train := diagonalSet(100, 3)
+	boosted, err := TrainAdaBoost(train, Config{CategoryAttr: "label"}, 10)
+	if err != nil {
+		t.Fatalf("boosting failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "boosted.json")
+	if err := boosted.SaveJSON(path); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	loaded, err := LoadBoostedJSON(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	for _, item := range train {
+		want, _ := boosted.Predict(item)
+		got, err := loaded.Predict(item)
+		if err != nil || got != want {
+			t.Fatalf("loaded model predicts %q (%v), want %q", got, err, want)
+		}
+	}
+}
+
+func TestTrain_WeightAttr(t *testing.T) {
+	ts := TrainingSet{
+		TrainingItem{"x": 1.0, "label": "a", "w": 1.0},
+		TrainingItem{"x": 1.0, "label": "a", "w": 1.0},
+		TrainingItem{"x": 1.0, "label": "b", "w": 5.0},
+	}
+	model, err := Train(ts, Config{CategoryAttr: "label", WeightAttr: "w"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if model.Root.Category != "b" {
+		t.Fatalf("expected heavier class b, got %q", model.Root.Category)
+	}
+	for _, f := range model.Metadata.FeatureNames {
+		if f == "w" {
+			t.Fatal("weight attribute must not be a feature")
+		}
+	}
+
+	ts[0]["w"] = -1.0
+	if _, err := Train(ts, Config{CategoryAttr: "label", WeightAttr: "w"}); err == nil {
+		t.Fatal("expected error for negative weight")
+	}
+}
@@ -0,0 +1,249 @@
+package dtree
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Calibration methods accepted by Model.Calibrate.
+const (
+	CalibrationPlatt    = "platt"
+	CalibrationIsotonic = "isotonic"
+)
+
+// CalibratedModel wraps a binary model and remaps its positive-class
+// probability through a mapping fitted on validation data.
+type CalibratedModel struct {
+	Model  *Model `json:"model"`
+	Method string `json:"method"`
+	// Classes holds the negative and positive class, in that order.
+	Classes [2]string `json:"classes"`
+
+	// Platt scaling: p = 1 / (1 + exp(A*f + B)).
+	A float64 `json:"a,omitempty"`
+	B float64 `json:"b,omitempty"`
+
+	// Isotonic regression: a non-decreasing piecewise-linear map through
+	// (Thresholds[i], Values[i]).
+	Thresholds []float64 `json:"thresholds,omitempty"`
+	Values     []float64 `json:"values,omitempty"`
+}
+
+// Calibrate fits a probability calibration for a binary model on valid, a
+// labelled set not used for training. The model's PredictProba output for
+// the positive class (the lexicographically larger class) is the input to
+// the mapping. method is CalibrationPlatt (logistic) or CalibrationIsotonic.
+func (m *Model) Calibrate(valid TrainingSet, method string) (*CalibratedModel, error) {
+	if m == nil || m.Root == nil {
+		return nil, errors.New("model is nil")
+	}
+	if method != CalibrationPlatt && method != CalibrationIsotonic {
+		return nil, fmt.Errorf("unknown calibration method %q (want platt or isotonic)", method)
+	}
+	classes := m.classUniverse()
+	if len(classes) != 2 {
+		return nil, fmt.Errorf("calibration supports binary classification only, model has %d classes", len(classes))
+	}
+	if len(valid) == 0 {
+		return nil, errors.New("validation set cannot be empty")
+	}
+
+	cm := &CalibratedModel{Model: m, Method: method, Classes: [2]string{classes[0], classes[1]}}
+	scores := make([]float64, len(valid))
+	labels := make([]float64, len(valid))
+	for i, item := range valid {
+		v, ok := item[m.Config.CategoryAttr]
+		if !ok || v == nil {
+			return nil, fmt.Errorf("item %d has no %q label", i, m.Config.CategoryAttr)
+		}
+		proba, err := m.PredictProba(item)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		scores[i] = proba[cm.Classes[1]]
+		if valueKey(v) == cm.Classes[1] {
+			labels[i] = 1
+		}
+	}
+
+	if method == CalibrationPlatt {
+		cm.A, cm.B = fitPlatt(scores, labels)
+	} else {
+		cm.Thresholds, cm.Values = fitIsotonic(scores, labels)
+	}
+	return cm, nil
+}
+
+// PredictProba returns calibrated probabilities for both classes.
+func (cm *CalibratedModel) PredictProba(item TrainingItem) (map[string]float64, error) {
+	proba, err := cm.Model.PredictProba(item)
+	if err != nil {
+		return nil, err
+	}
+	p := cm.calibrate(proba[cm.Classes[1]])
+	return map[string]float64{cm.Classes[0]: 1 - p, cm.Classes[1]: p}, nil
+}
+
+// Predict returns the class with the higher calibrated probability.
+func (cm *CalibratedModel) Predict(item TrainingItem) (string, error) {
+	proba, err := cm.PredictProba(item)
+	if err != nil {
+		return "", err
+	}
+	if proba[cm.Classes[1]] >= 0.5 {
+		return cm.Classes[1], nil
+	}
+	return cm.Classes[0], nil
+}
+
+func (cm *CalibratedModel) calibrate(f float64) float64 {
+	if cm.Method == CalibrationPlatt {
+		return sigmoid(-(cm.A*f + cm.B))
+	}
+	return interpolate(cm.Thresholds, cm.Values, f)
+}
+
+func sigmoid(x float64) float64 { return 1 / (1 + math.Exp(-x)) }
+
+// fitPlatt fits p = 1/(1+exp(A*f+B)) by Newton's method with backtracking,
+// following Lin, Lin and Weng's refinement of Platt's algorithm, including
+// Platt's smoothed targets to avoid overfitting the extremes.
+func fitPlatt(f, y []float64) (float64, float64) {
+	var pos, neg float64
+	for _, v := range y {
+		if v == 1 {
+			pos++
+		} else {
+			neg++
+		}
+	}
+	hi, lo := (pos+1)/(pos+2), 1/(neg+2)
+	t := make([]float64, len(y))
+	for i, v := range y {
+		if v == 1 {
+			t[i] = hi
+		} else {
+			t[i] = lo
+		}
+	}
+
+	// loss is the cross-entropy of the sigmoid against the smoothed targets,
+	// written to stay finite for large |A*f+B|.
+	loss := func(a, b float64) float64 {
+		var l float64
+		for i := range f {
+			z := a*f[i] + b
+			if z >= 0 {
+				l += t[i]*z + math.Log1p(math.Exp(-z))
+			} else {
+				l += (t[i]-1)*z + math.Log1p(math.Exp(z))
+			}
+		}
+		return l
+	}
+
+	const sigma = 1e-12
+	a, b := 0.0, math.Log((neg+1)/(pos+1))
+	fval := loss(a, b)
+	for iter := 0; iter < 100; iter++ {
+		// Gradient and Hessian of the loss.
+		h11, h22, h21, g1, g2 := sigma, sigma, 0.0, 0.0, 0.0
+		for i := range f {
+			p := sigmoid(-(a*f[i] + b))
+			d2 := p * (1 - p)
+			h11 += f[i] * f[i] * d2
+			h22 += d2
+			h21 += f[i] * d2
+			d1 := t[i] - p
+			g1 += f[i] * d1
+			g2 += d1
+		}
+		if math.Abs(g1) < 1e-5 && math.Abs(g2) < 1e-5 {
+			break
+		}
+		det := h11*h22 - h21*h21
+		dA := -(h22*g1 - h21*g2) / det
+		dB := -(-h21*g1 + h11*g2) / det
+		gd := g1*dA + g2*dB
+		step := 1.0
+		for step >= 1e-10 {
+			na, nb := a+step*dA, b+step*dB
+			if nf := loss(na, nb); nf < fval+1e-4*step*gd {
+				a, b, fval = na, nb, nf
+				break
+			}
+			step /= 2
+		}
+		if step < 1e-10 {
+			break
+		}
+	}
+	return a, b
+}
+
+// fitIsotonic runs pool-adjacent-violators on (f, y) and returns the distinct
+// inputs in ascending order with their fitted non-decreasing values.
+func fitIsotonic(f, y []float64) ([]float64, []float64) {
+	idx := make([]int, len(f))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return f[idx[i]] < f[idx[j]] })
+
+	type block struct {
+		xs     []float64
+		sum, n float64
+	}
+	var blocks []block
+	for _, i := range idx {
+		if len(blocks) > 0 {
+			last := &blocks[len(blocks)-1]
+			if last.xs[len(last.xs)-1] == f[i] {
+				last.sum += y[i]
+				last.n++
+				continue
+			}
+		}
+		blocks = append(blocks, block{xs: []float64{f[i]}, sum: y[i], n: 1})
+		// Merge backwards while the means decrease.
+		for len(blocks) > 1 {
+			prev, cur := blocks[len(blocks)-2], blocks[len(blocks)-1]
+			if prev.sum/prev.n <= cur.sum/cur.n {
+				break
+			}
+			merged := block{xs: append(prev.xs, cur.xs...), sum: prev.sum + cur.sum, n: prev.n + cur.n}
+			blocks = append(blocks[:len(blocks)-2], merged)
+		}
+	}
+
+	var xs, vs []float64
+	for _, bl := range blocks {
+		for _, x := range bl.xs {
+			xs = append(xs, x)
+			vs = append(vs, bl.sum/bl.n)
+		}
+	}
+	return xs, vs
+}
+
+// interpolate evaluates the piecewise-linear function through (xs, vs) at x,
+// clamping outside the fitted range.
+func interpolate(xs, vs []float64, x float64) float64 {
+	if len(xs) == 0 {
+		return x
+	}
+	if x <= xs[0] {
+		return vs[0]
+	}
+	if x >= xs[len(xs)-1] {
+		return vs[len(vs)-1]
+	}
+	i := sort.SearchFloat64s(xs, x)
+	if xs[i] == x {
+		return vs[i]
+	}
+	frac := (x - xs[i-1]) / (xs[i] - xs[i-1])
+	return vs[i-1] + frac*(vs[i]-vs[i-1])
+}
@@ -0,0 +1,79 @@
+package dtree
+
+import "testing"
+
+func brierScore(t *testing.T, proba func(TrainingItem) (map[string]float64, error), set TrainingSet, positive string) float64 {
+	t.Helper()
+	sum := 0.0
+	for _, item := range set {
+		p, err := proba(item)
+		if err != nil {
+			t.Fatalf("predict proba failed: %v", err)
+		}
+		y := 0.0
+		if item["label"] == positive {
+			y = 1
+		}
+		d := p[positive] - y
+		sum += d * d
+	}
+	return sum / float64(len(set))
+}
+
+func TestCalibrate_ReducesBrierScore(t *testing.T) {
+	// Fully grown trees memorize the label noise and output 0/1 probabilities.
+	train, valid, test := diagonalSet(300, 11), diagonalSet(400, 12), diagonalSet(400, 13)
+	model, err := Train(train, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	raw := brierScore(t, model.PredictProba, test, "pos")
+
+	for _, method := range []string{CalibrationPlatt, CalibrationIsotonic} {
+		cm, err := model.Calibrate(valid, method)
+		if err != nil {
+			t.Fatalf("%s: calibrate failed: %v", method, err)
+		}
+		if cm.Classes != [2]string{"neg", "pos"} {
+			t.Fatalf("%s: unexpected classes %v", method, cm.Classes)
+		}
+		calibrated := brierScore(t, cm.PredictProba, test, "pos")
+		if calibrated >= raw {
+			t.Errorf("%s: Brier score %.4f did not improve on raw %.4f", method, calibrated, raw)
+		}
+	}
+}
+
+func TestCalibrate_Errors(t *testing.T) {
+	multi := TrainingSet{
+		TrainingItem{"x": 1.0, "label": "a"},
+		TrainingItem{"x": 2.0, "label": "b"},
+		TrainingItem{"x": 3.0, "label": "c"},
+	}
+	model, _ := Train(multi, Config{CategoryAttr: "label"})
+	if _, err := model.Calibrate(multi, CalibrationPlatt); err == nil {
+		t.Error("expected error for multi-class model")
+	}
+	binary, _ := Train(diagonalSet(50, 1), Config{CategoryAttr: "label"})
+	if _, err := binary.Calibrate(diagonalSet(50, 2), "beta"); err == nil {
+		t.Error("expected error for unknown method")
+	}
+}
+
+func TestFitIsotonic_Monotone(t *testing.T) {
+	xs, vs := fitIsotonic([]float64{0.1, 0.2, 0.3, 0.4, 0.4}, []float64{0, 1, 0, 1, 1})
+	if len(xs) != 4 {
+		t.Fatalf("expected 4 distinct inputs, got %v", xs)
+	}
+	for i := 1; i < len(vs); i++ {
+		if vs[i] < vs[i-1] {
+			t.Fatalf("fitted values not monotone: %v", vs)
+		}
+	}
+	if vs[1] != 0.5 || vs[2] != 0.5 || vs[3] != 1 {
+		t.Fatalf("unexpected fit %v", vs)
+	}
+	if got := interpolate(xs, vs, 0.05); got != 0 {
+		t.Fatalf("expected clamping below range, got %v", got)
+	}
+}
@@ -0,0 +1,107 @@
+package dtree
+
+import (
+	"math"
+	"sort"
+)
+
+// splitPValue returns the p-value of Pearson's chi-square test of
+// independence between a split's branches and the class, given the class
+// counts of each branch. It returns 1 when the table has fewer than two
+// non-empty rows or columns, as such a split carries no evidence.
+func splitPValue(branches []map[string]int) float64 {
+	colTotals := make(map[string]float64)
+	rowTotals := make([]float64, len(branches))
+	total := 0.0
+	rows := 0
+	for i, counts := range branches {
+		for c, n := range counts {
+			colTotals[c] += float64(n)
+			rowTotals[i] += float64(n)
+		}
+		if rowTotals[i] > 0 {
+			rows++
+		}
+		total += rowTotals[i]
+	}
+	// Sum in a fixed class order so the result is deterministic.
+	classes := make([]string, 0, len(colTotals))
+	for c, n := range colTotals {
+		if n > 0 {
+			classes = append(classes, c)
+		}
+	}
+	sort.Strings(classes)
+	if rows < 2 || len(classes) < 2 {
+		return 1
+	}
+
+	stat := 0.0
+	for i, counts := range branches {
+		if rowTotals[i] == 0 {
+			continue
+		}
+		for _, c := range classes {
+			expected := rowTotals[i] * colTotals[c] / total
+			d := float64(counts[c]) - expected
+			stat += d * d / expected
+		}
+	}
+	return chiSquareSurvival(stat, float64((rows-1)*(len(classes)-1)))
+}
+
+// chiSquareSurvival returns P(X >= x) for X chi-square distributed with df
+// degrees of freedom.
+func chiSquareSurvival(x, df float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	return gammaQ(df/2, x/2)
+}
+
+// gammaQ is the regularized upper incomplete gamma function Q(a, x), computed
+// by its series for x < a+1 and its continued fraction otherwise.
+func gammaQ(a, x float64) float64 {
+	const (
+		maxIter = 500
+		eps     = 1e-14
+		tiny    = 1e-300
+	)
+	lg, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lg)
+	if x < a+1 {
+		sum, term := 1/a, 1/a
+		for n := 1; n < maxIter; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*eps {
+				break
+			}
+		}
+		return 1 - sum*prefix
+	}
+	// Modified Lentz's method.
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < maxIter; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < eps {
+			break
+		}
+	}
+	return prefix * h
+}
@@ -0,0 +1,85 @@
+package dtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestChiSquareSurvival(t *testing.T) {
+	// Critical values at the 5% and 1% levels.
+	for _, tc := range []struct{ x, df, want float64 }{
+		{3.841, 1, 0.05},
+		{5.991, 2, 0.05},
+		{18.307, 10, 0.05},
+		{6.635, 1, 0.01},
+		{0.5, 4, 0.9735},
+	} {
+		if got := chiSquareSurvival(tc.x, tc.df); math.Abs(got-tc.want) > 1e-3 {
+			t.Errorf("chiSquareSurvival(%v, %v) = %.4f, want %.4f", tc.x, tc.df, got, tc.want)
+		}
+	}
+}
+
+func TestSplitPValue(t *testing.T) {
+	strong := splitPValue([]map[string]int{{"a": 40, "b": 2}, {"a": 3, "b": 45}})
+	if strong > 1e-6 {
+		t.Errorf("strongly associated split has p-value %g", strong)
+	}
+	none := splitPValue([]map[string]int{{"a": 20, "b": 20}, {"a": 10, "b": 10}})
+	if none < 0.99 {
+		t.Errorf("independent split has p-value %g, want about 1", none)
+	}
+	if p := splitPValue([]map[string]int{{"a": 5}, {"a": 3}}); p != 1 {
+		t.Errorf("single-class table has p-value %g, want 1", p)
+	}
+}
+
+// noiseSet has a label driven by signal and an unrelated noise column.
+func noiseSet(n int, seed int64) TrainingSet {
+	rng := rand.New(rand.NewSource(seed))
+	ts := make(TrainingSet, n)
+	for i := range ts {
+		label := "a"
+		if rng.Float64() < 0.5 {
+			label = "b"
+		}
+		ts[i] = TrainingItem{"noise": float64(rng.Intn(10)), "label": label}
+	}
+	return ts
+}
+
+func TestChiSquarePValue_StopsNoiseSplits(t *testing.T) {
+	set := noiseSet(300, 1)
+	loose, err := Train(set, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if loose.Root.isLeaf() {
+		t.Fatal("without the test the tree should overfit the noise feature")
+	}
+	strict, err := Train(set, Config{CategoryAttr: "label", ChiSquarePValue: 0.001})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if !strict.Root.isLeaf() {
+		t.Fatalf("expected no split on pure noise, got root split %s", strict.Root.condition())
+	}
+
+	// A real signal still splits.
+	signal, err := Train(diagonalSet(300, 1), Config{CategoryAttr: "label", ChiSquarePValue: 0.001})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if signal.Root.isLeaf() {
+		t.Error("expected a significant split on the diagonal data")
+	}
+}
+
+func TestChiSquarePValue_Validate(t *testing.T) {
+	for _, p := range []float64{-0.1, 1.5, math.NaN()} {
+		if err := (Config{CategoryAttr: "label", ChiSquarePValue: p}).Validate(); err == nil {
+			t.Errorf("expected error for ChiSquarePValue=%v", p)
+		}
+	}
+}
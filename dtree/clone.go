@@ -0,0 +1,129 @@
+package dtree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Clone returns a deep copy of the model: the config, metadata and every tree
+// node, including ClassCounts, OutputCounts and Children maps and PivotSet
+// and Categories slices. Pivots are scalar values and are copied as is.
+// Changes to the clone never affect m.
+func (m *Model) Clone() *Model {
+	if m == nil {
+		return nil
+	}
+	cp := &Model{Root: cloneNode(m.Root), Config: m.Config}
+	cp.Config.IgnoredAttributes = cloneStrings(m.Config.IgnoredAttributes)
+	cp.Config.CategoryAttrs = cloneStrings(m.Config.CategoryAttrs)
+	cp.Config.LexicographicFeatures = cloneStrings(m.Config.LexicographicFeatures)
+	if m.Config.MonotoneConstraints != nil {
+		cp.Config.MonotoneConstraints = make(map[string]int, len(m.Config.MonotoneConstraints))
+		for k, v := range m.Config.MonotoneConstraints {
+			cp.Config.MonotoneConstraints[k] = v
+		}
+	}
+	if m.Config.OrdinalFeatures != nil {
+		cp.Config.OrdinalFeatures = make(map[string][]string, len(m.Config.OrdinalFeatures))
+		for k, levels := range m.Config.OrdinalFeatures {
+			cp.Config.OrdinalFeatures[k] = cloneStrings(levels)
+		}
+	}
+	if m.Metadata != nil {
+		meta := *m.Metadata
+		meta.FeatureNames = cloneStrings(m.Metadata.FeatureNames)
+		meta.Warnings = cloneStrings(m.Metadata.Warnings)
+		meta.Classes = cloneStrings(m.Metadata.Classes)
+		cp.Metadata = &meta
+	}
+	return cp
+}
+
+func cloneNode(n *TreeItem) *TreeItem {
+	if n == nil {
+		return nil
+	}
+	cp := *n
+	cp.Match = cloneNode(n.Match)
+	cp.NoMatch = cloneNode(n.NoMatch)
+	cp.PivotSet = cloneStrings(n.PivotSet)
+	cp.Categories = cloneStrings(n.Categories)
+	cp.Missing = cloneNode(n.Missing)
+	if n.Children != nil {
+		cp.Children = make(map[string]*TreeItem, len(n.Children))
+		for k, c := range n.Children {
+			cp.Children[k] = cloneNode(c)
+		}
+	}
+	if n.Oblique != nil {
+		o := *n.Oblique
+		o.Attributes = cloneStrings(o.Attributes)
+		o.Weights = append([]float64(nil), o.Weights...)
+		cp.Oblique = &o
+	}
+	if n.ClassCounts != nil {
+		cp.ClassCounts = make(map[string]int, len(n.ClassCounts))
+		for k, v := range n.ClassCounts {
+			cp.ClassCounts[k] = v
+		}
+	}
+	if n.OutputCounts != nil {
+		cp.OutputCounts = make(map[string]map[string]int, len(n.OutputCounts))
+		for attr, counts := range n.OutputCounts {
+			c := make(map[string]int, len(counts))
+			for k, v := range counts {
+				c[k] = v
+			}
+			cp.OutputCounts[attr] = c
+		}
+	}
+	return &cp
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s...)
+}
+
+// Subtree returns a new model whose root is a deep copy of the node with the
+// given ID, keeping m's config. Node IDs are kept, so leaves can still be
+// traced back to the original tree. It fails if no node has that ID or the
+// node is not a valid root.
+func (m *Model) Subtree(nodeID int) (*Model, error) {
+	if m == nil || m.Root == nil {
+		return nil, errors.New("model has nil root node")
+	}
+	node := findByID(m.Root, nodeID)
+	if node == nil {
+		return nil, fmt.Errorf("node %d not found", nodeID)
+	}
+	sub := m.Clone()
+	sub.Root = cloneNode(node)
+	if sub.Metadata != nil {
+		sub.Metadata.NumSamples = 0
+		for _, c := range node.ClassCounts {
+			sub.Metadata.NumSamples += c
+		}
+	}
+	if err := sub.Validate(); err != nil {
+		return nil, fmt.Errorf("node %d cannot be a root: %w", nodeID, err)
+	}
+	return sub, nil
+}
+
+func findByID(n *TreeItem, id int) *TreeItem {
+	if n == nil {
+		return nil
+	}
+	if n.ID == id {
+		return n
+	}
+	for _, b := range n.branches() {
+		if found := findByID(b.node, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
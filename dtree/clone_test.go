@@ -0,0 +1,113 @@
+package dtree
+
+import "testing"
+
+func TestClone_Independent(t *testing.T) {
+	cfg := Config{CategoryAttr: "Play", IgnoredAttributes: []string{"Day"}}
+	model, err := Train(playTennisSet(), cfg)
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	clone := model.Clone()
+	if err := clone.Validate(); err != nil {
+		t.Fatalf("clone should validate: %v", err)
+	}
+	if !model.Equal(clone) {
+		t.Fatalf("clone differs: %v", model.Diff(clone))
+	}
+	for _, item := range playTennisSet() {
+		want, _ := model.Predict(item)
+		got, _ := clone.Predict(item)
+		if got != want {
+			t.Fatalf("clone predicts %q, original %q", got, want)
+		}
+	}
+
+	leaf := clone.Root
+	for !leaf.isLeaf() {
+		leaf = leaf.Match
+	}
+	orig := model.Root
+	for !orig.isLeaf() {
+		orig = orig.Match
+	}
+	wantCategory := orig.Category
+	leaf.Category = "changed"
+	leaf.ClassCounts["changed"] = 99
+	clone.Config.IgnoredAttributes[0] = "Other"
+	clone.Metadata.FeatureNames[0] = "Other"
+
+	if orig.Category != wantCategory || orig.ClassCounts["changed"] != 0 {
+		t.Fatal("mutating the clone's leaf changed the original")
+	}
+	if model.Config.IgnoredAttributes[0] != "Day" || model.Metadata.FeatureNames[0] == "Other" {
+		t.Fatal("mutating the clone's config or metadata changed the original")
+	}
+}
+
+func TestClone_Nil(t *testing.T) {
+	var m *Model
+	if m.Clone() != nil {
+		t.Fatal("expected nil clone of nil model")
+	}
+}
+
+func TestSubtree_PredictsLikeBranch(t *testing.T) {
+	set := syntheticSet(400)
+	model, err := Train(set, Config{CategoryAttr: "label", MaxDepth: 5})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	branch := model.Root.Match
+	if branch == nil || branch.isLeaf() {
+		t.Fatal("expected an internal node below the root")
+	}
+	sub, err := model.Subtree(branch.ID)
+	if err != nil {
+		t.Fatalf("subtree failed: %v", err)
+	}
+	if sub.Root == branch || sub.Root.ID != branch.ID {
+		t.Fatal("subtree root should be a copy of the node, keeping its ID")
+	}
+
+	inBranch := make(map[int]bool)
+	var walk func(n *TreeItem)
+	walk = func(n *TreeItem) {
+		inBranch[n.ID] = true
+		for _, b := range n.branches() {
+			walk(b.node)
+		}
+	}
+	walk(branch)
+
+	routed := 0
+	for _, item := range set {
+		leafID, want, _ := model.PredictLeaf(item)
+		if !inBranch[leafID] {
+			continue
+		}
+		routed++
+		gotID, got, err := sub.PredictLeaf(item)
+		if err != nil {
+			t.Fatalf("subtree predict failed: %v", err)
+		}
+		if got != want || gotID != leafID {
+			t.Fatalf("subtree predicted %q (leaf %d), original %q (leaf %d)", got, gotID, want, leafID)
+		}
+	}
+	if routed == 0 {
+		t.Fatal("no items routed into the branch")
+	}
+
+	sub.Root.Category = "changed"
+	if branch.Category == "changed" {
+		t.Fatal("subtree must not share nodes with the original")
+	}
+}
+
+func TestSubtree_NotFound(t *testing.T) {
+	model, _ := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if _, err := model.Subtree(9999); err == nil {
+		t.Fatal("expected error for unknown node ID")
+	}
+}
@@ -0,0 +1,154 @@
+package dtree
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Column types returned by InferColumnTypes and accepted by CoerceTypes.
+const (
+	ColumnNumber = "number"
+	ColumnBool   = "bool"
+	ColumnString = "string"
+	// ColumnIgnore drops a column entirely. InferColumnTypes never returns it.
+	ColumnIgnore = "ignore"
+)
+
+// validColumnType reports whether typ is one of the Column* types.
+func validColumnType(typ string) bool {
+	switch typ {
+	case ColumnNumber, ColumnBool, ColumnString, ColumnIgnore:
+		return true
+	}
+	return false
+}
+
+// valueKind classifies a single value for type inference. Strings that parse
+// as numbers or booleans count as such, so "3" and 3 agree.
+func valueKind(v interface{}) string {
+	switch x := v.(type) {
+	case float64, float32, int, int32, int64:
+		return ColumnNumber
+	case bool:
+		return ColumnBool
+	case string:
+		if _, err := strconv.ParseFloat(x, 64); err == nil {
+			return ColumnNumber
+		}
+		if x == "true" || x == "false" {
+			return ColumnBool
+		}
+	}
+	return ColumnString
+}
+
+// InferColumnTypes decides a type for every column seen in the first
+// sampleRows items of set (all items if sampleRows <= 0). A column is a
+// number or bool column when all of its non-missing values are numbers or
+// booleans, either native or as strings; otherwise it is a string column.
+// Columns that mix kinds are reported in warnings, sorted by column name.
+func InferColumnTypes(set TrainingSet, sampleRows int) (map[string]string, []string) {
+	if sampleRows <= 0 || sampleRows > len(set) {
+		sampleRows = len(set)
+	}
+	kinds := make(map[string]map[string]bool)
+	for _, item := range set[:sampleRows] {
+		for k, v := range item {
+			if kinds[k] == nil {
+				kinds[k] = make(map[string]bool)
+			}
+			if v != nil {
+				kinds[k][valueKind(v)] = true
+			}
+		}
+	}
+
+	cols := make([]string, 0, len(kinds))
+	for col := range kinds {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+
+	types := make(map[string]string, len(kinds))
+	var warnings []string
+	for _, col := range cols {
+		seen := kinds[col]
+		switch {
+		case len(seen) == 1 && seen[ColumnNumber]:
+			types[col] = ColumnNumber
+		case len(seen) == 1 && seen[ColumnBool]:
+			types[col] = ColumnBool
+		default:
+			types[col] = ColumnString
+			if len(seen) > 1 {
+				warnings = append(warnings, fmt.Sprintf("column %q mixes %v values; reading it as string", col, sortedKeys(seen)))
+			}
+		}
+	}
+	return types, warnings
+}
+
+// CoerceTypes converts the values of set in place to the types given per
+// column: numeric strings become float64, "true"/"false" become bool, and
+// string columns get every value formatted as a string. ColumnIgnore columns
+// are deleted. Missing values and columns absent from types are left alone.
+// It fails on the first value that cannot be converted, naming the 0-based
+// item index.
+func CoerceTypes(set TrainingSet, types map[string]string) error {
+	cols := make([]string, 0, len(types))
+	for col, typ := range types {
+		if !validColumnType(typ) {
+			return fmt.Errorf("column %q has unknown type %q", col, typ)
+		}
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	for i, item := range set {
+		for _, col := range cols {
+			if types[col] == ColumnIgnore {
+				delete(item, col)
+				continue
+			}
+			v, ok := item[col]
+			if !ok || v == nil {
+				continue
+			}
+			cv, err := coerceValue(v, types[col])
+			if err != nil {
+				return fmt.Errorf("item %d: column %q: %w", i, col, err)
+			}
+			item[col] = cv
+		}
+	}
+	return nil
+}
+
+func coerceValue(v interface{}, typ string) (interface{}, error) {
+	switch typ {
+	case ColumnNumber:
+		if isNumeric(v) {
+			return toFloat(v), nil
+		}
+		if s, ok := v.(string); ok {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f, nil
+			}
+		}
+	case ColumnBool:
+		switch x := v.(type) {
+		case bool:
+			return x, nil
+		case string:
+			if x == "true" || x == "false" {
+				return x == "true", nil
+			}
+		}
+	default:
+		switch v.(type) {
+		case string, float64, float32, int, int32, int64, bool:
+			return valueKey(v), nil
+		}
+	}
+	return nil, fmt.Errorf("cannot convert %v to %s", v, typ)
+}
@@ -0,0 +1,92 @@
+package dtree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInferColumnTypes(t *testing.T) {
+	set := TrainingSet{
+		{"n": "3", "b": true, "s": "red", "mixed": 1.0, "label": "a"},
+		{"n": 4.5, "b": "false", "s": nil, "mixed": "blue", "label": "b"},
+		{"n": nil, "label": "a"},
+	}
+	types, warnings := InferColumnTypes(set, 0)
+	want := map[string]string{"n": ColumnNumber, "b": ColumnBool, "s": ColumnString, "mixed": ColumnString, "label": ColumnString}
+	for col, typ := range want {
+		if types[col] != typ {
+			t.Errorf("type of %q = %q, want %q", col, types[col], typ)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `"mixed"`) {
+		t.Fatalf("warnings = %q, want one for column mixed", warnings)
+	}
+
+	// Only the sampled rows decide the type.
+	types, warnings = InferColumnTypes(set, 1)
+	if types["mixed"] != ColumnNumber || len(warnings) != 0 {
+		t.Fatalf("sampling one row: types = %v, warnings = %q", types, warnings)
+	}
+}
+
+func TestCoerceTypes(t *testing.T) {
+	set := TrainingSet{
+		{"n": "3", "b": "true", "s": 2.0},
+		{"n": 3.0, "b": false, "s": "x"},
+		{"n": nil},
+	}
+	types := map[string]string{"n": ColumnNumber, "b": ColumnBool, "s": ColumnString}
+	if err := CoerceTypes(set, types); err != nil {
+		t.Fatalf("coerce failed: %v", err)
+	}
+	if set[0]["n"] != 3.0 || set[1]["n"] != 3.0 || set[2]["n"] != nil {
+		t.Errorf("numbers not coerced: %v", set)
+	}
+	if set[0]["b"] != true || set[1]["b"] != false {
+		t.Errorf("bools not coerced: %v", set)
+	}
+	if set[0]["s"] != "2" {
+		t.Errorf("string column value = %#v, want \"2\"", set[0]["s"])
+	}
+
+	bad := TrainingSet{{"n": 1.0}, {"n": "many"}}
+	if err := CoerceTypes(bad, map[string]string{"n": ColumnNumber}); err == nil || !strings.Contains(err.Error(), "item 1") {
+		t.Fatalf("expected error naming item 1, got %v", err)
+	}
+	if err := CoerceTypes(bad, map[string]string{"n": "date"}); err == nil {
+		t.Fatal("expected error for unknown type")
+	}
+}
+
+func TestCoerceTypes_ConsistentTraining(t *testing.T) {
+	// Without coercion "3" is a category and 3 a number, so the same value
+	// would be routed differently depending on how it was written.
+	var set TrainingSet
+	for i := 0; i < 40; i++ {
+		var x interface{} = float64(i % 10)
+		if i%2 == 0 {
+			x = valueKey(x)
+		}
+		label := "lo"
+		if i%10 >= 5 {
+			label = "hi"
+		}
+		set = append(set, TrainingItem{"x": x, "label": label})
+	}
+	types, _ := InferColumnTypes(set, 10)
+	if err := CoerceTypes(set, types); err != nil {
+		t.Fatalf("coerce failed: %v", err)
+	}
+	for i, item := range set {
+		if !isNumeric(item["x"]) {
+			t.Fatalf("item %d: x = %#v, want a number", i, item["x"])
+		}
+	}
+	model, err := Train(set, Config{CategoryAttr: "label", MinSamples: 1})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if model.Root.PredicateName != ">=" {
+		t.Fatalf("expected a numeric split at the root, got %q", model.Root.PredicateName)
+	}
+}
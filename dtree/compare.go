@@ -0,0 +1,147 @@
+package dtree
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Equal reports whether m and other have the same configuration and the same
+// tree, including split pivots, leaf categories and class counts. Metadata
+// such as the training time is ignored. Two nil models are equal.
+func (m *Model) Equal(other *Model) bool {
+	return len(m.Diff(other)) == 0
+}
+
+// Diff describes how other differs from m, one human-readable line per
+// difference, e.g. "root split changed from Outlook==sunny to Humidity>=75".
+// It returns nil when the models are equal. Below a changed split the
+// subtrees are not compared, since they no longer correspond.
+func (m *Model) Diff(other *Model) []string {
+	switch {
+	case m == nil && other == nil:
+		return nil
+	case m == nil:
+		return []string{"first model is nil"}
+	case other == nil:
+		return []string{"second model is nil"}
+	}
+	diffs := diffConfig(m.Config, other.Config)
+	return diffNode("root", m.Root, other.Root, diffs)
+}
+
+// diffConfig compares Config field by field. Nil and empty slices are equal.
+func diffConfig(a, b Config) []string {
+	var diffs []string
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		fa, fb := va.Field(i), vb.Field(i)
+		if fa.Kind() == reflect.Slice && fa.Len() == 0 && fb.Len() == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			diffs = append(diffs, fmt.Sprintf("config.%s changed from %v to %v",
+				va.Type().Field(i).Name, fa.Interface(), fb.Interface()))
+		}
+	}
+	return diffs
+}
+
+func diffNode(path string, a, b *TreeItem, diffs []string) []string {
+	switch {
+	case a == nil && b == nil:
+		return diffs
+	case a == nil:
+		return append(diffs, fmt.Sprintf("%s added: %s", path, nodeDesc(b)))
+	case b == nil:
+		return append(diffs, fmt.Sprintf("%s removed: %s", path, nodeDesc(a)))
+	}
+
+	if a.isLeaf() != b.isLeaf() || (!a.isLeaf() && !sameSplit(a, b)) {
+		return append(diffs, fmt.Sprintf("%s changed from %s to %s", path, nodeDesc(a), nodeDesc(b)))
+	}
+	if a.isLeaf() && a.Category != b.Category {
+		diffs = append(diffs, fmt.Sprintf("%s leaf category changed from %s to %s", path, a.Category, b.Category))
+	}
+	if !reflect.DeepEqual(a.ClassCounts, b.ClassCounts) && (len(a.ClassCounts) > 0 || len(b.ClassCounts) > 0) {
+		diffs = append(diffs, fmt.Sprintf("%s class counts changed from %s to %s",
+			path, formatCounts(a.ClassCounts), formatCounts(b.ClassCounts)))
+	}
+	if !reflect.DeepEqual(a.OutputCounts, b.OutputCounts) && (len(a.OutputCounts) > 0 || len(b.OutputCounts) > 0) {
+		keys := make(map[string]bool)
+		for k := range a.OutputCounts {
+			keys[k] = true
+		}
+		for k := range b.OutputCounts {
+			keys[k] = true
+		}
+		for _, k := range sortedKeys(keys) {
+			if !reflect.DeepEqual(a.OutputCounts[k], b.OutputCounts[k]) {
+				diffs = append(diffs, fmt.Sprintf("%s %s counts changed from %s to %s",
+					path, k, formatCounts(a.OutputCounts[k]), formatCounts(b.OutputCounts[k])))
+			}
+		}
+	}
+	if a.MatchedCount != b.MatchedCount || a.NoMatchedCount != b.NoMatchedCount {
+		diffs = append(diffs, fmt.Sprintf("%s branch sizes changed from %d/%d to %d/%d",
+			path, a.MatchedCount, a.NoMatchedCount, b.MatchedCount, b.NoMatchedCount))
+	}
+	if a.isLeaf() {
+		return diffs
+	}
+
+	if len(a.Children) > 0 || len(b.Children) > 0 {
+		keys := make(map[string]bool)
+		for k := range a.Children {
+			keys[k] = true
+		}
+		for k := range b.Children {
+			keys[k] = true
+		}
+		for _, k := range sortedKeys(keys) {
+			diffs = diffNode(fmt.Sprintf("%s.children[%s]", path, k), a.Children[k], b.Children[k], diffs)
+		}
+		return diffNode(path+".missing", a.Missing, b.Missing, diffs)
+	}
+	diffs = diffNode(path+".match", a.Match, b.Match, diffs)
+	return diffNode(path+".noMatch", a.NoMatch, b.NoMatch, diffs)
+}
+
+func sameSplit(a, b *TreeItem) bool {
+	return a.Attribute == b.Attribute && a.PredicateName == b.PredicateName &&
+		reflect.DeepEqual(toComparable(a.Pivot), toComparable(b.Pivot)) &&
+		reflect.DeepEqual(a.PivotSet, b.PivotSet) &&
+		reflect.DeepEqual(a.Oblique, b.Oblique)
+}
+
+// nodeDesc summarizes a node for diff output.
+func nodeDesc(n *TreeItem) string {
+	if n.isLeaf() {
+		return "leaf " + n.Category
+	}
+	return "split " + splitDesc(n)
+}
+
+// splitDesc renders a split condition compactly, e.g. "Humidity>=75".
+// Numeric pivots are normalized so 75 and 75.0 compare equal.
+func splitDesc(n *TreeItem) string {
+	if n.Oblique != nil {
+		return n.Oblique.String()
+	}
+	if len(n.Children) > 0 {
+		return n.Attribute + " " + n.PredicateName
+	}
+	if n.PredicateName == "subset" {
+		return n.condition()
+	}
+	return n.Attribute + n.PredicateName + formatValue(n.Pivot)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
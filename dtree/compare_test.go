@@ -0,0 +1,66 @@
+package dtree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestModelEqual_Self(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if !model.Equal(model) {
+		t.Fatalf("model should equal itself: %v", model.Diff(model))
+	}
+}
+
+func TestModelEqual_Retrained(t *testing.T) {
+	a, _ := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	b, _ := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if !a.Equal(b) {
+		t.Fatalf("identically trained models should be equal: %v", a.Diff(b))
+	}
+}
+
+func TestModelDiff_Different(t *testing.T) {
+	a, _ := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	b, _ := Train(playTennisSet(), Config{CategoryAttr: "Play", MaxDepth: 1})
+	if a.Equal(b) {
+		t.Fatal("models with different depth limits should differ")
+	}
+	diffs := a.Diff(b)
+	if len(diffs) == 0 || !strings.HasPrefix(diffs[0], "config.MaxDepth changed from 0 to 1") {
+		t.Fatalf("expected MaxDepth diff first, got %v", diffs)
+	}
+
+	c := &Model{Config: a.Config, Root: &TreeItem{
+		Attribute: "Humidity", PredicateName: ">=", Pivot: 75.0,
+		Match:       &TreeItem{Category: "no", ClassCounts: map[string]int{"no": 1}},
+		NoMatch:     &TreeItem{Category: "yes", ClassCounts: map[string]int{"yes": 1}},
+		ClassCounts: map[string]int{"no": 1, "yes": 1},
+	}}
+	found := false
+	for _, d := range a.Diff(c) {
+		if strings.HasPrefix(d, "root changed from split ") && strings.HasSuffix(d, "to split Humidity>=75") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected root split change, got %v", a.Diff(c))
+	}
+}
+
+func TestModelDiff_Nil(t *testing.T) {
+	var a, b *Model
+	if !a.Equal(b) {
+		t.Fatal("two nil models should be equal")
+	}
+	m := &Model{Config: Config{CategoryAttr: "x"}}
+	if m.Equal(nil) || a.Equal(m) {
+		t.Fatal("nil and non-nil models should differ")
+	}
+	if d := m.Diff(&Model{Config: m.Config, Root: &TreeItem{Category: "a"}}); len(d) != 1 || !strings.HasPrefix(d[0], "root added") {
+		t.Fatalf("expected root added, got %v", d)
+	}
+}
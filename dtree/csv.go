@@ -0,0 +1,130 @@
+package dtree
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode"
+)
+
+// CSVOptions controls how ReadCSVStream and ReadCSVFunc parse CSV input.
+type CSVOptions struct {
+	// Comma is the field delimiter. Zero means ','.
+	Comma rune
+	// NA lists cell values read as missing (nil) in addition to empty cells.
+	NA []string
+	// Types fixes the type of the listed columns instead of detecting it per
+	// cell: ColumnString keeps the cell text as is (so "02134" stays a
+	// string), ColumnNumber and ColumnBool fail on cells that do not parse,
+	// and ColumnIgnore leaves the column out of every item. Missing cells are
+	// nil whatever the type.
+	Types map[string]string
+}
+
+// ReadCSVStream parses CSV with a header row into a training set, reading one
+// record at a time. Numeric cells become float64, "true"/"false" become bool,
+// and everything else stays a string. Errors name the offending row, counting
+// the header as row 1.
+func ReadCSVStream(r io.Reader, opts CSVOptions) (TrainingSet, error) {
+	var set TrainingSet
+	_, err := ReadCSVFunc(r, opts, func(row int, item TrainingItem) error {
+		set = append(set, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(set) == 0 {
+		return nil, errors.New("CSV file is empty (no data rows)")
+	}
+	return set, nil
+}
+
+// ReadCSVFunc parses CSV with a header row and calls fn for each data row
+// without retaining it, so callers can count or validate inputs of any size.
+// row is the 1-based row number including the header. Returning an error from
+// fn stops reading and returns that error. The header is returned on success.
+func ReadCSVFunc(r io.Reader, opts CSVOptions, fn func(row int, item TrainingItem) error) ([]string, error) {
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	// Trimming would swallow empty fields when the delimiter is itself whitespace.
+	cr.TrimLeadingSpace = !unicode.IsSpace(cr.Comma)
+	// Column counts are checked below to report them with row numbers.
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	na := make(map[string]bool, len(opts.NA))
+	for _, tok := range opts.NA {
+		na[tok] = true
+	}
+
+	for col, typ := range opts.Types {
+		if !validColumnType(typ) {
+			return nil, fmt.Errorf("column %q has unknown type %q", col, typ)
+		}
+	}
+
+	rec, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CSV header: %w", err)
+	}
+	header := append([]string(nil), rec...)
+
+	rowNum := 2 // Start at 2 (1 is header)
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV row %d: %w", rowNum, err)
+		}
+		if len(rec) != len(header) {
+			return nil, fmt.Errorf("row %d has %d columns but header has %d", rowNum, len(rec), len(header))
+		}
+		item := make(TrainingItem, len(header))
+		for i, h := range header {
+			typ, ok := opts.Types[h]
+			switch {
+			case !ok:
+				item[h] = parseCSVValue(rec[i], na)
+			case typ == ColumnIgnore:
+			case rec[i] == "" || na[rec[i]]:
+				item[h] = nil
+			default:
+				v, err := coerceValue(rec[i], typ)
+				if err != nil {
+					return nil, fmt.Errorf("row %d: column %q: %w", rowNum, h, err)
+				}
+				item[h] = v
+			}
+		}
+		if err := fn(rowNum, item); err != nil {
+			return nil, err
+		}
+		rowNum++
+	}
+	return header, nil
+}
+
+// parseCSVValue converts CSV cell strings to float64, bool, or leaves as string.
+// Empty cells and any value in na become nil (missing).
+func parseCSVValue(s string, na map[string]bool) interface{} {
+	if s == "" || na[s] {
+		return nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	if s == "true" {
+		return true
+	}
+	if s == "false" {
+		return false
+	}
+	return s
+}
@@ -0,0 +1,153 @@
+package dtree
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseCSVValue_NATokens(t *testing.T) {
+	na := map[string]bool{"NA": true, "N/A": true, "null": true, "?": true}
+	for _, tok := range []string{"", "NA", "N/A", "null", "?"} {
+		if v := parseCSVValue(tok, na); v != nil {
+			t.Errorf("expected %q to be missing, got %v", tok, v)
+		}
+	}
+	// Without configured tokens only the empty string is missing.
+	if v := parseCSVValue("NA", nil); v != "NA" {
+		t.Errorf("expected NA to stay a string by default, got %v", v)
+	}
+	if v := parseCSVValue("na", na); v != "na" {
+		t.Errorf("tokens are case-sensitive, got %v", v)
+	}
+	if v := parseCSVValue("3.5", na); v != 3.5 {
+		t.Errorf("expected numeric parse, got %v", v)
+	}
+}
+
+// rowsReader lazily generates a CSV stream with n data rows, optionally
+// corrupting one row, without materializing the whole input.
+type rowsReader struct {
+	n, next, badRow int
+	buf             bytes.Buffer
+}
+
+func (r *rowsReader) Read(p []byte) (int, error) {
+	for r.buf.Len() < len(p) {
+		switch {
+		case r.next == 0:
+			r.buf.WriteString("id,x,color,label\n")
+		case r.next > r.n:
+			if r.buf.Len() == 0 {
+				return 0, io.EOF
+			}
+			return r.buf.Read(p)
+		case r.next == r.badRow:
+			r.buf.WriteString("oops,1\n")
+		default:
+			fmt.Fprintf(&r.buf, "%d,%d.5,c%d,l%d\n", r.next, r.next%97, r.next%5, r.next%3)
+		}
+		r.next++
+	}
+	return r.buf.Read(p)
+}
+
+func TestReadCSVStream_LargeInput(t *testing.T) {
+	const n = 50000
+	set, err := ReadCSVStream(&rowsReader{n: n}, CSVOptions{})
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if len(set) != n {
+		t.Fatalf("expected %d rows, got %d", n, len(set))
+	}
+	last := set[n-1]
+	if last["id"] != float64(n) || last["x"] != float64(n%97)+0.5 || last["color"] != fmt.Sprintf("c%d", n%5) {
+		t.Fatalf("unexpected last row: %v", last)
+	}
+}
+
+func TestReadCSVStream_MalformedRow(t *testing.T) {
+	_, err := ReadCSVStream(&rowsReader{n: 1000, badRow: 700}, CSVOptions{})
+	if err == nil {
+		t.Fatal("expected error for malformed row")
+	}
+	// Data row 700 is line 701 once the header is counted.
+	if want := "row 701 has 2 columns but header has 4"; err.Error() != want {
+		t.Fatalf("expected %q, got %q", want, err.Error())
+	}
+
+	_, err = ReadCSVStream(strings.NewReader("a,b\n1,\"unterminated\n"), CSVOptions{})
+	if err == nil || !strings.Contains(err.Error(), "error reading CSV row 2") {
+		t.Fatalf("expected parse error naming row 2, got %v", err)
+	}
+}
+
+func TestReadCSVFunc_CountsWithoutRetaining(t *testing.T) {
+	rows := 0
+	lastRow := 0
+	header, err := ReadCSVFunc(&rowsReader{n: 2500}, CSVOptions{}, func(row int, item TrainingItem) error {
+		rows++
+		lastRow = row
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if rows != 2500 || lastRow != 2501 {
+		t.Fatalf("expected 2500 rows ending at row 2501, got %d ending at %d", rows, lastRow)
+	}
+	if strings.Join(header, ",") != "id,x,color,label" {
+		t.Fatalf("unexpected header: %v", header)
+	}
+
+	stop := fmt.Errorf("stop")
+	_, err = ReadCSVFunc(&rowsReader{n: 10}, CSVOptions{}, func(row int, item TrainingItem) error {
+		if row == 5 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("expected callback error to be returned, got %v", err)
+	}
+}
+
+func TestReadCSVStream_Options(t *testing.T) {
+	set, err := ReadCSVStream(strings.NewReader("a;b\nNA;x\n2;?\n"), CSVOptions{Comma: ';', NA: []string{"NA", "?"}})
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if set[0]["a"] != nil || set[0]["b"] != "x" || set[1]["a"] != 2.0 || set[1]["b"] != nil {
+		t.Fatalf("unexpected rows: %v", set)
+	}
+	if _, err := ReadCSVStream(strings.NewReader("a,b\n"), CSVOptions{}); err == nil {
+		t.Fatal("expected error for CSV without data rows")
+	}
+}
+
+func TestReadCSVStream_Types(t *testing.T) {
+	data := "id,zip,flag,n,label\n1,02134,true,3,a\n2,90210,false,NA,b\n"
+	types := map[string]string{"id": ColumnIgnore, "zip": ColumnString, "flag": ColumnBool}
+	set, err := ReadCSVStream(strings.NewReader(data), CSVOptions{Types: types, NA: []string{"NA"}})
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	want := TrainingItem{"zip": "02134", "flag": true, "n": 3.0, "label": "a"}
+	if fmt.Sprint(set[0]) != fmt.Sprint(want) {
+		t.Errorf("row 0 = %v, want %v", set[0], want)
+	}
+	if _, ok := set[1]["id"]; ok || set[1]["n"] != nil {
+		t.Errorf("row 1 = %v", set[1])
+	}
+
+	if _, err := ReadCSVStream(strings.NewReader(data), CSVOptions{Types: map[string]string{"zip": ColumnBool}}); err == nil ||
+		!strings.Contains(err.Error(), `row 2: column "zip"`) {
+		t.Errorf("expected a conversion error naming the row, got %v", err)
+	}
+	if _, err := ReadCSVStream(strings.NewReader(data), CSVOptions{Types: map[string]string{"zip": "date"}}); err == nil {
+		t.Error("expected error for an unknown type")
+	}
+}
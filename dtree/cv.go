@@ -0,0 +1,78 @@
+package dtree
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// CVSummary aggregates the test accuracy of every fold of a cross-validation.
+type CVSummary struct {
+	// FoldAccuracies holds each fold's accuracy, repeat by repeat.
+	FoldAccuracies []float64 `json:"foldAccuracies"`
+	// Mean and StdDev are over FoldAccuracies; StdDev is the sample
+	// standard deviation, 0 for a single fold.
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stdDev"`
+}
+
+// CrossValidate runs k-fold cross-validation: set is shuffled with seed and
+// dealt into k folds of near-equal size, and for each fold a model trained
+// with cfg on the other folds is scored on it. The result is reproducible
+// for a given seed.
+func CrossValidate(set TrainingSet, cfg Config, k int, seed int64) (CVSummary, error) {
+	return RepeatedCrossValidate(set, cfg, k, 1, seed)
+}
+
+// RepeatedCrossValidate runs k-fold cross-validation repeats times, each
+// with a different shuffle drawn from seed, and summarizes the accuracy of
+// all k*repeats folds. Repeating smooths out the luck of a single
+// partition. The result is reproducible for a given seed.
+func RepeatedCrossValidate(set TrainingSet, cfg Config, k, repeats int, seed int64) (CVSummary, error) {
+	if k < 2 {
+		return CVSummary{}, errors.New("k must be at least 2")
+	}
+	if repeats <= 0 {
+		return CVSummary{}, errors.New("repeats must be positive")
+	}
+	if len(set) < k {
+		return CVSummary{}, fmt.Errorf("training set has %d items, fewer than %d folds", len(set), k)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	var summary CVSummary
+	for r := 0; r < repeats; r++ {
+		perm := rng.Perm(len(set))
+		for f := 0; f < k; f++ {
+			isTest := make([]bool, len(set))
+			for i := f; i < len(perm); i += k {
+				isTest[perm[i]] = true
+			}
+			train, test, _ := partition(set, isTest)
+			model, err := Train(train, cfg)
+			if err != nil {
+				return CVSummary{}, fmt.Errorf("repeat %d, fold %d: %w", r, f, err)
+			}
+			acc, err := model.Score(test)
+			if err != nil {
+				return CVSummary{}, fmt.Errorf("repeat %d, fold %d: %w", r, f, err)
+			}
+			summary.FoldAccuracies = append(summary.FoldAccuracies, acc)
+		}
+	}
+
+	n := float64(len(summary.FoldAccuracies))
+	for _, acc := range summary.FoldAccuracies {
+		summary.Mean += acc
+	}
+	summary.Mean /= n
+	if n > 1 {
+		ss := 0.0
+		for _, acc := range summary.FoldAccuracies {
+			ss += (acc - summary.Mean) * (acc - summary.Mean)
+		}
+		summary.StdDev = math.Sqrt(ss / (n - 1))
+	}
+	return summary, nil
+}
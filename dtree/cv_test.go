@@ -0,0 +1,59 @@
+package dtree
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRepeatedCrossValidate(t *testing.T) {
+	set := syntheticSet(150)
+	cfg := Config{CategoryAttr: "label", MaxDepth: 4}
+	got, err := RepeatedCrossValidate(set, cfg, 5, 3, 7)
+	if err != nil {
+		t.Fatalf("RepeatedCrossValidate failed: %v", err)
+	}
+	if len(got.FoldAccuracies) != 15 {
+		t.Fatalf("got %d fold accuracies, want 15", len(got.FoldAccuracies))
+	}
+	if got.Mean < 0 || got.Mean > 1 {
+		t.Fatalf("mean accuracy %v is outside [0,1]", got.Mean)
+	}
+	if got.StdDev < 0 {
+		t.Fatalf("stddev %v is negative", got.StdDev)
+	}
+
+	again, err := RepeatedCrossValidate(set, cfg, 5, 3, 7)
+	if err != nil {
+		t.Fatalf("RepeatedCrossValidate failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, again) {
+		t.Fatalf("same seed gave %+v, then %+v", got, again)
+	}
+
+	single, err := CrossValidate(set, cfg, 5, 7)
+	if err != nil {
+		t.Fatalf("CrossValidate failed: %v", err)
+	}
+	if !reflect.DeepEqual(single.FoldAccuracies, got.FoldAccuracies[:5]) {
+		t.Fatalf("CrossValidate folds %v differ from the first repeat %v", single.FoldAccuracies, got.FoldAccuracies[:5])
+	}
+}
+
+func TestRepeatedCrossValidate_Invalid(t *testing.T) {
+	set := syntheticSet(10)
+	cfg := Config{CategoryAttr: "label"}
+	for _, tc := range []struct {
+		k, repeats int
+		want       string
+	}{
+		{5, 0, "repeats must be positive"},
+		{1, 2, "k must be at least 2"},
+		{20, 1, "fewer than 20 folds"},
+	} {
+		_, err := RepeatedCrossValidate(set, cfg, tc.k, tc.repeats, 1)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("k=%d repeats=%d: got error %v, want %q", tc.k, tc.repeats, err, tc.want)
+		}
+	}
+}
@@ -1,6 +1,20 @@
 package dtree
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestCounterUnique(t *testing.T) {
 	ts := TrainingSet{
@@ -39,6 +53,32 @@ func TestSplit(t *testing.T) {
 	}
 }
 
+func TestSplitCounted_MatchesRecount(t *testing.T) {
+	set := syntheticSet(500)
+	res := splitCounted(set, "x", "label", predicateGte, 40.0)
+	plain := split(set, "x", predicateGte, 40.0)
+	if len(res.Match) != len(plain.Match) || len(res.NoMatch) != len(plain.NoMatch) {
+		t.Fatalf("partition sizes differ from split: %d/%d vs %d/%d",
+			len(res.Match), len(res.NoMatch), len(plain.Match), len(plain.NoMatch))
+	}
+	checkSide := func(name string, counts map[string]int, part TrainingSet) {
+		want := counterUniqueValues(part, "label")
+		if len(counts) != len(want) {
+			t.Fatalf("%s: got %v, want %v", name, counts, want)
+		}
+		for k, v := range want {
+			if counts[k] != v {
+				t.Fatalf("%s: count for %q is %d, want %d", name, k, counts[k], v)
+			}
+		}
+		if d := math.Abs(entropyFromCounts(counts, len(part)) - entropy(part, "label")); d > 1e-12 {
+			t.Fatalf("%s: entropy from counts differs from entropy of set by %v", name, d)
+		}
+	}
+	checkSide("match", res.MatchCounts, res.Match)
+	checkSide("noMatch", res.NoMatchCounts, res.NoMatch)
+}
+
 func TestTrainAndPredict_PlayTennis(t *testing.T) {
 	// Small subset of the PlayTennis dataset
 	ts := TrainingSet{
@@ -81,8 +121,468 @@ func TestTrainAndPredict_PlayTennis(t *testing.T) {
 	}
 }
 
+func TestTrain_ZeroGainSplitYieldsLeaf(t *testing.T) {
+	// The only candidate attribute splits the labels evenly on both sides,
+	// so every split has zero gain and the root must stay a leaf.
+	ts := TrainingSet{
+		TrainingItem{"x": "a", "label": "yes"},
+		TrainingItem{"x": "b", "label": "no"},
+		TrainingItem{"x": "a", "label": "no"},
+		TrainingItem{"x": "b", "label": "yes"},
+	}
+	model, err := Train(ts, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	root := model.Root
+	if root.Match != nil || root.NoMatch != nil {
+		t.Fatalf("expected single leaf, got internal node on %q", root.Attribute)
+	}
+	if root.Attribute != "" || root.PredicateName != "" {
+		t.Fatalf("leaf should carry no split metadata: %+v", root)
+	}
+	if root.Category != "no" {
+		t.Fatalf("expected tie broken to 'no', got %q", root.Category)
+	}
+	if root.ClassCounts["yes"] != 2 || root.ClassCounts["no"] != 2 {
+		t.Fatalf("unexpected class counts: %+v", root.ClassCounts)
+	}
+	if err := model.Validate(); err != nil {
+		t.Fatalf("model should validate: %v", err)
+	}
+}
+
+func TestTrain_OneSidedSplitYieldsLeaf(t *testing.T) {
+	// x is constant, so its only pivot sends every sample to Match. That
+	// split must not be chosen, leaving a leaf with a real category rather
+	// than a node with an empty, category-less child.
+	ts := TrainingSet{
+		TrainingItem{"x": 5.0, "label": "yes"},
+		TrainingItem{"x": 5.0, "label": "yes"},
+		TrainingItem{"x": 5.0, "label": "no"},
+	}
+	for _, cfg := range []Config{
+		{CategoryAttr: "label"},
+		{CategoryAttr: "label", MaxThresholds: 2},
+		{CategoryAttr: "label", HistogramBins: 4},
+	} {
+		model, err := Train(ts, cfg)
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		if !model.Root.isLeaf() {
+			t.Fatalf("expected a single leaf, got split %s", model.Root.condition())
+		}
+		if model.Root.Category != "yes" {
+			t.Fatalf("expected majority category yes, got %q", model.Root.Category)
+		}
+	}
+
+	// No leaf of a tree trained on real data is left without a category.
+	model, err := Train(syntheticSet(300), Config{CategoryAttr: "label", MinSamplesLeaf: 3})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	var walk func(n *TreeItem)
+	walk = func(n *TreeItem) {
+		if n.isLeaf() && n.Category == "" {
+			t.Errorf("leaf %d has an empty category", n.ID)
+		}
+		for _, b := range n.branches() {
+			walk(b.node)
+		}
+	}
+	walk(model.Root)
+}
+
+func TestTrain_GiniCriterion(t *testing.T) {
+	ts := playTennisSet()
+	model, err := Train(ts, Config{CategoryAttr: "Play", Criterion: CriterionGini})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if model.Config.Criterion != CriterionGini {
+		t.Fatalf("criterion not preserved: %q", model.Config.Criterion)
+	}
+	for _, item := range ts {
+		if pred, _ := model.Predict(item); pred != item["Play"] {
+			t.Errorf("gini tree mispredicted training item %v: %s", item, pred)
+		}
+	}
+	if g := giniFromCounts(map[string]int{"a": 2, "b": 2}, 4); math.Abs(g-0.5) > 1e-12 {
+		t.Fatalf("expected gini 0.5 for a 50/50 split, got %v", g)
+	}
+}
+
+func TestTrain_MinSamplesLeaf(t *testing.T) {
+	model, err := Train(syntheticSet(300), Config{CategoryAttr: "label", MinSamplesLeaf: 20})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	var check func(n *TreeItem)
+	check = func(n *TreeItem) {
+		if n.Match == nil && n.NoMatch == nil {
+			return
+		}
+		if n.MatchedCount < 20 || n.NoMatchedCount < 20 {
+			t.Fatalf("split on %s sends %d/%d samples, below MinSamplesLeaf", n.Attribute, n.MatchedCount, n.NoMatchedCount)
+		}
+		check(n.Match)
+		check(n.NoMatch)
+	}
+	check(model.Root)
+}
+
+func TestTrain_ResolvedConfig(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	c := model.Config
+	if c.Criterion != CriterionEntropy || c.MissingStrategy != MissingMajority || c.LeafStrategy != LeafMajority {
+		t.Fatalf("defaults not resolved: criterion %q, missing %q, leaf %q", c.Criterion, c.MissingStrategy, c.LeafStrategy)
+	}
+
+	multi, err := Train(TrainingSet{{"x": 1.0, "a": "p", "b": "q"}, {"x": 2.0, "a": "r", "b": "s"}},
+		Config{CategoryAttrs: []string{"a", "b"}, Criterion: CriterionGini})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if multi.Config.CategoryAttr != "a" || multi.Config.Criterion != CriterionGini {
+		t.Fatalf("resolved config = %+v", multi.Config)
+	}
+}
+
+func TestTrain_MaxFeaturesSeeded(t *testing.T) {
+	set := syntheticSet(300)
+	cfg := Config{CategoryAttr: "label", MaxFeatures: 1, Seed: 7}
+	a, err := Train(set, cfg)
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	b, _ := Train(set, cfg)
+	if a.ToText() != b.ToText() {
+		t.Fatal("same seed should produce the same tree")
+	}
+	if a.Config.MaxFeatures != 1 || a.Config.Seed != 7 {
+		t.Fatalf("config not preserved: %+v", a.Config)
+	}
+}
+
+func TestTrain_MaxCategories(t *testing.T) {
+	// id is unique per item, so "id == <value>" isolates single items and
+	// out-gains the noisy x split at deep nodes.
+	set := diagonalSet(200, 3)
+	for i, item := range set {
+		item["id"] = "c" + strconv.Itoa(i)
+	}
+	usesID := func(m *Model) bool {
+		_, ok := m.FeatureImportance()["id"]
+		return ok
+	}
+
+	limited, err := Train(set, Config{CategoryAttr: "label", MaxCategories: 50})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if usesID(limited) {
+		t.Fatal("high-cardinality id should not be split on")
+	}
+	want := `feature "id" has 200 categories, more than MaxCategories; it is not split on`
+	if got := limited.Metadata.Warnings; len(got) != 1 || got[0] != want {
+		t.Fatalf("warnings = %q, want [%q]", got, want)
+	}
+
+	raised, err := Train(set, Config{CategoryAttr: "label", MaxCategories: 500})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if !usesID(raised) || len(raised.Metadata.Warnings) != 0 {
+		t.Fatalf("id should be split on under a raised limit; warnings %q", raised.Metadata.Warnings)
+	}
+
+	if _, err := Train(set, Config{CategoryAttr: "label", MaxCategories: -1}); err == nil {
+		t.Error("expected error for negative MaxCategories")
+	}
+}
+
+func TestBestSplit(t *testing.T) {
+	for _, cfg := range []Config{
+		{CategoryAttr: "Play"},
+		{CategoryAttr: "Play", Criterion: CriterionGini},
+		{CategoryAttr: "Play", MultiwaySplits: true},
+	} {
+		model, err := Train(playTennisSet(), cfg)
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		info, err := BestSplit(playTennisSet(), cfg)
+		if err != nil {
+			t.Fatalf("BestSplit failed: %v", err)
+		}
+		root := model.Root
+		if info.Attribute != root.Attribute || info.PredicateName != root.PredicateName ||
+			info.Pivot != root.Pivot || math.Abs(info.Gain-root.Gain) > 1e-12 {
+			t.Fatalf("%+v: BestSplit = %+v, root splits %s %s %v (gain %v)",
+				cfg, info, root.Attribute, root.PredicateName, root.Pivot, root.Gain)
+		}
+		if len(root.Children) == 0 && (info.MatchCount != root.MatchedCount || info.NoMatchCount != root.NoMatchedCount) {
+			t.Fatalf("partition sizes %d/%d, want %d/%d", info.MatchCount, info.NoMatchCount, root.MatchedCount, root.NoMatchedCount)
+		}
+		if len(root.Children) > 0 && len(info.GroupCounts) != len(root.Children) {
+			t.Fatalf("GroupCounts = %v, want %d groups", info.GroupCounts, len(root.Children))
+		}
+	}
+
+	first, _ := BestSplit(playTennisSet(), Config{CategoryAttr: "Play"})
+	other, err := BestSplit(playTennisSet(), Config{CategoryAttr: "Play", IgnoredAttributes: []string{first.Attribute}})
+	if err == nil && other.Attribute == first.Attribute {
+		t.Fatalf("BestSplit used ignored attribute %q", first.Attribute)
+	}
+
+	pure := TrainingSet{{"x": 1.0, "label": "a"}, {"x": 2.0, "label": "a"}}
+	if _, err := BestSplit(pure, Config{CategoryAttr: "label"}); err == nil {
+		t.Error("expected error for a pure set")
+	}
+	if _, err := BestSplit(pure, Config{}); err == nil {
+		t.Error("expected error for an invalid config")
+	}
+}
+
+// tiedSet returns items where copies p, q, r and s of one boolean feature
+// all split the root equally well.
+func tiedSet() TrainingSet {
+	var set TrainingSet
+	for i := 0; i < 40; i++ {
+		v, label := "on", "yes"
+		if i%2 == 1 {
+			v, label = "off", "no"
+		}
+		if i%7 == 0 {
+			label = map[string]string{"yes": "no", "no": "yes"}[label]
+		}
+		set = append(set, TrainingItem{"s": v, "r": v, "q": v, "p": v, "label": label})
+	}
+	return set
+}
+
+func TestTrain_TieBreakDeterministic(t *testing.T) {
+	set := tiedSet()
+	var want string
+	for seed := int64(1); seed <= 5; seed++ {
+		shuffled := append(TrainingSet(nil), set...)
+		rand.New(rand.NewSource(seed)).Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		model, err := Train(shuffled, Config{CategoryAttr: "label", TieBreak: TieBreakDeterministic})
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		// "off" and "on" give the same split; the smaller pivot wins.
+		if model.Root.Attribute != "p" || model.Root.Pivot != "off" {
+			t.Fatalf("root = %s %v, want p off", model.Root.Attribute, model.Root.Pivot)
+		}
+		if seed == 1 {
+			want = model.ToText()
+		} else if model.ToText() != want {
+			t.Fatal("deterministic tie-break should not depend on item order")
+		}
+	}
+}
+
+func TestTrain_TieBreakRandom(t *testing.T) {
+	set := tiedSet()
+	roots := make(map[string]bool)
+	for seed := int64(1); seed <= 20; seed++ {
+		cfg := Config{CategoryAttr: "label", TieBreak: TieBreakRandom, Seed: seed}
+		a, err := Train(set, cfg)
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		b, _ := Train(set, cfg)
+		if a.ToText() != b.ToText() {
+			t.Fatal("same seed should produce the same tree")
+		}
+		roots[fmt.Sprint(a.Root.Attribute, a.Root.Pivot)] = true
+	}
+	if len(roots) < 2 {
+		t.Fatalf("random tie-break always picked %v", roots)
+	}
+
+	if _, err := Train(set, Config{CategoryAttr: "label", TieBreak: TieBreakRandom}); err == nil {
+		t.Error("expected error for random tie-break without a seed")
+	}
+	if _, err := Train(set, Config{CategoryAttr: "label", TieBreak: "first"}); err == nil {
+		t.Error("expected error for unknown tie-break")
+	}
+}
+
+func TestPredict_LeafTie(t *testing.T) {
+	// Identical features: the root is a 2-2 leaf.
+	set := TrainingSet{
+		{"x": 1.0, "label": "b"}, {"x": 1.0, "label": "a"},
+		{"x": 1.0, "label": "b"}, {"x": 1.0, "label": "a"},
+	}
+	for i := 0; i < 10; i++ {
+		model, err := Train(set, Config{CategoryAttr: "label", LeafStrategy: LeafMajority})
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		if got, _ := model.Predict(TrainingItem{"x": 1.0}); got != "a" {
+			t.Fatalf("tied leaf predicted %q, want the lowest class a", got)
+		}
+	}
+}
+
+func TestPredict_DefaultClass(t *testing.T) {
+	// The Match side of the split is an empty leaf, as a degenerate split
+	// leaves behind.
+	root := &TreeItem{
+		Attribute:      "x",
+		PredicateName:  ">=",
+		Pivot:          5.0,
+		NoMatchedCount: 2,
+		ClassCounts:    map[string]int{"lo": 2},
+		Match:          &TreeItem{},
+		NoMatch:        &TreeItem{Category: "lo", ClassCounts: map[string]int{"lo": 2}},
+	}
+	model := &Model{Root: root, Config: Config{CategoryAttr: "label"}}
+	if got, err := model.Predict(TrainingItem{"x": 7.0}); err != nil || got != "" {
+		t.Fatalf("without DefaultClass got %q, %v; want the empty class", got, err)
+	}
+
+	model = &Model{Root: root, Config: Config{CategoryAttr: "label", DefaultClass: "unknown"}}
+	if got, err := model.Predict(TrainingItem{"x": 7.0}); err != nil || got != "unknown" {
+		t.Fatalf("got %q, %v; want DefaultClass", got, err)
+	}
+	if got, _ := model.Predict(TrainingItem{"x": 1.0}); got != "lo" {
+		t.Fatalf("non-empty leaf predicted %q, want lo", got)
+	}
+	if _, got, err := model.PredictLeaf(TrainingItem{"x": 7.0}); err != nil || got != "unknown" {
+		t.Fatalf("PredictLeaf got %q, %v; want DefaultClass", got, err)
+	}
+	multi := &Model{Root: root, Config: Config{CategoryAttr: "label", CategoryAttrs: []string{"label"}, DefaultClass: "unknown"}}
+	if got, err := multi.PredictMulti(TrainingItem{"x": 7.0}); err != nil || got["label"] != "unknown" {
+		t.Fatalf("PredictMulti got %v, %v; want DefaultClass", got, err)
+	}
+	model.Config.LeafStrategy = LeafProportional
+	if got, _ := model.Predict(TrainingItem{"x": 7.0}); got != "unknown" {
+		t.Fatalf("proportional strategy predicted %q, want DefaultClass", got)
+	}
+}
+
+func TestPredict_UnseenAsMissing(t *testing.T) {
+	// "red" is the pivot; "green" and "blue" went to NoMatch in training,
+	// but most items were red, so majority routing favors Match.
+	var set TrainingSet
+	for i := 0; i < 6; i++ {
+		set = append(set, TrainingItem{"color": "red", "label": "yes"})
+	}
+	set = append(set,
+		TrainingItem{"color": "green", "label": "no"},
+		TrainingItem{"color": "blue", "label": "no"},
+	)
+	model, err := Train(set, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if model.Root.Categories != nil {
+		t.Fatalf("Categories recorded without UnseenAsMissing: %v", model.Root.Categories)
+	}
+	if got, _ := model.Predict(TrainingItem{"color": "purple"}); got != "no" {
+		t.Fatalf("without UnseenAsMissing purple predicted %q, want no", got)
+	}
+
+	model, err = Train(set, Config{CategoryAttr: "label", UnseenAsMissing: true})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if want := []string{"blue", "green", "red"}; !reflect.DeepEqual(model.Root.Categories, want) {
+		t.Fatalf("Categories = %v, want %v", model.Root.Categories, want)
+	}
+	for color, want := range map[string]string{"red": "yes", "green": "no", "purple": "yes"} {
+		if got, err := model.Predict(TrainingItem{"color": color}); err != nil || got != want {
+			t.Errorf("%s predicted %q, %v; want %q", color, got, err, want)
+		}
+	}
+
+	model.Config.MissingStrategy = MissingFail
+	if _, err := model.Predict(TrainingItem{"color": "purple"}); err == nil {
+		t.Fatal("expected an error for an unseen value with the fail strategy")
+	}
+	if err := model.Validate(); err != nil {
+		t.Fatalf("model should validate: %v", err)
+	}
+	model.Root.Categories = []string{"red", "blue"}
+	if err := model.Validate(); err == nil {
+		t.Fatal("expected unsorted Categories to fail validation")
+	}
+}
+
+func TestPredict_LeafProportional(t *testing.T) {
+	leaf := &TreeItem{Category: "yes", ClassCounts: map[string]int{"yes": 3, "no": 1}}
+	draws := func(seed int64) []string {
+		m := &Model{Root: leaf, Config: Config{CategoryAttr: "label", LeafStrategy: LeafProportional, Seed: seed}}
+		out := make([]string, 4000)
+		for i := range out {
+			out[i], _ = m.Predict(TrainingItem{})
+		}
+		return out
+	}
+	a := draws(5)
+	if !reflect.DeepEqual(a, draws(5)) {
+		t.Fatal("same seed should replay the same draws")
+	}
+	if reflect.DeepEqual(a, draws(6)) {
+		t.Fatal("different seeds should give different draws")
+	}
+	yes := 0
+	for _, c := range a {
+		if c == "yes" {
+			yes++
+		}
+	}
+	if share := float64(yes) / float64(len(a)); math.Abs(share-0.75) > 0.03 {
+		t.Fatalf("yes drawn %.3f of the time, want about 0.75", share)
+	}
+
+	// PredictLeaf and PredictMulti follow the strategy too.
+	m := &Model{Root: leaf, Config: Config{CategoryAttr: "label", LeafStrategy: LeafProportional, Seed: 5}}
+	for i, want := range a[:100] {
+		if _, got, _ := m.PredictLeaf(TrainingItem{}); got != want {
+			t.Fatalf("PredictLeaf draw %d: got %q, want %q as from Predict", i, got, want)
+		}
+	}
+	multiLeaf := &TreeItem{
+		Category:     "yes",
+		ClassCounts:  leaf.ClassCounts,
+		OutputCounts: map[string]map[string]int{"label": leaf.ClassCounts, "size": {"big": 4}},
+	}
+	m = &Model{Root: multiLeaf, Config: Config{CategoryAttr: "label", CategoryAttrs: []string{"label", "size"}, LeafStrategy: LeafProportional, Seed: 5}}
+	for i, want := range a[:100] {
+		if got, _ := m.PredictMulti(TrainingItem{}); got["label"] != want || got["size"] != "big" {
+			t.Fatalf("PredictMulti draw %d: got %v, want label %q as from Predict", i, got, want)
+		}
+	}
+
+	if _, err := Train(playTennisSet(), Config{CategoryAttr: "Play", LeafStrategy: "mode"}); err == nil {
+		t.Error("expected error for unknown leaf strategy")
+	}
+}
+
 // Train validation tests
 
+func TestTrain_InvalidCriterion(t *testing.T) {
+	ts := TrainingSet{TrainingItem{"label": "yes"}}
+	_, err := Train(ts, Config{CategoryAttr: "label", Criterion: "variance"})
+	if err == nil {
+		t.Fatal("expected error for unknown criterion")
+	}
+	if err.Error() != "config.Criterion must be one of entropy, gini" {
+		t.Fatalf("unexpected error message: %v", err)
+	}
+}
+
 func TestTrain_EmptyTrainingSet(t *testing.T) {
 	ts := TrainingSet{}
 	cfg := Config{CategoryAttr: "label"}
@@ -123,6 +623,33 @@ func TestTrain_CategoryAttrNotFound(t *testing.T) {
 	}
 }
 
+func TestTrain_InvalidLabels(t *testing.T) {
+	ts := TrainingSet{
+		TrainingItem{"feature": "a", "label": "yes"},
+		TrainingItem{"feature": "b", "label": nil},
+		TrainingItem{"feature": "c", "label": "no"},
+		TrainingItem{"feature": "d"},
+	}
+	_, err := Train(ts, Config{CategoryAttr: "label"})
+	if err == nil {
+		t.Fatal("expected error for items without a valid label")
+	}
+	want := `2 of 4 training items have no valid "label" label (missing, nil, NaN, infinite or not a string, number or bool); first is item 1`
+	if err.Error() != want {
+		t.Fatalf("unexpected error message: %v", err)
+	}
+
+	if _, err := Train(ts[:1], Config{CategoryAttr: "label"}); err != nil {
+		t.Fatalf("valid labels rejected: %v", err)
+	}
+	if _, err := Train(TrainingSet{{"label": []string{"x"}}}, Config{CategoryAttr: "label"}); err == nil {
+		t.Fatal("expected error for an unsupported label type")
+	}
+	if _, err := Train(TrainingSet{{"label": 1.0}, {"label": math.NaN()}}, Config{CategoryAttr: "label"}); err == nil {
+		t.Fatal("expected error for a NaN label")
+	}
+}
+
 func TestTrain_NegativeMaxDepth(t *testing.T) {
 	ts := TrainingSet{
 		TrainingItem{"label": "yes"},
@@ -239,50 +766,1464 @@ func TestPredictProba_NilItem(t *testing.T) {
 	}
 }
 
-func TestPredictBatch_ErrorHandling(t *testing.T) {
+func TestPredict_StrictMissingAttribute(t *testing.T) {
 	ts := TrainingSet{
-		TrainingItem{"feature": "a", "label": "yes"},
-		TrainingItem{"feature": "b", "label": "no"},
+		TrainingItem{"outlook": "sunny", "label": "no"},
+		TrainingItem{"outlook": "sunny", "label": "no"},
+		TrainingItem{"outlook": "rain", "label": "yes"},
 	}
-	cfg := Config{CategoryAttr: "label"}
-	model, _ := Train(ts, cfg)
+	model, err := Train(ts, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	item := TrainingItem{"temperature": 70.0}
 
-	// Test with one nil item in batch
-	items := []TrainingItem{
-		{"feature": "a"},
-		nil,
-		{"feature": "b"},
+	// Lenient (default) mode follows the majority child.
+	if _, err := model.Predict(item); err != nil {
+		t.Fatalf("lenient predict failed: %v", err)
 	}
-	results, err := model.PredictBatch(items)
-	if err == nil {
-		t.Fatal("expected error for nil item in batch")
+	if _, err := model.PredictProba(item); err != nil {
+		t.Fatalf("lenient predict proba failed: %v", err)
 	}
-	// Should return partial results (first item only)
-	if len(results) != 1 {
-		t.Fatalf("expected 1 partial result, got %d", len(results))
+
+	model.Config.StrictPredict = true
+	want := `item is missing attribute "outlook" required by the model`
+	if _, err := model.Predict(item); err == nil || err.Error() != want {
+		t.Fatalf("strict predict: expected %q, got %v", want, err)
+	}
+	if _, err := model.PredictProba(item); err == nil || err.Error() != want {
+		t.Fatalf("strict predict proba: expected %q, got %v", want, err)
+	}
+
+	// Present attributes still predict normally in strict mode.
+	pred, err := model.Predict(TrainingItem{"outlook": "rain"})
+	if err != nil || pred != "yes" {
+		t.Fatalf("strict predict on complete item: got %q, %v", pred, err)
 	}
 }
 
-func TestPredictProbaBatch_ErrorHandling(t *testing.T) {
-	ts := TrainingSet{
-		TrainingItem{"feature": "a", "label": "yes"},
-		TrainingItem{"feature": "b", "label": "no"},
+// skewedModel has a single ">=" split whose NoMatch child saw more samples.
+func skewedModel(strategy string) *Model {
+	return &Model{
+		Config: Config{CategoryAttr: "label", MissingStrategy: strategy},
+		Root: &TreeItem{
+			Attribute:      "x",
+			PredicateName:  ">=",
+			Pivot:          5.0,
+			MatchedCount:   1,
+			NoMatchedCount: 3,
+			ClassCounts:    map[string]int{"hi": 1, "lo": 3},
+			Match:          &TreeItem{Category: "hi", ClassCounts: map[string]int{"hi": 1}},
+			NoMatch:        &TreeItem{Category: "lo", ClassCounts: map[string]int{"lo": 3}},
+		},
 	}
-	cfg := Config{CategoryAttr: "label"}
-	model, _ := Train(ts, cfg)
+}
 
-	// Test with one nil item in batch
-	items := []TrainingItem{
-		{"feature": "a"},
-		nil,
-		{"feature": "b"},
+// skewedCategoricalModel is skewedModel with an == split on "color", whose
+// Match side saw most training samples.
+func skewedCategoricalModel(strategy string) *Model {
+	return &Model{
+		Config: Config{CategoryAttr: "label", MissingStrategy: strategy},
+		Root: &TreeItem{
+			Attribute:      "color",
+			PredicateName:  "==",
+			Pivot:          "red",
+			MatchedCount:   3,
+			NoMatchedCount: 1,
+			ClassCounts:    map[string]int{"hi": 3, "lo": 1},
+			Match:          &TreeItem{Category: "hi", ClassCounts: map[string]int{"hi": 3}},
+			NoMatch:        &TreeItem{Category: "lo", ClassCounts: map[string]int{"lo": 1}},
+		},
 	}
-	results, err := model.PredictProbaBatch(items)
-	if err == nil {
-		t.Fatal("expected error for nil item in batch")
+}
+
+func TestPredict_MissingStrategies(t *testing.T) {
+	cases := []struct {
+		strategy string
+		want     string
+		// wantCat is the prediction of the categorical model.
+		wantCat string
+	}{
+		{"", "lo", "hi"},
+		{MissingMajority, "lo", "hi"},
+		{MissingMatch, "hi", "hi"},
+		{MissingNoMatch, "lo", "lo"},
 	}
-	// Should return partial results (first item only)
-	if len(results) != 1 {
-		t.Fatalf("expected 1 partial result, got %d", len(results))
+	for _, tc := range cases {
+		cat := skewedCategoricalModel(tc.strategy)
+		for _, item := range []TrainingItem{{"other": 1.0}, {"color": nil}} {
+			if got, err := cat.Predict(item); err != nil || got != tc.wantCat {
+				t.Errorf("strategy %q, categorical item %v: got %q, %v; want %s", tc.strategy, item, got, err, tc.wantCat)
+			}
+		}
+	}
+	for _, tc := range cases {
+		model := skewedModel(tc.strategy)
+		for _, item := range []TrainingItem{{"other": 1.0}, {"x": nil}, {"x": math.NaN()}, {"x": math.Inf(1)}, {"x": math.Inf(-1)}} {
+			got, err := model.Predict(item)
+			if err != nil {
+				t.Fatalf("strategy %q: predict failed: %v", tc.strategy, err)
+			}
+			if got != tc.want {
+				t.Errorf("strategy %q, item %v: expected %s, got %s", tc.strategy, item, tc.want, got)
+			}
+			proba, err := model.PredictProba(item)
+			if err != nil {
+				t.Fatalf("strategy %q: predict proba failed: %v", tc.strategy, err)
+			}
+			if proba[tc.want] != 1 {
+				t.Errorf("strategy %q: expected proba[%s]=1, got %v", tc.strategy, tc.want, proba)
+			}
+		}
+	}
+
+	model := skewedModel(MissingFail)
+	for _, item := range []TrainingItem{{"other": 1.0}, {"x": nil}, {"x": math.NaN()}} {
+		if _, err := model.Predict(item); err == nil {
+			t.Errorf("fail strategy: expected error for item %v", item)
+		}
+		if _, err := model.PredictProba(item); err == nil {
+			t.Errorf("fail strategy: expected proba error for item %v", item)
+		}
+	}
+	// Present values are unaffected by the strategy.
+	if got, err := model.Predict(TrainingItem{"x": 7.0}); err != nil || got != "hi" {
+		t.Fatalf("fail strategy on present value: got %q, %v", got, err)
+	}
+	cat := skewedCategoricalModel(MissingFail)
+	if _, err := cat.Predict(TrainingItem{"color": nil}); err == nil {
+		t.Error("fail strategy: expected error for a nil categorical value")
+	}
+	if got, err := cat.Predict(TrainingItem{"color": "blue"}); err != nil || got != "lo" {
+		t.Fatalf("fail strategy on present categorical value: got %q, %v", got, err)
+	}
+}
+
+func TestTrain_NonFiniteFeatures(t *testing.T) {
+	set := diagonalSet(200, 3)
+	for i := 0; i < len(set); i += 7 {
+		set[i]["x"] = math.NaN()
+	}
+	for i := 3; i < len(set); i += 11 {
+		set[i]["y"] = math.Inf(1)
+	}
+	model, err := Train(set, Config{CategoryAttr: "label", MaxDepth: 4})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	var walk func(n *TreeItem)
+	walk = func(n *TreeItem) {
+		if n == nil || n.isLeaf() {
+			return
+		}
+		if nonFinite(n.Pivot) {
+			t.Fatalf("split %q uses a non-finite pivot", n.condition())
+		}
+		walk(n.Match)
+		walk(n.NoMatch)
+	}
+	walk(model.Root)
+	if model.Root.isLeaf() {
+		t.Fatal("expected the finite values to still produce splits")
+	}
+}
+
+func TestTrain_InvalidMissingStrategy(t *testing.T) {
+	ts := TrainingSet{TrainingItem{"label": "yes"}}
+	_, err := Train(ts, Config{CategoryAttr: "label", MissingStrategy: "random"})
+	if err == nil {
+		t.Fatal("expected error for invalid missing strategy")
+	}
+	if !strings.Contains(err.Error(), "MissingStrategy") {
+		t.Fatalf("unexpected error message: %v", err)
+	}
+
+	model, err := Train(ts, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if model.Config.MissingStrategy != MissingMajority {
+		t.Fatalf("expected default strategy %q, got %q", MissingMajority, model.Config.MissingStrategy)
+	}
+}
+
+func TestCheckFeatures(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	item := TrainingItem{"Outlook": "sunny", "Temperature": 80.0, "Wind": true, "Pressure": 1013.0, "Play": "no"}
+	got := model.CheckFeatures(item)
+	want := []string{`missing feature "Humidity"`, `unexpected feature "Pressure"`}
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	complete := TrainingItem{"Outlook": "sunny", "Temperature": 80.0, "Humidity": 70.0, "Wind": true}
+	if issues := model.CheckFeatures(complete); len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+
+	// Without metadata, the split attributes define the expected features.
+	model.Metadata = nil
+	var want2 []string
+	for _, attr := range treeAttributes(model.Root) {
+		want2 = append(want2, fmt.Sprintf("missing feature %q", attr))
+	}
+	want2 = append(want2, `unexpected feature "Unknown"`)
+	if got := model.CheckFeatures(TrainingItem{"Unknown": 1.0}); strings.Join(got, "; ") != strings.Join(want2, "; ") {
+		t.Fatalf("expected %v, got %v", want2, got)
+	}
+}
+
+func TestPredictProba_LaplaceSmoothing(t *testing.T) {
+	ts := TrainingSet{
+		TrainingItem{"x": 1.0, "label": "a"},
+		TrainingItem{"x": 1.0, "label": "a"},
+		TrainingItem{"x": 5.0, "label": "b"},
+		TrainingItem{"x": 9.0, "label": "c"},
+	}
+	model, err := Train(ts, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	item := TrainingItem{"x": 1.0}
+
+	// Without smoothing only the leaf's class is present.
+	raw, _ := model.PredictProba(item)
+	if len(raw) != 1 || raw["a"] != 1 {
+		t.Fatalf("expected unsmoothed {a:1}, got %v", raw)
+	}
+
+	model.Config.LaplaceAlpha = 1
+	proba, err := model.PredictProba(item)
+	if err != nil {
+		t.Fatalf("predict proba failed: %v", err)
+	}
+	if len(proba) != 3 {
+		t.Fatalf("expected all 3 classes, got %v", proba)
+	}
+	sum := 0.0
+	for c, p := range proba {
+		if p <= 0 {
+			t.Errorf("class %s has non-positive probability %v", c, p)
+		}
+		sum += p
+	}
+	if math.Abs(sum-1) > 1e-12 {
+		t.Fatalf("probabilities sum to %v", sum)
+	}
+	// Leaf counts {a:2}: (2+1)/(2+3) for a, (0+1)/(2+3) for the others.
+	if math.Abs(proba["a"]-0.6) > 1e-12 || math.Abs(proba["b"]-0.2) > 1e-12 {
+		t.Fatalf("unexpected smoothed values: %v", proba)
+	}
+}
+
+func TestTrain_NegativeLaplaceAlpha(t *testing.T) {
+	ts := TrainingSet{TrainingItem{"label": "yes"}}
+	if _, err := Train(ts, Config{CategoryAttr: "label", LaplaceAlpha: -1}); err == nil {
+		t.Fatal("expected error for negative LaplaceAlpha")
+	}
+}
+
+func TestPredictBatch_ErrorHandling(t *testing.T) {
+	ts := TrainingSet{
+		TrainingItem{"feature": "a", "label": "yes"},
+		TrainingItem{"feature": "b", "label": "no"},
+	}
+	cfg := Config{CategoryAttr: "label"}
+	model, _ := Train(ts, cfg)
+
+	// Test with one nil item in batch
+	items := []TrainingItem{
+		{"feature": "a"},
+		nil,
+		{"feature": "b"},
+	}
+	results, err := model.PredictBatch(items)
+	if err == nil {
+		t.Fatal("expected error for nil item in batch")
+	}
+	// Should return partial results (first item only)
+	if len(results) != 1 {
+		t.Fatalf("expected 1 partial result, got %d", len(results))
+	}
+}
+
+func TestModel_ClassPriors(t *testing.T) {
+	set := playTennisSet()
+	model, err := Train(set, Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	freq := make(map[string]float64)
+	for _, item := range set {
+		freq[item["Play"].(string)] += 1 / float64(len(set))
+	}
+	got := model.ClassPriors()
+	if len(got) != len(freq) {
+		t.Fatalf("ClassPriors() = %v, want %v", got, freq)
+	}
+	for c, p := range freq {
+		if math.Abs(got[c]-p) > 1e-12 {
+			t.Errorf("prior of %q = %v, want %v", c, got[c], p)
+		}
+	}
+
+	// Without root counts the leaves are summed.
+	legacy := &Model{Config: Config{CategoryAttr: "label"}, Root: &TreeItem{
+		Attribute: "x", PredicateName: ">=", Pivot: 1.0,
+		Match:   &TreeItem{Category: "yes", ClassCounts: map[string]int{"yes": 3, "no": 1}},
+		NoMatch: &TreeItem{Category: "no", ClassCounts: map[string]int{"no": 4}},
+	}}
+	if got := legacy.ClassPriors(); !reflect.DeepEqual(got, map[string]float64{"yes": 0.375, "no": 0.625}) {
+		t.Fatalf("ClassPriors() from leaves = %v", got)
+	}
+
+	var nilModel *Model
+	if nilModel.ClassPriors() != nil || (&Model{}).ClassPriors() != nil || (&Model{Root: &TreeItem{}}).ClassPriors() != nil {
+		t.Fatal("expected nil priors for a nil model, nil root or empty tree")
+	}
+}
+
+func TestModel_Classes(t *testing.T) {
+	multi, err := Train(syntheticSet(300), Config{CategoryAttr: "label", MaxDepth: 1})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	want := []string{"a", "b", "c"}
+	if got := multi.Classes(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Classes() = %v, want %v", got, want)
+	}
+	multi.Classes()[0] = "z"
+	if got := multi.Classes(); got[0] != "a" {
+		t.Fatal("Classes returned the cached slice itself")
+	}
+
+	// Without metadata the classes come from the leaves; the empty leaf of a
+	// split with no samples on one side contributes nothing.
+	legacy := &Model{Config: Config{CategoryAttr: "label"}, Root: &TreeItem{
+		Attribute: "x", PredicateName: ">=", Pivot: 1.0,
+		Match:       &TreeItem{Category: "yes", ClassCounts: map[string]int{"yes": 3, "no": 1}},
+		NoMatch:     &TreeItem{Category: "", ClassCounts: map[string]int{}},
+		ClassCounts: map[string]int{"yes": 3, "no": 1},
+	}}
+	if got := legacy.Classes(); !reflect.DeepEqual(got, []string{"no", "yes"}) {
+		t.Fatalf("Classes() without metadata = %v", got)
+	}
+
+	single, err := Train(TrainingSet{{"x": 1.0, "label": "only"}, {"x": 2.0, "label": "only"}}, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if got := single.Classes(); !reflect.DeepEqual(got, []string{"only"}) {
+		t.Fatalf("single-class Classes() = %v", got)
+	}
+	var nilModel *Model
+	if nilModel.Classes() != nil {
+		t.Fatal("nil model should have no classes")
+	}
+}
+
+func TestPredictProbaFull_StableKeys(t *testing.T) {
+	set := syntheticSet(300)
+	for _, alpha := range []float64{0, 1} {
+		model, err := Train(set, Config{CategoryAttr: "label", LaplaceAlpha: alpha})
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		classes := model.Classes()
+		partial := false
+		for i, item := range set {
+			proba, err := model.PredictProbaFull(item)
+			if err != nil {
+				t.Fatalf("item %d: %v", i, err)
+			}
+			keys := make([]string, 0, len(proba))
+			sum := 0.0
+			for k, p := range proba {
+				keys = append(keys, k)
+				sum += p
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, classes) {
+				t.Fatalf("alpha %v, item %d: keys %v, want %v", alpha, i, keys, classes)
+			}
+			if math.Abs(sum-1) > 1e-9 {
+				t.Fatalf("alpha %v, item %d: probabilities sum to %v", alpha, i, sum)
+			}
+			if plain, _ := model.PredictProba(item); len(plain) < len(classes) {
+				partial = true
+			}
+		}
+		if alpha == 0 && !partial {
+			t.Fatal("test set never reaches a leaf missing a class; the test proves nothing")
+		}
+	}
+}
+
+func TestPredictProbaOrdered(t *testing.T) {
+	set := syntheticSet(300)
+	model, err := Train(set, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	classes := model.Classes()
+	for i, item := range set {
+		ordered, err := model.PredictProbaOrdered(item)
+		if err != nil {
+			t.Fatalf("item %d: %v", i, err)
+		}
+		proba, _ := model.PredictProba(item)
+		if len(ordered) != len(classes) {
+			t.Fatalf("item %d: got %d entries, want %d", i, len(ordered), len(classes))
+		}
+		for j, cp := range ordered {
+			if cp.Class != classes[j] {
+				t.Fatalf("item %d: entry %d is %q, want %q", i, j, cp.Class, classes[j])
+			}
+			if cp.Proba != proba[cp.Class] {
+				t.Fatalf("item %d: %s = %v, PredictProba gives %v", i, cp.Class, cp.Proba, proba[cp.Class])
+			}
+		}
+	}
+	if _, err := (*Model)(nil).PredictProbaOrdered(set[0]); err == nil {
+		t.Fatal("expected error for nil model")
+	}
+}
+
+func TestPredictBatchProgress(t *testing.T) {
+	model, _ := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	var items []TrainingItem
+	for i := 0; i < 100; i++ {
+		items = append(items, playTennisSet()...)
+	}
+	var calls [][2]int
+	got, err := model.PredictBatchProgress(items, func(done, total int) {
+		calls = append(calls, [2]int{done, total})
+	})
+	if err != nil {
+		t.Fatalf("prediction failed: %v", err)
+	}
+	want, _ := model.PredictBatch(items)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("progress reporting changed the predictions")
+	}
+	if len(calls) != 100 {
+		t.Errorf("got %d progress calls, want one per percent", len(calls))
+	}
+	for i, c := range calls {
+		if c[1] != len(items) || (i > 0 && c[0] <= calls[i-1][0]) {
+			t.Fatalf("call %d = %v: totals must match and done must increase", i, c)
+		}
+	}
+	if last := calls[len(calls)-1]; last[0] != last[1] {
+		t.Fatalf("final call reported %d of %d", last[0], last[1])
+	}
+
+	// Errors behave as in PredictBatch: partial results, no call for the failure.
+	calls = nil
+	partial, err := model.PredictBatchProgress([]TrainingItem{items[0], nil}, func(done, total int) {
+		calls = append(calls, [2]int{done, total})
+	})
+	if err == nil || len(partial) != 1 || len(calls) != 1 || calls[0] != [2]int{1, 2} {
+		t.Fatalf("got %v, %v, calls %v", partial, err, calls)
+	}
+	if _, err := model.PredictBatchProgress(items[:3], nil); err != nil {
+		t.Fatalf("nil callback failed: %v", err)
+	}
+}
+
+func TestPredictProbaBatch_ErrorHandling(t *testing.T) {
+	ts := TrainingSet{
+		TrainingItem{"feature": "a", "label": "yes"},
+		TrainingItem{"feature": "b", "label": "no"},
+	}
+	cfg := Config{CategoryAttr: "label"}
+	model, _ := Train(ts, cfg)
+
+	// Test with one nil item in batch
+	items := []TrainingItem{
+		{"feature": "a"},
+		nil,
+		{"feature": "b"},
+	}
+	results, err := model.PredictProbaBatch(items)
+	if err == nil {
+		t.Fatal("expected error for nil item in batch")
+	}
+	// Should return partial results (first item only)
+	if len(results) != 1 {
+		t.Fatalf("expected 1 partial result, got %d", len(results))
+	}
+}
+
+// syntheticSet builds a deterministic mixed-type dataset for benchmarks.
+func syntheticSet(n int) TrainingSet {
+	colors := []string{"red", "green", "blue", "black"}
+	set := make(TrainingSet, 0, n)
+	for i := 0; i < n; i++ {
+		x := float64((i * 7919) % 100)
+		y := float64((i * 104729) % 50)
+		c := colors[(i*31)%len(colors)]
+		label := "a"
+		switch {
+		case x+y > 90:
+			label = "b"
+		case c == "red" && x > 30:
+			label = "c"
+		}
+		if i%17 == 0 {
+			label = "a" // label noise
+		}
+		set = append(set, TrainingItem{"x": x, "y": y, "color": c, "label": label})
+	}
+	return set
+}
+
+func BenchmarkTrain_Synthetic(b *testing.B) {
+	set := syntheticSet(3000)
+	cfg := Config{CategoryAttr: "label"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Train(set, cfg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTrain_MaxThresholds(b *testing.B) {
+	set := diagonalSet(2000, 1)
+	for _, n := range []int{0, 32, 8} {
+		cfg := Config{CategoryAttr: "label", MaxThresholds: n}
+		b.Run(fmt.Sprintf("thresholds=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := Train(set, cfg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// countingObserver tallies training callbacks.
+type countingObserver struct {
+	nodes, samples int
+	maxDepth       int
+	splits         map[string]int
+}
+
+func (o *countingObserver) OnNodeCreated(depth, samples int) {
+	o.nodes++
+	if depth == 0 {
+		o.samples = samples
+	}
+	if depth > o.maxDepth {
+		o.maxDepth = depth
+	}
+}
+
+func (o *countingObserver) OnSplitEvaluated(attr string) {
+	if o.splits == nil {
+		o.splits = make(map[string]int)
+	}
+	o.splits[attr]++
+}
+
+func BenchmarkTrain_Observer(b *testing.B) {
+	set := syntheticSet(3000)
+	cfg := Config{CategoryAttr: "label", Observer: &countingObserver{}}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Train(set, cfg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTrain_Observer(t *testing.T) {
+	for _, cfg := range []Config{
+		{CategoryAttr: "label"},
+		{CategoryAttr: "label", MultiwaySplits: true, MaxDepth: 4},
+	} {
+		obs := &countingObserver{}
+		cfg.Observer = obs
+		set := syntheticSet(300)
+		model, err := Train(set, cfg)
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		stats := model.Stats()
+		if obs.nodes != stats.TotalNodes {
+			t.Errorf("observed %d nodes, tree has %d", obs.nodes, stats.TotalNodes)
+		}
+		if obs.maxDepth != stats.TreeDepth || obs.samples != len(set) {
+			t.Errorf("observed depth %d and %d root samples, want %d and %d", obs.maxDepth, obs.samples, stats.TreeDepth, len(set))
+		}
+		if obs.splits[model.Root.Attribute] == 0 {
+			t.Errorf("no evaluations recorded for root attribute %q: %v", model.Root.Attribute, obs.splits)
+		}
+		if model.Config.Observer != nil {
+			t.Error("trained model should not retain the observer")
+		}
+		cfg.Observer = nil
+		plain, _ := Train(set, cfg)
+		if !model.Equal(plain) {
+			t.Errorf("observer changed the tree: %v", model.Diff(plain))
+		}
+	}
+}
+
+func TestTrain_Verbose(t *testing.T) {
+	var log bytes.Buffer
+	cfg := Config{CategoryAttr: "Play", Verbose: true, LogOutput: &log}
+	model, err := Train(playTennisSet(), cfg)
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	out := log.String()
+	if !strings.Contains(out, "(pure node)") {
+		t.Errorf("expected a pure node reason in the log:\n%s", out)
+	}
+	root := "[depth 0] " + strconv.Itoa(len(playTennisSet())) + " samples: split on " + model.Root.condition() + " (gain "
+	if !strings.HasPrefix(out, root) {
+		t.Errorf("first line should describe the root split:\n%s", out)
+	}
+	if got, want := strings.Count(out, "\n"), model.Stats().TotalNodes; got != want {
+		t.Errorf("got %d log lines, want one per node (%d)", got, want)
+	}
+	if model.Config.Verbose || model.Config.LogOutput != nil {
+		t.Error("Verbose and LogOutput should be cleared from the trained model")
+	}
+
+	log.Reset()
+	cfg.MaxDepth = 1
+	if _, err := Train(playTennisSet(), cfg); err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if !strings.Contains(log.String(), "(max depth)") {
+		t.Errorf("expected a max depth reason in the log:\n%s", log.String())
+	}
+}
+
+func TestTrain_MaxThresholdsDegradesGracefully(t *testing.T) {
+	train, test := diagonalSet(600, 21), diagonalSet(600, 22)
+	full, err := Train(train, Config{CategoryAttr: "label", MaxDepth: 6})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	fullAcc := accuracy(t, full.Predict, test)
+	for _, n := range []int{64, 16, 4} {
+		model, err := Train(train, Config{CategoryAttr: "label", MaxDepth: 6, MaxThresholds: n})
+		if err != nil {
+			t.Fatalf("training with %d thresholds failed: %v", n, err)
+		}
+		acc := accuracy(t, model.Predict, test)
+		if acc < fullAcc-0.1 {
+			t.Errorf("%d thresholds: accuracy %.3f fell far below %.3f", n, acc, fullAcc)
+		}
+	}
+}
+
+func TestQuantilePivots(t *testing.T) {
+	var set TrainingSet
+	for i := 0; i < 100; i++ {
+		set = append(set, TrainingItem{"x": float64(i), "few": float64(i % 3)})
+	}
+	pivots := quantilePivots(set, []string{"x", "few"}, 4)
+	if len(pivots["x"]) != 4 || !pivots["x"][20] || !pivots["x"][80] {
+		t.Fatalf("unexpected quantiles for x: %v", pivots["x"])
+	}
+	if len(pivots["few"]) != 3 {
+		t.Fatalf("attributes with fewer values than the cap keep all of them: %v", pivots["few"])
+	}
+}
+
+func colorSet() TrainingSet {
+	return TrainingSet{
+		TrainingItem{"color": "red", "size": "small", "label": "apple"},
+		TrainingItem{"color": "red", "size": "large", "label": "apple"},
+		TrainingItem{"color": "green", "size": "small", "label": "lime"},
+		TrainingItem{"color": "green", "size": "large", "label": "lime"},
+		TrainingItem{"color": "yellow", "size": "small", "label": "lemon"},
+		TrainingItem{"color": "yellow", "size": "large", "label": "banana"},
+		TrainingItem{"color": "yellow", "size": "large", "label": "banana"},
+	}
+}
+
+func TestTrain_MultiwaySplits(t *testing.T) {
+	model, err := Train(colorSet(), Config{CategoryAttr: "label", MultiwaySplits: true})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	root := model.Root
+	if root.Attribute != "color" || root.PredicateName != "in" {
+		t.Fatalf("expected multiway split on color, got %s %s", root.Attribute, root.PredicateName)
+	}
+	if len(root.Children) != 3 || root.Match != nil || root.NoMatch != nil {
+		t.Fatalf("expected 3 children and no binary branches, got %d", len(root.Children))
+	}
+	for _, v := range []string{"red", "green", "yellow"} {
+		if root.Children[v] == nil {
+			t.Errorf("missing child for %q", v)
+		}
+	}
+	if err := model.Validate(); err != nil {
+		t.Fatalf("multiway model should validate: %v", err)
+	}
+
+	for _, item := range colorSet() {
+		pred, err := model.Predict(item)
+		if err != nil {
+			t.Fatalf("predict failed: %v", err)
+		}
+		if pred != item["label"] {
+			t.Errorf("item %v: expected %v, got %s", item, item["label"], pred)
+		}
+	}
+
+	// Unseen and missing values fall back to the root's majority class.
+	for _, item := range []TrainingItem{{"color": "purple", "size": "small"}, {"size": "small"}} {
+		pred, err := model.Predict(item)
+		if err != nil {
+			t.Fatalf("predict failed: %v", err)
+		}
+		if pred != "apple" {
+			t.Errorf("item %v: expected majority fallback apple, got %s", item, pred)
+		}
+	}
+
+	stats := model.Stats()
+	if stats.TotalNodes != stats.LeafNodes+stats.InternalNodes || len(stats.Classes) != 4 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if text := model.ToText(); !strings.Contains(text, "yellow: size") {
+		t.Errorf("expected multiway branch labels in text output:\n%s", text)
+	}
+}
+
+func TestTrain_MultiwayMissingChild(t *testing.T) {
+	// Items without a color are all "unknown"; "<nil>" is an ordinary color.
+	var set TrainingSet
+	for i := 0; i < 4; i++ {
+		set = append(set,
+			TrainingItem{"color": "red", "label": "apple"},
+			TrainingItem{"color": "<nil>", "label": "grape"},
+			TrainingItem{"color": nil, "label": "unknown"},
+			TrainingItem{"label": "unknown"},
+		)
+	}
+	model, err := Train(set, Config{CategoryAttr: "label", MultiwaySplits: true})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	root := model.Root
+	if root.Missing == nil || len(root.Children) != 2 {
+		t.Fatalf("expected children red and <nil> plus a missing child, got %d children, missing %v", len(root.Children), root.Missing)
+	}
+	if got := root.Missing.ClassCounts; got["unknown"] != 8 || len(got) != 1 {
+		t.Fatalf("missing child counts = %v, want unknown=8", got)
+	}
+	if err := model.Validate(); err != nil {
+		t.Fatalf("model should validate: %v", err)
+	}
+
+	for _, tc := range []struct {
+		item TrainingItem
+		want string
+	}{
+		{TrainingItem{"color": nil}, "unknown"},
+		{TrainingItem{}, "unknown"},
+		{TrainingItem{"color": math.NaN()}, "unknown"},
+		{TrainingItem{"color": "<nil>"}, "grape"},
+		{TrainingItem{"color": "red"}, "apple"},
+	} {
+		if got, err := model.Predict(tc.item); err != nil || got != tc.want {
+			t.Errorf("%v predicted %q, %v; want %q", tc.item, got, err, tc.want)
+		}
+	}
+
+	loaded := model.Clone()
+	if !model.Equal(loaded) || loaded.Root.Missing == root.Missing {
+		t.Fatal("Clone should deep-copy the missing child")
+	}
+	model.Config.MissingStrategy = MissingFail
+	if _, err := model.Predict(TrainingItem{"color": nil}); err == nil {
+		t.Fatal("expected an error for a nil value with the fail strategy")
+	}
+}
+
+func TestValidate_MultiwayNilChild(t *testing.T) {
+	m := &Model{
+		Config: Config{CategoryAttr: "label"},
+		Root: &TreeItem{
+			Attribute:     "color",
+			PredicateName: "in",
+			ClassCounts:   map[string]int{"a": 1},
+			Children:      map[string]*TreeItem{"red": nil, "blue": {Category: "a", ClassCounts: map[string]int{"a": 1}}},
+		},
+	}
+	if err := m.Validate(); err == nil {
+		t.Fatal("expected error for nil multiway child")
+	}
+}
+
+func TestTrain_SubsetSplits(t *testing.T) {
+	// Red and blue are "yes", green and black "no": no single color
+	// separates the classes, the group {blue, red} does.
+	var set TrainingSet
+	for i := 0; i < 40; i++ {
+		c := []string{"red", "green", "blue", "black"}[i%4]
+		label := "no"
+		if c == "red" || c == "blue" {
+			label = "yes"
+		}
+		set = append(set, TrainingItem{"color": c, "label": label})
+	}
+
+	single, err := BestSplit(set, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("BestSplit failed: %v", err)
+	}
+	cfg := Config{CategoryAttr: "label", SubsetSplits: true}
+	info, err := BestSplit(set, cfg)
+	if err != nil {
+		t.Fatalf("BestSplit failed: %v", err)
+	}
+	if info.PredicateName != "subset" || !reflect.DeepEqual(info.PivotSet, []string{"blue", "red"}) {
+		t.Fatalf("BestSplit = %+v, want subset {blue, red}", info)
+	}
+	if info.Gain <= single.Gain+minGain {
+		t.Fatalf("subset gain %v does not beat the best == split's %v", info.Gain, single.Gain)
+	}
+
+	model, err := Train(set, cfg)
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	root := model.Root
+	if root.PredicateName != "subset" || !root.Match.isLeaf() || !root.NoMatch.isLeaf() {
+		t.Fatalf("expected a single subset split, got %q", root.condition())
+	}
+	if got := root.condition(); got != "color in {blue, red}" {
+		t.Errorf("condition = %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := model.SaveJSON(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadJSON(path)
+	if err != nil {
+		t.Fatalf("reloading failed: %v", err)
+	}
+	for _, m := range []*Model{model, loaded} {
+		for _, tc := range []struct {
+			item TrainingItem
+			want string
+		}{
+			{TrainingItem{"color": "red"}, "yes"},
+			{TrainingItem{"color": "blue"}, "yes"},
+			{TrainingItem{"color": "green"}, "no"},
+			{TrainingItem{"color": "purple"}, "no"},
+			{TrainingItem{"color": nil}, "no"},
+		} {
+			if got, err := m.Predict(tc.item); err != nil || got != tc.want {
+				t.Errorf("item %v: got %q, %v; want %q", tc.item, got, err, tc.want)
+			}
+		}
+	}
+
+	loaded.Root.PivotSet = []string{"red", "blue"}
+	if err := loaded.Validate(); err == nil {
+		t.Error("expected error for an unsorted pivotSet")
+	}
+}
+
+func TestCounterUniqueValues_Bool(t *testing.T) {
+	ts := TrainingSet{
+		TrainingItem{"label": true},
+		TrainingItem{"label": true},
+		TrainingItem{"label": false},
+	}
+	counts := counterUniqueValues(ts, "label")
+	if counts["true"] != 2 || counts["false"] != 1 || len(counts) != 2 {
+		t.Fatalf("expected {true:2 false:1}, got %v", counts)
+	}
+}
+
+func TestTrain_BoolFeatureAndLabel(t *testing.T) {
+	ts := TrainingSet{
+		TrainingItem{"windy": true, "sunny": "yes", "stay": true},
+		TrainingItem{"windy": true, "sunny": "no", "stay": true},
+		TrainingItem{"windy": false, "sunny": "yes", "stay": false},
+		TrainingItem{"windy": false, "sunny": "no", "stay": false},
+	}
+	model, err := Train(ts, Config{CategoryAttr: "stay"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if model.Root.Attribute != "windy" {
+		t.Fatalf("expected split on windy, got %q", model.Root.Attribute)
+	}
+	if model.Root.ClassCounts["true"] != 2 || model.Root.ClassCounts["false"] != 2 {
+		t.Fatalf("unexpected root counts %v", model.Root.ClassCounts)
+	}
+	for _, item := range ts {
+		pred, err := model.Predict(item)
+		if err != nil {
+			t.Fatalf("predict failed: %v", err)
+		}
+		if want := fmt.Sprint(item["stay"]); pred != want {
+			t.Errorf("item %v: expected %s, got %s", item, want, pred)
+		}
+	}
+}
+
+// cancelAfterCtx reports context.Canceled once Err has been called n times,
+// simulating a cancellation that lands partway through training.
+type cancelAfterCtx struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfterCtx) Err() error {
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestTrainContext_CancelMidTraining(t *testing.T) {
+	set := syntheticSet(5000)
+	ctx := &cancelAfterCtx{Context: context.Background(), n: 2000}
+	model, err := TrainContext(ctx, set, Config{CategoryAttr: "label"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if model != nil {
+		t.Fatal("expected no model on cancellation")
+	}
+	if ctx.n > 0 {
+		t.Fatal("training finished before the cancellation point")
+	}
+}
+
+func TestTrainContext_Deadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	if _, err := TrainContext(ctx, syntheticSet(100), Config{CategoryAttr: "label"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if _, err := TrainContext(context.Background(), syntheticSet(100), Config{CategoryAttr: "label"}); err != nil {
+		t.Fatalf("training with a live context failed: %v", err)
+	}
+}
+
+// bumpySet is positive for large x, plus a spurious positive band at 20..29
+// that an unconstrained tree will carve out.
+func bumpySet() TrainingSet {
+	var ts TrainingSet
+	for x := 0; x < 100; x++ {
+		label := "0"
+		if x >= 60 || (x >= 20 && x < 30) {
+			label = "1"
+		}
+		ts = append(ts, TrainingItem{"x": float64(x), "label": label})
+	}
+	return ts
+}
+
+// noisyMonotoneSet draws n items whose chance of label "1" rises with x and
+// with each of the extra attributes, all uniform on [0, 10).
+func noisyMonotoneSet(seed int64, n int, extra ...string) TrainingSet {
+	rng := rand.New(rand.NewSource(seed))
+	var ts TrainingSet
+	for i := 0; i < n; i++ {
+		item := TrainingItem{"x": rng.Float64() * 10}
+		sum := item["x"].(float64)
+		for _, attr := range extra {
+			v := rng.Float64() * 10
+			item[attr] = v
+			sum += v
+		}
+		item["label"] = "0"
+		if rng.Float64()*10*float64(len(extra)+1) < sum {
+			item["label"] = "1"
+		}
+		ts = append(ts, item)
+	}
+	return ts
+}
+
+// monotoneViolations counts the steps along x, over a grid of the extra
+// attributes, where m's probability of "1" decreases.
+func monotoneViolations(t *testing.T, m *Model, extra ...string) int {
+	t.Helper()
+	grid := []TrainingItem{{}}
+	for _, attr := range extra {
+		var next []TrainingItem
+		for _, item := range grid {
+			for v := 0.5; v < 10; v += 3 {
+				cp := TrainingItem{attr: v}
+				for k, w := range item {
+					cp[k] = w
+				}
+				next = append(next, cp)
+			}
+		}
+		grid = next
+	}
+	n := 0
+	for _, item := range grid {
+		prev := 0.0
+		for x := 0.0; x <= 10; x += 0.25 {
+			item["x"] = x
+			proba, err := m.PredictProba(item)
+			if err != nil {
+				t.Fatalf("predict proba failed: %v", err)
+			}
+			if proba["1"] < prev {
+				n++
+			}
+			prev = proba["1"]
+		}
+	}
+	return n
+}
+
+func positiveCurve(t *testing.T, m *Model) []float64 {
+	t.Helper()
+	var curve []float64
+	for x := -5; x < 105; x++ {
+		proba, err := m.PredictProba(TrainingItem{"x": float64(x)})
+		if err != nil {
+			t.Fatalf("predict proba failed: %v", err)
+		}
+		curve = append(curve, proba["1"])
+	}
+	return curve
+}
+
+func TestTrain_MonotoneConstraints(t *testing.T) {
+	free, err := Train(bumpySet(), Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	decreases := false
+	curve := positiveCurve(t, free)
+	for i := 1; i < len(curve); i++ {
+		if curve[i] < curve[i-1] {
+			decreases = true
+		}
+	}
+	if !decreases {
+		t.Fatal("test data should produce a non-monotone unconstrained tree")
+	}
+
+	cfg := Config{CategoryAttr: "label", MonotoneConstraints: map[string]int{"x": 1}}
+	model, err := Train(bumpySet(), cfg)
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	curve = positiveCurve(t, model)
+	for i := 1; i < len(curve); i++ {
+		if curve[i] < curve[i-1] {
+			t.Fatalf("positive probability decreases at x=%d: %v -> %v", i-5, curve[i-1], curve[i])
+		}
+	}
+	if curve[0] >= curve[len(curve)-1] {
+		t.Fatalf("expected the constrained tree to still rise with x: %v", curve)
+	}
+
+	cfg.MonotoneConstraints["x"] = -1
+	model, _ = Train(bumpySet(), cfg)
+	curve = positiveCurve(t, model)
+	for i := 1; i < len(curve); i++ {
+		if curve[i] > curve[i-1] {
+			t.Fatalf("decreasing constraint violated at x=%d", i-5)
+		}
+	}
+}
+
+func TestTrain_InvalidMonotoneConstraint(t *testing.T) {
+	cfg := Config{CategoryAttr: "label", MonotoneConstraints: map[string]int{"x": 2}}
+	if _, err := Train(bumpySet(), cfg); err == nil {
+		t.Fatal("expected error for constraint value 2")
+	}
+}
+
+func TestPredictLeaf_StableAcrossReloads(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if model.Root.ID != 1 {
+		t.Fatalf("expected root ID 1, got %d", model.Root.ID)
+	}
+	ids := make(map[int]bool)
+	var walk func(n *TreeItem)
+	walk = func(n *TreeItem) {
+		if ids[n.ID] || n.ID == 0 {
+			t.Fatalf("node ID %d is missing or duplicated", n.ID)
+		}
+		ids[n.ID] = true
+		for _, b := range n.branches() {
+			walk(b.node)
+		}
+	}
+	walk(model.Root)
+
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := model.SaveJSON(path); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	first, _ := LoadJSON(path)
+	second, _ := LoadJSON(path)
+	for _, item := range playTennisSet() {
+		id1, class1, err := first.PredictLeaf(item)
+		if err != nil {
+			t.Fatalf("predict leaf failed: %v", err)
+		}
+		id2, class2, _ := second.PredictLeaf(item)
+		idOrig, _, _ := model.PredictLeaf(item)
+		if id1 != id2 || id1 != idOrig || class1 != class2 {
+			t.Fatalf("leaf IDs differ across loads: %d/%d/%d", idOrig, id1, id2)
+		}
+		if want, _ := model.Predict(item); class1 != want {
+			t.Fatalf("PredictLeaf class %q, Predict %q", class1, want)
+		}
+	}
+}
+
+func TestPredictWithThreshold_Sweep(t *testing.T) {
+	train := syntheticSet(400)
+	for _, item := range train {
+		if item["label"] == "c" {
+			item["label"] = "a"
+		}
+	}
+	model, err := Train(train, Config{CategoryAttr: "label", MaxDepth: 3})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	prevRate := 2.0
+	for _, threshold := range []float64{0, 0.1, 0.25, 0.5, 0.75, 0.9, 1} {
+		positives := 0
+		for _, item := range train {
+			pred, err := model.PredictWithThreshold(item, "b", threshold)
+			if err != nil {
+				t.Fatalf("threshold %v: %v", threshold, err)
+			}
+			if pred == "b" {
+				positives++
+			}
+		}
+		rate := float64(positives) / float64(len(train))
+		if rate > prevRate {
+			t.Fatalf("positive rate rose from %v to %v at threshold %v", prevRate, rate, threshold)
+		}
+		if threshold == 0 && rate != 1 {
+			t.Fatalf("threshold 0 should always predict the positive class, rate %v", rate)
+		}
+		prevRate = rate
+	}
+}
+
+func TestPredictWithThreshold_Errors(t *testing.T) {
+	binary, _ := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	item := playTennisSet()[0]
+	if _, err := binary.PredictWithThreshold(item, "maybe", 0.5); err == nil {
+		t.Error("expected error for unknown positive class")
+	}
+	for _, th := range []float64{-0.1, 1.1, math.NaN()} {
+		if _, err := binary.PredictWithThreshold(item, "yes", th); err == nil {
+			t.Errorf("expected error for threshold %v", th)
+		}
+	}
+	multi, _ := Train(syntheticSet(200), Config{CategoryAttr: "label"})
+	if _, err := multi.PredictWithThreshold(TrainingItem{"x": 1.0}, "a", 0.5); err == nil {
+		t.Error("expected error for multi-class model")
+	}
+}
+
+func TestTrain_FeatureWarnings(t *testing.T) {
+	ts := TrainingSet{
+		TrainingItem{"x": 1.0, "const": "same", "empty": nil, "sparse": "a", "label": "a"},
+		TrainingItem{"x": 2.0, "const": "same", "empty": nil, "label": "b"},
+		TrainingItem{"x": 3.0, "const": "same", "label": "b"},
+	}
+	model, err := Train(ts, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	want := []string{`feature "const" is constant`, `feature "empty" has only missing values`}
+	if got := model.Metadata.Warnings; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("warnings = %q, want %q", got, want)
+	}
+
+	plain, _ := Train(ts, Config{CategoryAttr: "label", IgnoredAttributes: []string{"const", "empty"}})
+	if len(plain.Metadata.Warnings) != 0 {
+		t.Fatalf("ignored attributes should not warn: %q", plain.Metadata.Warnings)
+	}
+	if model.Root.Attribute != plain.Root.Attribute {
+		t.Fatal("warnings must not change the tree")
+	}
+}
+
+func TestTrain_IgnoredAttributes(t *testing.T) {
+	// tmp_a and id each separate the classes perfectly, so the tree uses
+	// them unless they are ignored.
+	var ts TrainingSet
+	for i := 0; i < 20; i++ {
+		label := "a"
+		if i%2 == 1 {
+			label = "b"
+		}
+		ts = append(ts, TrainingItem{
+			"x":     float64(i%4) + float64(i%2)*0.5,
+			"tmp_a": label,
+			"tmp_b": float64(i % 2),
+			"id":    label + strconv.Itoa(i%2),
+			"label": label,
+		})
+	}
+	for _, tc := range []struct {
+		name    string
+		ignored []string
+	}{
+		{"exact", []string{"tmp_a", "tmp_b", "id"}},
+		{"glob", []string{"tmp_*", "id"}},
+	} {
+		model, err := Train(ts, Config{CategoryAttr: "label", IgnoredAttributes: tc.ignored})
+		if err != nil {
+			t.Fatalf("%s: training failed: %v", tc.name, err)
+		}
+		if model.Root.isLeaf() {
+			t.Fatalf("%s: expected a split on x", tc.name)
+		}
+		for attr := range model.FeatureImportance() {
+			if attr != "x" {
+				t.Errorf("%s: tree splits on ignored attribute %q", tc.name, attr)
+			}
+		}
+	}
+
+	cfg := Config{CategoryAttr: "label", IgnoredAttributes: []string{"tmp_*", "idd", "scratch_*"}}
+	quiet, _ := Train(ts, cfg)
+	if len(quiet.Metadata.Warnings) != 0 {
+		t.Errorf("unused ignores warned without WarnUnusedIgnores: %q", quiet.Metadata.Warnings)
+	}
+	cfg.WarnUnusedIgnores = true
+	model, err := Train(ts, cfg)
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	want := []string{`ignored attribute "idd" matches no attribute`, `ignored attribute "scratch_*" matches no attribute`}
+	if got := model.Metadata.Warnings; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"", "tmp_[a"} {
+		if _, err := Train(ts, Config{CategoryAttr: "label", IgnoredAttributes: []string{bad}}); err == nil {
+			t.Errorf("expected error for ignored entry %q", bad)
+		}
+	}
+}
+
+func TestTrain_RecordsSplitGain(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if model.Root.Gain <= 0 {
+		t.Fatalf("root gain = %v, want positive", model.Root.Gain)
+	}
+	var walk func(n *TreeItem)
+	walk = func(n *TreeItem) {
+		if n.isLeaf() && n.Gain != 0 {
+			t.Errorf("leaf %d has gain %v", n.ID, n.Gain)
+		}
+		for _, b := range n.branches() {
+			walk(b.node)
+		}
+	}
+	walk(model.Root)
+
+	// Models saved before gains were recorded still load.
+	old := model.Clone()
+	var clear func(n *TreeItem)
+	clear = func(n *TreeItem) {
+		n.Gain = 0
+		for _, b := range n.branches() {
+			clear(b.node)
+		}
+	}
+	clear(old.Root)
+	var buf bytes.Buffer
+	if err := encodeIndented(&buf, old); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), `"gain"`) {
+		t.Error("zero gains should be omitted from JSON")
+	}
+	if _, err := DecodeJSON(&buf); err != nil {
+		t.Fatalf("model without gains should load: %v", err)
+	}
+
+	bad := model.Clone()
+	bad.Root.Gain = -1
+	if err := bad.Validate(); err == nil {
+		t.Error("expected error for a negative gain")
+	}
+	bad.Root.Gain = math.NaN()
+	if err := bad.Validate(); err == nil {
+		t.Error("expected error for a NaN gain")
+	}
+}
+
+// quadrantSet labels points in the unit square twice: "side" by x and "half"
+// by y, so no single split predicts both labels.
+func quadrantSet(n int, seed int64) TrainingSet {
+	rng := rand.New(rand.NewSource(seed))
+	ts := make(TrainingSet, n)
+	for i := range ts {
+		x, y := rng.Float64(), rng.Float64()
+		side, half := "left", "bottom"
+		if x >= 0.5 {
+			side = "right"
+		}
+		if y >= 0.5 {
+			half = "top"
+		}
+		ts[i] = TrainingItem{"x": x, "y": y, "side": side, "half": half}
+	}
+	return ts
+}
+
+func TestTrain_MultiOutput(t *testing.T) {
+	model, err := Train(quadrantSet(400, 1), Config{CategoryAttrs: []string{"side", "half"}})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if model.Config.CategoryAttr != "side" {
+		t.Errorf("CategoryAttr = %q, want the first output", model.Config.CategoryAttr)
+	}
+	if counts := model.Root.OutputCounts; len(counts["side"]) != 2 || len(counts["half"]) != 2 {
+		t.Fatalf("root OutputCounts = %v, want both labels", counts)
+	}
+
+	test := quadrantSet(200, 2)
+	correct := map[string]int{}
+	for _, item := range test {
+		preds, err := model.PredictMulti(item)
+		if err != nil {
+			t.Fatalf("PredictMulti failed: %v", err)
+		}
+		for _, attr := range []string{"side", "half"} {
+			if preds[attr] == item[attr] {
+				correct[attr]++
+			}
+		}
+		if pred, _ := model.Predict(item); pred != preds["side"] {
+			t.Errorf("Predict = %q, PredictMulti side = %q", pred, preds["side"])
+		}
+	}
+	for attr, n := range correct {
+		if acc := float64(n) / float64(len(test)); acc < 0.95 {
+			t.Errorf("%s accuracy = %.3f, want >= 0.95", attr, acc)
+		}
+	}
+	if len(correct) != 2 {
+		t.Errorf("correct = %v, want both labels", correct)
+	}
+
+	// Single-output models answer under CategoryAttr.
+	single, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	preds, err := single.PredictMulti(playTennisSet()[0])
+	if err != nil || len(preds) != 1 || preds["Play"] != "no" {
+		t.Errorf("single-output PredictMulti = %v, %v", preds, err)
+	}
+	if single.Root.OutputCounts != nil {
+		t.Error("single-output models should not record OutputCounts")
+	}
+}
+
+func TestTrain_MultiOutputConfigErrors(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"duplicate":    {CategoryAttrs: []string{"side", "side"}},
+		"empty name":   {CategoryAttrs: []string{"side", ""}},
+		"mismatch":     {CategoryAttr: "half", CategoryAttrs: []string{"side", "half"}},
+		"histogram":    {CategoryAttrs: []string{"side", "half"}, HistogramBins: 8},
+		"oblique":      {CategoryAttrs: []string{"side", "half"}, AllowObliqueSplits: true},
+		"missing attr": {CategoryAttrs: []string{"side", "colour"}},
+	} {
+		if _, err := Train(quadrantSet(50, 1), cfg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestTrainBaseline_PredictsMajority(t *testing.T) {
+	set := playTennisSet() // 4 yes, 3 no
+	model, err := TrainBaseline(set, Config{CategoryAttr: "Play", MaxDepth: 3})
+	if err != nil {
+		t.Fatalf("TrainBaseline failed: %v", err)
+	}
+	if err := model.Validate(); err != nil {
+		t.Fatalf("baseline model is invalid: %v", err)
+	}
+	stats := model.Stats()
+	if stats.LeafNodes != 1 || stats.InternalNodes != 0 {
+		t.Errorf("stats = %+v, want a single leaf", stats)
+	}
+	if !reflect.DeepEqual(model.Root.ClassCounts, map[string]int{"yes": 4, "no": 3}) {
+		t.Errorf("ClassCounts = %v", model.Root.ClassCounts)
+	}
+	for _, item := range append(set, TrainingItem{"Outlook": "fog"}) {
+		if pred, err := model.Predict(item); err != nil || pred != "yes" {
+			t.Errorf("Predict(%v) = %q, %v; want yes", item, pred, err)
+		}
+	}
+	if model.Metadata == nil || model.Metadata.NumSamples != len(set) {
+		t.Errorf("metadata = %+v", model.Metadata)
+	}
+
+	// The single-leaf model survives a save/load round trip.
+	var buf bytes.Buffer
+	if err := encodeIndented(&buf, model); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := DecodeJSON(&buf)
+	if err != nil {
+		t.Fatalf("DecodeJSON failed: %v", err)
+	}
+	if pred, _ := loaded.Predict(set[0]); pred != "yes" {
+		t.Errorf("reloaded baseline predicts %q", pred)
+	}
+
+	// Weights decide the majority.
+	weighted := playTennisSet()
+	for _, item := range weighted {
+		item["w"] = 1.0
+		if item["Play"] == "no" {
+			item["w"] = 3.0
+		}
+	}
+	wm, err := TrainBaseline(weighted, Config{CategoryAttr: "Play", WeightAttr: "w"})
+	if err != nil {
+		t.Fatalf("TrainBaseline failed: %v", err)
+	}
+	if pred, _ := wm.Predict(weighted[0]); pred != "no" {
+		t.Errorf("weighted baseline predicts %q, want no", pred)
+	}
+
+	if _, err := TrainBaseline(set, Config{CategoryAttr: "missing"}); err == nil {
+		t.Error("expected error for a missing label")
 	}
 }
@@ -0,0 +1,190 @@
+package dtree
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// OneHotColumn is a boolean column generated by OneHotEncode. It records
+// the attribute and value the column stands for, so columns are never
+// matched back to attributes by name.
+type OneHotColumn struct {
+	Name  string `json:"name"`
+	Attr  string `json:"attr"`
+	Value string `json:"value"`
+}
+
+// OneHotEncode expands each categorical attribute in attrs into boolean
+// "attr=value" columns, one per distinct value seen in set, and drops the
+// original attribute. It returns a new set (the input is not modified) and
+// the generated columns in a stable order. Missing (nil) values encode as
+// all-false columns. Attributes not listed, including the label, are copied
+// unchanged. It fails if two generated columns would share a name, as with
+// attributes "a" and "a=b" holding the values "b=c" and "c".
+func OneHotEncode(set TrainingSet, attrs []string) (TrainingSet, []OneHotColumn, error) {
+	if len(set) == 0 {
+		return nil, nil, errors.New("training set cannot be empty")
+	}
+	var columns []OneHotColumn
+	names := make(map[string]string)
+	for _, attr := range attrs {
+		values := make(map[string]bool)
+		seen := false
+		for _, item := range set {
+			v, ok := item[attr]
+			if !ok {
+				continue
+			}
+			seen = true
+			if v == nil {
+				continue
+			}
+			if isNumeric(v) {
+				return nil, nil, fmt.Errorf("attribute %q is numeric, not categorical", attr)
+			}
+			values[valueKey(v)] = true
+		}
+		if !seen {
+			return nil, nil, fmt.Errorf("attribute %q not found in any training items", attr)
+		}
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			name := attr + "=" + k
+			if other, ok := names[name]; ok {
+				return nil, nil, fmt.Errorf("column %q is generated by both attribute %q and %q", name, other, attr)
+			}
+			names[name] = attr
+			columns = append(columns, OneHotColumn{Name: name, Attr: attr, Value: k})
+		}
+	}
+	return ApplyOneHot(set, attrs, columns), columns, nil
+}
+
+// ApplyOneHot encodes set using columns previously returned by OneHotEncode,
+// typically to prepare prediction inputs the same way as the training data.
+// Values that have no column (unseen during encoding) produce all-false columns.
+func ApplyOneHot(set TrainingSet, attrs []string, columns []OneHotColumn) TrainingSet {
+	out := make(TrainingSet, len(set))
+	for i, item := range set {
+		enc := make(TrainingItem, len(item)+len(columns))
+		for k, v := range item {
+			enc[k] = v
+		}
+		for _, attr := range attrs {
+			delete(enc, attr)
+		}
+		for _, col := range columns {
+			v, ok := item[col.Attr]
+			enc[col.Name] = ok && v != nil && valueKey(v) == col.Value
+		}
+		out[i] = enc
+	}
+	return out
+}
+
+// Binning methods for Discretize.
+const (
+	// BinEqualWidth splits the value range into bins of equal width.
+	BinEqualWidth = "equalwidth"
+	// BinEqualFreq puts about the same number of items into each bin.
+	BinEqualFreq = "equalfreq"
+)
+
+// Discretize replaces the numeric attribute attr with a bin label, so a
+// tree can be trained on it as a category. It returns a new set (the input
+// is not modified) and the bin edges, ascending: edges[0] is the smallest
+// value and edges[len(edges)-1] the largest. method is BinEqualWidth or
+// BinEqualFreq. Equal-frequency cuts that coincide, because of repeated
+// values, are merged, so fewer than bins bins may result. Missing (nil)
+// values stay missing. Use ApplyBins to encode prediction inputs the same way.
+func Discretize(set TrainingSet, attr string, bins int, method string) (TrainingSet, []float64, error) {
+	if len(set) == 0 {
+		return nil, nil, errors.New("training set cannot be empty")
+	}
+	if bins < 2 {
+		return nil, nil, errors.New("bins must be at least 2")
+	}
+	var values []float64
+	for _, item := range set {
+		v, ok := item[attr]
+		if !ok || v == nil || nonFinite(v) {
+			continue
+		}
+		if !isNumeric(v) {
+			return nil, nil, fmt.Errorf("attribute %q is not numeric", attr)
+		}
+		values = append(values, toFloat(v))
+	}
+	if len(values) == 0 {
+		return nil, nil, fmt.Errorf("attribute %q not found in any training items", attr)
+	}
+	sort.Float64s(values)
+	lo, hi := values[0], values[len(values)-1]
+	if lo == hi {
+		return nil, nil, fmt.Errorf("attribute %q has a single value", attr)
+	}
+
+	edges := []float64{lo}
+	switch method {
+	case BinEqualWidth:
+		for i := 1; i < bins; i++ {
+			edges = append(edges, lo+(hi-lo)*float64(i)/float64(bins))
+		}
+	case BinEqualFreq:
+		for i := 1; i < bins; i++ {
+			if cut := values[i*len(values)/bins]; cut > edges[len(edges)-1] {
+				edges = append(edges, cut)
+			}
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown binning method %q (want %s or %s)", method, BinEqualWidth, BinEqualFreq)
+	}
+	if len(edges) < 2 {
+		return nil, nil, fmt.Errorf("attribute %q has too few distinct values to bin", attr)
+	}
+	edges = append(edges, hi)
+	return ApplyBins(set, attr, edges), edges, nil
+}
+
+// ApplyBins encodes attr in set with edges previously returned by
+// Discretize. Values in [edges[i], edges[i+1]) get the label of bin i:
+// "<edges[1]" for the first bin, ">=edges[n-2]" for the last and
+// "[edges[i],edges[i+1])" in between, so values outside the training range
+// fall into the outer bins. Non-numeric values become missing (nil).
+func ApplyBins(set TrainingSet, attr string, edges []float64) TrainingSet {
+	out := make(TrainingSet, len(set))
+	for i, item := range set {
+		enc := make(TrainingItem, len(item))
+		for k, v := range item {
+			enc[k] = v
+		}
+		if v, ok := item[attr]; ok {
+			enc[attr] = nil
+			if finiteNumber(v) && len(edges) >= 3 {
+				enc[attr] = binLabel(edges, toFloat(v))
+			}
+		}
+		out[i] = enc
+	}
+	return out
+}
+
+// binLabel returns the ApplyBins label of v.
+func binLabel(edges []float64, v float64) string {
+	cuts := edges[1 : len(edges)-1]
+	i := sort.Search(len(cuts), func(j int) bool { return cuts[j] > v })
+	format := func(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+	switch i {
+	case 0:
+		return "<" + format(cuts[0])
+	case len(cuts):
+		return ">=" + format(cuts[len(cuts)-1])
+	}
+	return "[" + format(cuts[i-1]) + "," + format(cuts[i]) + ")"
+}
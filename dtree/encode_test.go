@@ -0,0 +1,175 @@
+package dtree
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOneHotEncode(t *testing.T) {
+	set := playTennisSet()
+	enc, cols, err := OneHotEncode(set, []string{"Outlook"})
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	want := []OneHotColumn{
+		{Name: "Outlook=overcast", Attr: "Outlook", Value: "overcast"},
+		{Name: "Outlook=rain", Attr: "Outlook", Value: "rain"},
+		{Name: "Outlook=sunny", Attr: "Outlook", Value: "sunny"},
+	}
+	if !reflect.DeepEqual(cols, want) {
+		t.Fatalf("expected columns %v, got %v", want, cols)
+	}
+	for i, item := range enc {
+		if _, ok := item["Outlook"]; ok {
+			t.Fatalf("row %d still has the original column", i)
+		}
+		if item["Play"] != set[i]["Play"] || item["Humidity"] != set[i]["Humidity"] {
+			t.Fatalf("row %d: unlisted columns changed: %v", i, item)
+		}
+		trues := 0
+		for _, c := range cols {
+			if item[c.Name] == true {
+				trues++
+				if c.Name != "Outlook="+set[i]["Outlook"].(string) {
+					t.Fatalf("row %d: wrong column %s set", i, c.Name)
+				}
+			}
+		}
+		if trues != 1 {
+			t.Fatalf("row %d: expected exactly one true column, got %d", i, trues)
+		}
+	}
+	if _, ok := set[0]["Outlook"]; !ok {
+		t.Fatal("input set must not be modified")
+	}
+
+	// Unseen values at predict time encode as all-false columns.
+	pred := ApplyOneHot(TrainingSet{{"Outlook": "snow", "Humidity": 50.0}}, []string{"Outlook"}, cols)
+	for _, c := range cols {
+		if pred[0][c.Name] != false {
+			t.Fatalf("expected %s=false for unseen value, got %v", c.Name, pred[0][c.Name])
+		}
+	}
+}
+
+func TestOneHotEncode_PrefixedAttributes(t *testing.T) {
+	// "a=b" starts with "a=", but its columns still belong to it alone.
+	set := TrainingSet{
+		{"a": "x", "a=b": "y"},
+		{"a": "z", "a=b": "w"},
+	}
+	enc, cols, err := OneHotEncode(set, []string{"a", "a=b"})
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	if len(cols) != 4 {
+		t.Fatalf("expected 4 columns, got %v", cols)
+	}
+	want := []TrainingItem{
+		{"a=x": true, "a=z": false, "a=b=w": false, "a=b=y": true},
+		{"a=x": false, "a=z": true, "a=b=w": true, "a=b=y": false},
+	}
+	for i := range want {
+		if !reflect.DeepEqual(enc[i], want[i]) {
+			t.Errorf("row %d: expected %v, got %v", i, want[i], enc[i])
+		}
+	}
+
+	// Columns whose names would coincide cannot be told apart.
+	clash := TrainingSet{{"a": "b=c", "a=b": "c"}}
+	if _, _, err := OneHotEncode(clash, []string{"a", "a=b"}); err == nil {
+		t.Error("expected error for colliding column names")
+	}
+}
+
+func TestOneHotEncode_Errors(t *testing.T) {
+	set := playTennisSet()
+	if _, _, err := OneHotEncode(set, []string{"Humidity"}); err == nil {
+		t.Error("expected error for numeric attribute")
+	}
+	if _, _, err := OneHotEncode(set, []string{"Nope"}); err == nil {
+		t.Error("expected error for unknown attribute")
+	}
+	if _, _, err := OneHotEncode(nil, []string{"Outlook"}); err == nil {
+		t.Error("expected error for empty set")
+	}
+}
+
+func TestDiscretize_EqualFreq(t *testing.T) {
+	set := diagonalSet(1000, 7)
+	// Skew x so equal-width bins would be very uneven.
+	for _, item := range set {
+		item["x"] = item["x"].(float64) * item["x"].(float64) * item["x"].(float64)
+	}
+	set[0]["x"] = nil
+	binned, edges, err := Discretize(set, "x", 4, BinEqualFreq)
+	if err != nil {
+		t.Fatalf("Discretize failed: %v", err)
+	}
+	if len(edges) != 5 {
+		t.Fatalf("edges = %v, want 5 edges", edges)
+	}
+	for i := 1; i < len(edges); i++ {
+		if edges[i] <= edges[i-1] {
+			t.Fatalf("edges not increasing: %v", edges)
+		}
+	}
+	counts := make(map[interface{}]int)
+	for _, item := range binned {
+		counts[item["x"]]++
+	}
+	if counts[nil] != 1 {
+		t.Errorf("missing value should stay missing, got %d nils", counts[nil])
+	}
+	delete(counts, nil)
+	if len(counts) != 4 {
+		t.Fatalf("got bins %v, want 4", counts)
+	}
+	for label, n := range counts {
+		if n < 230 || n > 270 {
+			t.Errorf("bin %v has %d items, want about 250", label, n)
+		}
+	}
+	if _, ok := set[1]["x"].(float64); !ok {
+		t.Error("input set should not be modified")
+	}
+
+	if _, err := Train(binned, Config{CategoryAttr: "label"}); err != nil {
+		t.Fatalf("training on bins failed: %v", err)
+	}
+	out := ApplyBins(TrainingSet{{"x": -5.0}, {"x": 99.0}}, "x", edges)
+	if !strings.HasPrefix(out[0]["x"].(string), "<") || !strings.HasPrefix(out[1]["x"].(string), ">=") {
+		t.Errorf("out-of-range values should fall into the outer bins: %v", out)
+	}
+}
+
+func TestDiscretize_EqualWidth(t *testing.T) {
+	set := TrainingSet{{"v": 0.0}, {"v": 1.0}, {"v": 2.0}, {"v": 9.0}, {"v": 10.0}}
+	binned, edges, err := Discretize(set, "v", 2, BinEqualWidth)
+	if err != nil {
+		t.Fatalf("Discretize failed: %v", err)
+	}
+	if want := []float64{0, 5, 10}; !reflect.DeepEqual(edges, want) {
+		t.Fatalf("edges = %v, want %v", edges, want)
+	}
+	if binned[2]["v"] != "<5" || binned[3]["v"] != ">=5" {
+		t.Fatalf("labels = %v, %v", binned[2]["v"], binned[3]["v"])
+	}
+
+	for _, tc := range []struct {
+		set    TrainingSet
+		bins   int
+		method string
+	}{
+		{set, 1, BinEqualWidth},
+		{set, 3, "kmeans"},
+		{TrainingSet{{"v": "a"}, {"v": "b"}}, 2, BinEqualWidth},
+		{TrainingSet{{"v": 1.0}, {"v": 1.0}}, 2, BinEqualFreq},
+		{TrainingSet{{"w": 1.0}}, 2, BinEqualFreq},
+	} {
+		if _, _, err := Discretize(tc.set, "v", tc.bins, tc.method); err == nil {
+			t.Errorf("expected error for %v bins=%d method=%q", tc.set, tc.bins, tc.method)
+		}
+	}
+}
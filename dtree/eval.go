@@ -0,0 +1,346 @@
+package dtree
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ClassMetrics holds the per-class scores of an EvalReport.
+type ClassMetrics struct {
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+	// Support is the number of items whose actual class is this one.
+	Support int `json:"support"`
+}
+
+// EvalReport summarizes how a model's predictions compare to known labels.
+type EvalReport struct {
+	Accuracy float64 `json:"accuracy"`
+	Total    int     `json:"total"`
+	// Classes is the sorted union of actual and predicted classes.
+	Classes []string `json:"classes"`
+	// Confusion counts items by actual class, then predicted class.
+	Confusion map[string]map[string]int `json:"confusion"`
+	PerClass  map[string]ClassMetrics   `json:"perClass"`
+}
+
+// Evaluate predicts every item in set and compares the result with the item's
+// Config.CategoryAttr value. Every item must carry a label.
+func (m *Model) Evaluate(set TrainingSet) (EvalReport, error) {
+	if m == nil {
+		return EvalReport{}, errors.New("model is nil")
+	}
+	if len(set) == 0 {
+		return EvalReport{}, errors.New("evaluation set cannot be empty")
+	}
+	actual := make([]string, len(set))
+	predicted := make([]string, len(set))
+	for i, item := range set {
+		v, ok := item[m.Config.CategoryAttr]
+		if !ok || v == nil {
+			return EvalReport{}, fmt.Errorf("item %d has no %q label", i, m.Config.CategoryAttr)
+		}
+		pred, err := m.Predict(item)
+		if err != nil {
+			return EvalReport{}, fmt.Errorf("item %d: %w", i, err)
+		}
+		actual[i], predicted[i] = valueKey(v), pred
+	}
+	return newEvalReport(actual, predicted), nil
+}
+
+// Score returns the accuracy of m on set, the share of items whose label
+// m.Config.CategoryAttr is predicted correctly, as a shortcut for
+// Evaluate(set).Accuracy. It fails on an empty set or an item without a label.
+func (m *Model) Score(set TrainingSet) (float64, error) {
+	report, err := m.Evaluate(set)
+	if err != nil {
+		return 0, err
+	}
+	return report.Accuracy, nil
+}
+
+// EvaluateCI returns the accuracy of m on set together with a 95% percentile
+// bootstrap confidence interval: set is resampled with replacement bootstraps
+// times and the 2.5th and 97.5th percentiles of the resampled accuracies are
+// the bounds. Each item is predicted once. The result is reproducible for a
+// given seed. Every item in set must carry a label.
+func (m *Model) EvaluateCI(set TrainingSet, bootstraps int, seed int64) (float64, [2]float64, error) {
+	if m == nil {
+		return 0, [2]float64{}, errors.New("model is nil")
+	}
+	if bootstraps <= 0 {
+		return 0, [2]float64{}, errors.New("bootstraps must be positive")
+	}
+	if len(set) == 0 {
+		return 0, [2]float64{}, errors.New("evaluation set cannot be empty")
+	}
+	correct := make([]bool, len(set))
+	hits := 0
+	for i, item := range set {
+		v, ok := item[m.Config.CategoryAttr]
+		if !ok || v == nil {
+			return 0, [2]float64{}, fmt.Errorf("item %d has no %q label", i, m.Config.CategoryAttr)
+		}
+		pred, err := m.Predict(item)
+		if err != nil {
+			return 0, [2]float64{}, fmt.Errorf("item %d: %w", i, err)
+		}
+		if correct[i] = pred == valueKey(v); correct[i] {
+			hits++
+		}
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	accs := make([]float64, bootstraps)
+	for b := range accs {
+		n := 0
+		for range set {
+			if correct[rng.Intn(len(set))] {
+				n++
+			}
+		}
+		accs[b] = float64(n) / float64(len(set))
+	}
+	sort.Float64s(accs)
+	last := float64(bootstraps - 1)
+	ci := [2]float64{accs[int(math.Floor(0.025*last))], accs[int(math.Ceil(0.975*last))]}
+	return float64(hits) / float64(len(set)), ci, nil
+}
+
+// newEvalReport builds a report from parallel slices of actual and predicted classes.
+func newEvalReport(actual, predicted []string) EvalReport {
+	r := EvalReport{
+		Total:     len(actual),
+		Confusion: make(map[string]map[string]int),
+		PerClass:  make(map[string]ClassMetrics),
+	}
+	classSet := make(map[string]bool)
+	correct := 0
+	for i := range actual {
+		a, p := actual[i], predicted[i]
+		classSet[a], classSet[p] = true, true
+		if r.Confusion[a] == nil {
+			r.Confusion[a] = make(map[string]int)
+		}
+		r.Confusion[a][p]++
+		if a == p {
+			correct++
+		}
+	}
+	if r.Total > 0 {
+		r.Accuracy = float64(correct) / float64(r.Total)
+	}
+	for c := range classSet {
+		r.Classes = append(r.Classes, c)
+	}
+	sort.Strings(r.Classes)
+
+	for _, c := range r.Classes {
+		tp := r.Confusion[c][c]
+		support, predictedAs := 0, 0
+		for _, p := range r.Classes {
+			support += r.Confusion[c][p]
+			predictedAs += r.Confusion[p][c]
+		}
+		cm := ClassMetrics{Support: support}
+		if predictedAs > 0 {
+			cm.Precision = float64(tp) / float64(predictedAs)
+		}
+		if support > 0 {
+			cm.Recall = float64(tp) / float64(support)
+		}
+		if cm.Precision+cm.Recall > 0 {
+			cm.F1 = 2 * cm.Precision * cm.Recall / (cm.Precision + cm.Recall)
+		}
+		r.PerClass[c] = cm
+	}
+	return r
+}
+
+// MacroAvg returns the unweighted mean of the per-class metrics, with
+// Support set to the total number of items.
+func (r EvalReport) MacroAvg() ClassMetrics {
+	avg := ClassMetrics{Support: r.Total}
+	if len(r.Classes) == 0 {
+		return avg
+	}
+	for _, c := range r.Classes {
+		cm := r.PerClass[c]
+		avg.Precision += cm.Precision
+		avg.Recall += cm.Recall
+		avg.F1 += cm.F1
+	}
+	n := float64(len(r.Classes))
+	avg.Precision /= n
+	avg.Recall /= n
+	avg.F1 /= n
+	return avg
+}
+
+// WeightedAvg returns the per-class metrics averaged by support.
+func (r EvalReport) WeightedAvg() ClassMetrics {
+	avg := ClassMetrics{Support: r.Total}
+	if r.Total == 0 {
+		return avg
+	}
+	for _, c := range r.Classes {
+		cm := r.PerClass[c]
+		w := float64(cm.Support) / float64(r.Total)
+		avg.Precision += cm.Precision * w
+		avg.Recall += cm.Recall * w
+		avg.F1 += cm.F1 * w
+	}
+	return avg
+}
+
+// String renders the report as a scikit-learn style classification report:
+// precision, recall, f1-score and support per class, followed by accuracy and
+// the macro and weighted averages. Scores use two fixed decimals.
+func (r EvalReport) String() string {
+	width := len("weighted avg")
+	for _, c := range r.Classes {
+		if len(c) > width {
+			width = len(c)
+		}
+	}
+	var b strings.Builder
+	row := func(name string, cm ClassMetrics) {
+		fmt.Fprintf(&b, "%*s  %9.2f %9.2f %9.2f %9d\n", width, name, cm.Precision, cm.Recall, cm.F1, cm.Support)
+	}
+
+	fmt.Fprintf(&b, "%*s  %9s %9s %9s %9s\n\n", width, "", "precision", "recall", "f1-score", "support")
+	for _, c := range r.Classes {
+		row(c, r.PerClass[c])
+	}
+	b.WriteByte('\n')
+	fmt.Fprintf(&b, "%*s  %9s %9s %9.2f %9d\n", width, "accuracy", "", "", r.Accuracy, r.Total)
+	row("macro avg", r.MacroAvg())
+	row("weighted avg", r.WeightedAvg())
+	return b.String()
+}
+
+const confusionHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Evaluation Report</title>
+<style>
+  body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; margin: 40px; color: #2c3e50; }
+  h1 { font-size: 24px; margin-bottom: 4px; }
+  .summary { color: #555; margin-bottom: 24px; }
+  table { border-collapse: collapse; margin-bottom: 32px; }
+  th, td { border: 1px solid #ddd; padding: 8px 14px; text-align: right; }
+  th { background: #f5f6f8; }
+  th.corner { font-weight: normal; color: #777; font-size: 12px; }
+  td.diag { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Evaluation Report</h1>
+<div class="summary">Accuracy <strong>{{.Accuracy}}</strong> on {{.Total}} samples</div>
+
+<h2>Confusion Matrix</h2>
+<table>
+  <tr><th class="corner">actual \ predicted</th>{{range .Classes}}<th>{{.}}</th>{{end}}</tr>
+  {{range .Rows}}<tr><th>{{.Class}}</th>{{range .Cells}}<td{{if .Diag}} class="diag"{{end}} style="{{.Style}}">{{.Count}}</td>{{end}}</tr>
+  {{end}}
+</table>
+
+<h2>Per-Class Scores</h2>
+<table>
+  <tr><th></th><th>precision</th><th>recall</th><th>f1-score</th><th>support</th></tr>
+  {{range .Scores}}<tr><th>{{.Name}}</th><td>{{.Precision}}</td><td>{{.Recall}}</td><td>{{.F1}}</td><td>{{.Support}}</td></tr>
+  {{end}}
+</table>
+</body>
+</html>`
+
+// confusionCell is one cell of the HTML confusion matrix.
+type confusionCell struct {
+	Count int
+	Diag  bool
+	Style template.CSS
+}
+
+// scoreRow is one row of the HTML per-class score table.
+type scoreRow struct {
+	Name                  string
+	Precision, Recall, F1 string
+	Support               int
+}
+
+// ToHTML writes the report as a self-contained HTML page: the accuracy, a
+// confusion matrix with actual classes as rows and predicted classes as
+// columns, each cell shaded in proportion to its count, and the per-class
+// precision, recall and F1 with macro and weighted averages. Classes follow
+// the order of r.Classes.
+func (r EvalReport) ToHTML(path string) error {
+	tmpl, err := template.New("report").Parse(confusionHTMLTemplate)
+	if err != nil {
+		return err
+	}
+	maxCount := 0
+	for _, row := range r.Confusion {
+		for _, n := range row {
+			if n > maxCount {
+				maxCount = n
+			}
+		}
+	}
+	type matrixRow struct {
+		Class string
+		Cells []confusionCell
+	}
+	var rows []matrixRow
+	for _, a := range r.Classes {
+		row := matrixRow{Class: a}
+		for _, p := range r.Classes {
+			n := r.Confusion[a][p]
+			shade := 0.0
+			if maxCount > 0 {
+				shade = float64(n) / float64(maxCount)
+			}
+			style := "background: rgba(52, 152, 219, " + strconv.FormatFloat(shade, 'f', 2, 64) + ")"
+			if shade > 0.6 {
+				style += "; color: #fff"
+			}
+			row.Cells = append(row.Cells, confusionCell{Count: n, Diag: a == p, Style: template.CSS(style)})
+		}
+		rows = append(rows, row)
+	}
+	score := func(name string, cm ClassMetrics) scoreRow {
+		f := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+		return scoreRow{Name: name, Precision: f(cm.Precision), Recall: f(cm.Recall), F1: f(cm.F1), Support: cm.Support}
+	}
+	var scores []scoreRow
+	for _, c := range r.Classes {
+		scores = append(scores, score(c, r.PerClass[c]))
+	}
+	scores = append(scores, score("macro avg", r.MacroAvg()), score("weighted avg", r.WeightedAvg()))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = tmpl.Execute(f, map[string]interface{}{
+		"Accuracy": strconv.FormatFloat(r.Accuracy, 'f', 4, 64),
+		"Total":    r.Total,
+		"Classes":  r.Classes,
+		"Rows":     rows,
+		"Scores":   scores,
+	})
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
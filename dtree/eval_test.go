@@ -0,0 +1,181 @@
+package dtree
+
+import (
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate_PlayTennis(t *testing.T) {
+	set := playTennisSet()
+	model, err := Train(set, Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	report, err := model.Evaluate(set)
+	if err != nil {
+		t.Fatalf("evaluate failed: %v", err)
+	}
+	if report.Accuracy != 1 || report.Total != len(set) {
+		t.Fatalf("expected perfect training accuracy on %d items, got %+v", len(set), report)
+	}
+	support := 0
+	for _, c := range report.Classes {
+		support += report.PerClass[c].Support
+	}
+	if support != len(set) {
+		t.Fatalf("supports sum to %d, want %d", support, len(set))
+	}
+}
+
+func TestEvaluate_MissingLabel(t *testing.T) {
+	model, _ := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if _, err := model.Evaluate(TrainingSet{{"Outlook": "sunny"}}); err == nil {
+		t.Fatal("expected error for item without a label")
+	}
+}
+
+func TestScore(t *testing.T) {
+	set := playTennisSet()
+	model, err := Train(set, Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if score, err := model.Score(set); err != nil || score != 1 {
+		t.Errorf("Score on the training set = %v, %v; want 1", score, err)
+	}
+
+	baseline, err := TrainBaseline(set, Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("TrainBaseline failed: %v", err)
+	}
+	if score, err := baseline.Score(set); err != nil || math.Abs(score-4.0/7) > 1e-12 {
+		t.Errorf("baseline Score = %v, %v; want the majority share 4/7", score, err)
+	}
+
+	if _, err := model.Score(nil); err == nil {
+		t.Error("expected error for an empty set")
+	}
+	if _, err := model.Score(TrainingSet{{"Outlook": "sunny"}}); err == nil {
+		t.Error("expected error for an item without a label")
+	}
+}
+
+func TestEvaluateCI(t *testing.T) {
+	model, err := Train(diagonalSet(200, 1), Config{CategoryAttr: "label", MaxDepth: 3})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	test := diagonalSet(100, 2)
+	acc, ci, err := model.EvaluateCI(test, 500, 7)
+	if err != nil {
+		t.Fatalf("EvaluateCI failed: %v", err)
+	}
+	if score, _ := model.Score(test); acc != score {
+		t.Errorf("point estimate %v differs from Score %v", acc, score)
+	}
+	if !(ci[0] <= acc && acc <= ci[1]) || ci[0] == ci[1] {
+		t.Errorf("interval %v should contain %v and have width", ci, acc)
+	}
+	if ci[1]-ci[0] > 0.3 {
+		t.Errorf("interval %v is implausibly wide for 100 items", ci)
+	}
+	if acc2, ci2, _ := model.EvaluateCI(test, 500, 7); acc2 != acc || ci2 != ci {
+		t.Error("EvaluateCI is not reproducible for a fixed seed")
+	}
+
+	if _, _, err := model.EvaluateCI(test, 0, 1); err == nil {
+		t.Error("expected error for zero bootstraps")
+	}
+	if _, _, err := model.EvaluateCI(nil, 100, 1); err == nil {
+		t.Error("expected error for an empty set")
+	}
+	if _, _, err := model.EvaluateCI(TrainingSet{{"x": 0.5}}, 100, 1); err == nil {
+		t.Error("expected error for an item without a label")
+	}
+}
+
+func TestEvalReport_Metrics(t *testing.T) {
+	actual := []string{"a", "a", "a", "b", "b", "c"}
+	predicted := []string{"a", "a", "b", "b", "c", "c"}
+	r := newEvalReport(actual, predicted)
+	if math.Abs(r.Accuracy-4.0/6) > 1e-12 {
+		t.Fatalf("accuracy = %v", r.Accuracy)
+	}
+	a := r.PerClass["a"]
+	if a.Precision != 1 || math.Abs(a.Recall-2.0/3) > 1e-12 || a.Support != 3 {
+		t.Fatalf("unexpected metrics for a: %+v", a)
+	}
+	if c := r.PerClass["c"]; c.Precision != 0.5 || c.Recall != 1 {
+		t.Fatalf("unexpected metrics for c: %+v", c)
+	}
+}
+
+func TestEvalReport_String(t *testing.T) {
+	r := newEvalReport(
+		[]string{"no", "no", "yes", "yes", "yes"},
+		[]string{"no", "yes", "yes", "yes", "yes"},
+	)
+	out := r.String()
+	lines := strings.Split(out, "\n")
+	if !strings.Contains(lines[0], "precision") || !strings.Contains(lines[0], "f1-score") {
+		t.Fatalf("missing header:\n%s", out)
+	}
+	want := map[string][]string{
+		"no":           {"1.00", "0.50", "0.67", "2"},
+		"yes":          {"0.75", "1.00", "0.86", "3"},
+		"accuracy":     {"0.80", "5"},
+		"macro avg":    {"0.88", "0.75", "0.76", "5"},
+		"weighted avg": {"0.85", "0.80", "0.78", "5"},
+	}
+	for name, fields := range want {
+		found := false
+		for _, line := range lines {
+			if strings.HasPrefix(strings.TrimSpace(line), name+" ") {
+				if got := strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), name)); strings.Join(got, " ") != strings.Join(fields, " ") {
+					t.Errorf("row %q = %v, want %v", name, got, fields)
+				}
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("missing row %q in:\n%s", name, out)
+		}
+	}
+}
+
+func TestEvalReport_ToHTML(t *testing.T) {
+	actual := []string{"cat", "cat", "cat", "dog", "dog", "<fox>"}
+	predicted := []string{"cat", "cat", "dog", "dog", "cat", "<fox>"}
+	r := newEvalReport(actual, predicted)
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := r.ToHTML(path); err != nil {
+		t.Fatalf("ToHTML failed: %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(raw)
+	for _, c := range r.Classes {
+		if !strings.Contains(page, ">"+html.EscapeString(c)+"</th>") {
+			t.Errorf("report is missing class label %q", c)
+		}
+	}
+	if strings.Contains(page, "<fox>") {
+		t.Error("class labels must be HTML-escaped")
+	}
+	if !strings.Contains(page, "on "+strconv.Itoa(len(actual))+" samples") {
+		t.Error("report is missing the total sample count")
+	}
+	if !strings.Contains(page, "rgba(52, 152, 219, 1.00)") || !strings.Contains(page, "rgba(52, 152, 219, 0.00)") {
+		t.Error("cells should be shaded in proportion to their counts")
+	}
+	if !strings.Contains(page, "macro avg") {
+		t.Error("report is missing the averaged scores")
+	}
+}
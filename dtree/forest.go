@@ -0,0 +1,248 @@
+package dtree
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Vote weightings for ForestConfig.VoteWeighting and Forest.VoteWeighting.
+const (
+	// VoteUniform gives every tree one vote.
+	VoteUniform = "uniform"
+	// VoteAccuracy scales each tree's vote by its accuracy in Accuracies.
+	VoteAccuracy = "accuracy"
+)
+
+// Forest is an ensemble of trees that share a label attribute. Predict takes
+// a majority vote and PredictProba averages the trees' probabilities, both
+// weighted per VoteWeighting.
+type Forest struct {
+	Trees []*Model `json:"trees"`
+	// Accuracies holds each tree's accuracy on its out-of-bag items or a
+	// validation set, as recorded by TrainForest or ScoreTrees.
+	Accuracies []float64 `json:"accuracies,omitempty"`
+	// VoteWeighting is VoteUniform (default) or VoteAccuracy, which needs
+	// Accuracies.
+	VoteWeighting string `json:"voteWeighting,omitempty"`
+}
+
+// ForestConfig configures TrainForest.
+type ForestConfig struct {
+	// Tree configures every tree. Its Seed seeds the bootstrap samples; tree
+	// i trains with Seed+i+1 so randomized options differ between trees.
+	Tree Config `json:"tree"`
+	// NumTrees is the number of trees to train.
+	NumTrees int `json:"numTrees"`
+	// VoteWeighting is VoteUniform (default) or VoteAccuracy.
+	VoteWeighting string `json:"voteWeighting,omitempty"`
+	// Validation, if set, is what tree accuracies are measured on instead of
+	// each tree's out-of-bag items.
+	Validation TrainingSet `json:"-"`
+	// StratifiedBootstrap draws each tree's sample within each class of
+	// Tree.CategoryAttr, as many items per class as set holds, so every
+	// sample keeps the class proportions and no tree misses a rare class.
+	StratifiedBootstrap bool `json:"stratifiedBootstrap,omitempty"`
+}
+
+// TrainForest trains cfg.NumTrees trees, each on a bootstrap sample of set
+// (len(set) items drawn with replacement, within each class if
+// cfg.StratifiedBootstrap is set), and records every tree's
+// accuracy on the items its sample left out, or on cfg.Validation. A tree
+// whose sample left nothing out is scored on set. The result is
+// reproducible for a given cfg.Tree.Seed.
+func TrainForest(set TrainingSet, cfg ForestConfig) (*Forest, error) {
+	if len(set) == 0 {
+		return nil, errors.New("training set cannot be empty")
+	}
+	if cfg.NumTrees <= 0 {
+		return nil, errors.New("config.NumTrees must be positive")
+	}
+	if !validVoteWeighting(cfg.VoteWeighting) {
+		return nil, errors.New("config.VoteWeighting must be one of uniform, accuracy")
+	}
+	if err := cfg.Tree.Validate(); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(cfg.Tree.Seed))
+	strata := [][]int{make([]int, len(set))}
+	for i := range strata[0] {
+		strata[0][i] = i
+	}
+	if cfg.StratifiedBootstrap {
+		strata = classStrata(set, cfg.Tree.CategoryAttr)
+	}
+	f := &Forest{VoteWeighting: cfg.VoteWeighting}
+	for t := 0; t < cfg.NumTrees; t++ {
+		sample := make(TrainingSet, 0, len(set))
+		drawn := make([]bool, len(set))
+		for _, stratum := range strata {
+			for range stratum {
+				j := stratum[rng.Intn(len(stratum))]
+				sample = append(sample, set[j])
+				drawn[j] = true
+			}
+		}
+		treeCfg := cfg.Tree
+		treeCfg.Seed = cfg.Tree.Seed + int64(t) + 1
+		tree, err := Train(sample, treeCfg)
+		if err != nil {
+			return nil, fmt.Errorf("tree %d: %w", t, err)
+		}
+		scoreSet := cfg.Validation
+		if scoreSet == nil {
+			for j, in := range drawn {
+				if !in {
+					scoreSet = append(scoreSet, set[j])
+				}
+			}
+			if scoreSet == nil {
+				scoreSet = set
+			}
+		}
+		acc, err := tree.Score(scoreSet)
+		if err != nil {
+			return nil, fmt.Errorf("tree %d: %w", t, err)
+		}
+		f.Trees = append(f.Trees, tree)
+		f.Accuracies = append(f.Accuracies, acc)
+	}
+	return f, nil
+}
+
+// classStrata groups the indices of set by their label value, in sorted
+// class order so a seed always draws the same samples.
+func classStrata(set TrainingSet, label string) [][]int {
+	groups := make(map[string][]int)
+	for i, item := range set {
+		key := valueKey(item[label])
+		groups[key] = append(groups[key], i)
+	}
+	classes := make([]string, 0, len(groups))
+	for c := range groups {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+	strata := make([][]int, len(classes))
+	for i, c := range classes {
+		strata[i] = groups[c]
+	}
+	return strata
+}
+
+// ScoreTrees sets Accuracies to each tree's accuracy on validation, so
+// VoteAccuracy can be used with ensembles built by NewVotingEnsemble.
+func (f *Forest) ScoreTrees(validation TrainingSet) error {
+	if f == nil || len(f.Trees) == 0 {
+		return errors.New("forest has no trees")
+	}
+	accs := make([]float64, len(f.Trees))
+	for i, tree := range f.Trees {
+		acc, err := tree.Score(validation)
+		if err != nil {
+			return fmt.Errorf("tree %d: %w", i, err)
+		}
+		accs[i] = acc
+	}
+	f.Accuracies = accs
+	return nil
+}
+
+// validVoteWeighting reports whether s is a known vote weighting. The empty
+// string is accepted and means VoteUniform.
+func validVoteWeighting(s string) bool {
+	switch s {
+	case "", VoteUniform, VoteAccuracy:
+		return true
+	}
+	return false
+}
+
+// voteWeights returns the weight of each tree's vote.
+func (f *Forest) voteWeights() ([]float64, error) {
+	if f == nil || len(f.Trees) == 0 {
+		return nil, errors.New("forest has no trees")
+	}
+	weights := make([]float64, len(f.Trees))
+	switch f.VoteWeighting {
+	case "", VoteUniform:
+		for i := range weights {
+			weights[i] = 1
+		}
+	case VoteAccuracy:
+		if len(f.Accuracies) != len(f.Trees) {
+			return nil, errors.New("accuracy voting needs one accuracy per tree; see ScoreTrees")
+		}
+		copy(weights, f.Accuracies)
+	default:
+		return nil, fmt.Errorf("unknown vote weighting %q", f.VoteWeighting)
+	}
+	return weights, nil
+}
+
+// NewVotingEnsemble combines already trained models, for example trees
+// trained on different shards of a data set. Every model must be valid and
+// predict the same CategoryAttr. An ensemble of one model predicts exactly
+// like that model.
+func NewVotingEnsemble(models ...*Model) (*Forest, error) {
+	if len(models) == 0 {
+		return nil, errors.New("ensemble needs at least one model")
+	}
+	for i, m := range models {
+		if err := m.Validate(); err != nil {
+			return nil, fmt.Errorf("model %d: %w", i, err)
+		}
+		if m.Config.CategoryAttr != models[0].Config.CategoryAttr {
+			return nil, fmt.Errorf("model %d predicts %q, want %q", i, m.Config.CategoryAttr, models[0].Config.CategoryAttr)
+		}
+	}
+	return &Forest{Trees: append([]*Model(nil), models...)}, nil
+}
+
+// Predict returns the class with the most votes, each tree voting with its
+// VoteWeighting weight. Ties go to the lexicographically smallest class.
+func (f *Forest) Predict(item TrainingItem) (string, error) {
+	weights, err := f.voteWeights()
+	if err != nil {
+		return "", err
+	}
+	votes := make(map[string]float64)
+	for i, tree := range f.Trees {
+		pred, err := tree.Predict(item)
+		if err != nil {
+			return "", err
+		}
+		votes[pred] += weights[i]
+	}
+	return heaviestClass(votes), nil
+}
+
+// PredictProba averages the trees' class probabilities, weighted per
+// VoteWeighting. A class missing from a tree's output counts as probability
+// 0 for that tree.
+func (f *Forest) PredictProba(item TrainingItem) (map[string]float64, error) {
+	weights, err := f.voteWeights()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]float64)
+	total := 0.0
+	for i, tree := range f.Trees {
+		proba, err := tree.PredictProba(item)
+		if err != nil {
+			return nil, err
+		}
+		for c, p := range proba {
+			out[c] += p * weights[i]
+		}
+		total += weights[i]
+	}
+	if total == 0 {
+		return nil, errors.New("forest vote weights sum to zero")
+	}
+	for c := range out {
+		out[c] /= total
+	}
+	return out, nil
+}
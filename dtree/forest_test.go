@@ -0,0 +1,208 @@
+package dtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewVotingEnsemble_Shards(t *testing.T) {
+	set := syntheticSet(600)
+	shards := []TrainingSet{set[0:300], set[150:450], set[300:600]}
+	var models []*Model
+	for _, shard := range shards {
+		m, err := Train(shard, Config{CategoryAttr: "label", MaxDepth: 4})
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		models = append(models, m)
+	}
+	ensemble, err := NewVotingEnsemble(models...)
+	if err != nil {
+		t.Fatalf("ensemble failed: %v", err)
+	}
+
+	for _, item := range syntheticSet(200) {
+		votes := make(map[string]int)
+		var avg float64
+		for _, m := range models {
+			pred, _ := m.Predict(item)
+			votes[pred]++
+			proba, _ := m.PredictProba(item)
+			avg += proba["a"] / 3
+		}
+		pred, err := ensemble.Predict(item)
+		if err != nil {
+			t.Fatalf("predict failed: %v", err)
+		}
+		if votes[pred] < 2 && len(votes) < 3 {
+			t.Fatalf("ensemble predicted %q against the majority %v", pred, votes)
+		}
+		proba, err := ensemble.PredictProba(item)
+		if err != nil {
+			t.Fatalf("predict proba failed: %v", err)
+		}
+		if math.Abs(proba["a"]-avg) > 1e-12 {
+			t.Fatalf("expected averaged probability %v, got %v", avg, proba["a"])
+		}
+	}
+}
+
+func TestNewVotingEnsemble_SingleModelIdentity(t *testing.T) {
+	model, _ := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	ensemble, err := NewVotingEnsemble(model)
+	if err != nil {
+		t.Fatalf("ensemble failed: %v", err)
+	}
+	for _, item := range playTennisSet() {
+		want, _ := model.Predict(item)
+		got, _ := ensemble.Predict(item)
+		wantP, _ := model.PredictProba(item)
+		gotP, _ := ensemble.PredictProba(item)
+		if got != want || len(gotP) != len(wantP) {
+			t.Fatalf("single-model ensemble differs: %q vs %q", got, want)
+		}
+		for c, p := range wantP {
+			if gotP[c] != p {
+				t.Fatalf("probability for %q: %v vs %v", c, gotP[c], p)
+			}
+		}
+	}
+}
+
+func TestNewVotingEnsemble_Errors(t *testing.T) {
+	if _, err := NewVotingEnsemble(); err == nil {
+		t.Error("expected error for no models")
+	}
+	a, _ := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	b, _ := Train(syntheticSet(50), Config{CategoryAttr: "label"})
+	if _, err := NewVotingEnsemble(a, b); err == nil {
+		t.Error("expected error for mismatched CategoryAttr")
+	}
+	if _, err := NewVotingEnsemble(a, &Model{}); err == nil {
+		t.Error("expected error for invalid submodel")
+	}
+}
+
+func TestTrainForest(t *testing.T) {
+	set := diagonalSet(300, 2)
+	cfg := ForestConfig{Tree: Config{CategoryAttr: "label", MaxDepth: 4, Seed: 3}, NumTrees: 5, VoteWeighting: VoteAccuracy}
+	f, err := TrainForest(set, cfg)
+	if err != nil {
+		t.Fatalf("TrainForest failed: %v", err)
+	}
+	if len(f.Trees) != 5 || len(f.Accuracies) != 5 {
+		t.Fatalf("got %d trees and %d accuracies, want 5 each", len(f.Trees), len(f.Accuracies))
+	}
+	for i, acc := range f.Accuracies {
+		if acc <= 0.5 || acc > 1 {
+			t.Errorf("tree %d: out-of-bag accuracy %v out of range", i, acc)
+		}
+	}
+	again, _ := TrainForest(set, cfg)
+	for i := range f.Trees {
+		if f.Trees[i].ToText() != again.Trees[i].ToText() || f.Accuracies[i] != again.Accuracies[i] {
+			t.Fatal("same seed should produce the same forest")
+		}
+	}
+
+	for _, bad := range []ForestConfig{
+		{Tree: cfg.Tree},
+		{Tree: cfg.Tree, NumTrees: 2, VoteWeighting: "oob"},
+		{Tree: Config{}, NumTrees: 2},
+	} {
+		if _, err := TrainForest(set, bad); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestTrainForest_StratifiedBootstrap(t *testing.T) {
+	// Two "rare" items among 50: a plain bootstrap misses both about one
+	// time in eight.
+	set := diagonalSet(48, 4)
+	set = append(set,
+		TrainingItem{"x": 0.5, "y": 0.5, "label": "rare"},
+		TrainingItem{"x": 0.6, "y": 0.4, "label": "rare"},
+	)
+	missing := func(f *Forest) int {
+		n := 0
+		for _, tree := range f.Trees {
+			if !stringInSlice("rare", tree.Metadata.Classes) {
+				n++
+			}
+		}
+		return n
+	}
+	cfg := ForestConfig{Tree: Config{CategoryAttr: "label", MaxDepth: 3, Seed: 1}, NumTrees: 40}
+	plain, err := TrainForest(set, cfg)
+	if err != nil {
+		t.Fatalf("TrainForest failed: %v", err)
+	}
+	if missing(plain) == 0 {
+		t.Fatal("every plain bootstrap kept the rare class; the test proves nothing")
+	}
+
+	cfg.StratifiedBootstrap = true
+	strat, err := TrainForest(set, cfg)
+	if err != nil {
+		t.Fatalf("TrainForest failed: %v", err)
+	}
+	if n := missing(strat); n != 0 {
+		t.Fatalf("%d stratified samples left out the rare class", n)
+	}
+}
+
+func TestForest_AccuracyVoting(t *testing.T) {
+	train, validation, test := diagonalSet(400, 4), diagonalSet(200, 5), diagonalSet(400, 6)
+	var models []*Model
+	for i := 0; i < 3; i++ {
+		m, err := Train(train[i*100:i*100+200], Config{CategoryAttr: "label", MaxDepth: 4})
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		models = append(models, m)
+	}
+	// Deliberately bad trees: trained on flipped labels.
+	flipped := make(TrainingSet, len(train))
+	for i, item := range train {
+		label := "pos"
+		if item["label"] == "pos" {
+			label = "neg"
+		}
+		flipped[i] = TrainingItem{"x": item["x"], "y": item["y"], "label": label}
+	}
+	for i := 0; i < 4; i++ {
+		m, err := Train(flipped[i*50:i*50+200], Config{CategoryAttr: "label", MaxDepth: 4})
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		models = append(models, m)
+	}
+
+	f, err := NewVotingEnsemble(models...)
+	if err != nil {
+		t.Fatalf("ensemble failed: %v", err)
+	}
+	f.VoteWeighting = VoteAccuracy
+	if _, err := f.Predict(test[0]); err == nil {
+		t.Fatal("expected error for accuracy voting without accuracies")
+	}
+	if err := f.ScoreTrees(validation); err != nil {
+		t.Fatalf("ScoreTrees failed: %v", err)
+	}
+
+	accuracy := func(weighting string) float64 {
+		f.VoteWeighting = weighting
+		correct := 0
+		for _, item := range test {
+			if pred, _ := f.Predict(item); pred == item["label"] {
+				correct++
+			}
+		}
+		return float64(correct) / float64(len(test))
+	}
+	uniform, weighted := accuracy(VoteUniform), accuracy(VoteAccuracy)
+	if weighted <= uniform || weighted < 0.8 {
+		t.Fatalf("accuracy voting %.3f should beat uniform voting %.3f", weighted, uniform)
+	}
+}
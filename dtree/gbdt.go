@@ -0,0 +1,482 @@
+package dtree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// GBDT objectives.
+const (
+	// ObjectiveRegression fits a numeric label with squared error.
+	ObjectiveRegression = "regression"
+	// ObjectiveBinary fits a two-class label with log loss; the positive class
+	// is the lexicographically larger one.
+	ObjectiveBinary = "binary"
+)
+
+// GBDTConfig configures TrainGBDT.
+type GBDTConfig struct {
+	// Label is the attribute to predict.
+	Label     string `json:"label"`
+	Objective string `json:"objective"`
+	// NumRounds is the number of trees to fit.
+	NumRounds int `json:"numRounds"`
+	// LearningRate shrinks each tree's contribution (default 0.1).
+	LearningRate float64 `json:"learningRate"`
+	// MaxDepth limits each regression tree (default 3).
+	MaxDepth       int `json:"maxDepth"`
+	MinSamplesLeaf int `json:"minSamplesLeaf,omitempty"`
+	// Validation, if set, is scored after every round. With
+	// EarlyStoppingRounds > 0, training stops once the validation loss has
+	// not improved for that many rounds and keeps the best rounds only.
+	Validation          TrainingSet `json:"-"`
+	EarlyStoppingRounds int         `json:"earlyStoppingRounds,omitempty"`
+}
+
+// RegressionNode is a node of a GBDT regression tree. Leaves carry Value;
+// internal nodes send items matching the predicate to Match.
+type RegressionNode struct {
+	Value         float64         `json:"value"`
+	Attribute     string          `json:"attribute,omitempty"`
+	PredicateName string          `json:"predicateName,omitempty"`
+	Pivot         interface{}     `json:"pivot,omitempty"`
+	Match         *RegressionNode `json:"match,omitempty"`
+	NoMatch       *RegressionNode `json:"noMatch,omitempty"`
+}
+
+// GBDTModel is a gradient-boosted ensemble of regression trees.
+type GBDTModel struct {
+	Config GBDTConfig `json:"config"`
+	// Base is the initial prediction (mean, or log-odds for binary).
+	Base  float64           `json:"base"`
+	Trees []*RegressionNode `json:"trees"`
+	// Classes holds the negative and positive class for ObjectiveBinary.
+	Classes []string `json:"classes,omitempty"`
+	// TrainLoss and ValidLoss record the loss after each kept round.
+	TrainLoss []float64 `json:"trainLoss,omitempty"`
+	ValidLoss []float64 `json:"validLoss,omitempty"`
+}
+
+// TrainGBDT fits cfg.NumRounds shallow regression trees, each to the
+// gradient of the loss (the pseudo-residuals) of the ensemble so far.
+func TrainGBDT(set TrainingSet, cfg GBDTConfig) (*GBDTModel, error) {
+	if len(set) == 0 {
+		return nil, errors.New("training set cannot be empty")
+	}
+	if cfg.Label == "" {
+		return nil, errors.New("config.Label is required")
+	}
+	if cfg.Objective == "" {
+		cfg.Objective = ObjectiveRegression
+	}
+	if cfg.Objective != ObjectiveRegression && cfg.Objective != ObjectiveBinary {
+		return nil, fmt.Errorf("unknown objective %q (want regression or binary)", cfg.Objective)
+	}
+	if cfg.NumRounds <= 0 {
+		return nil, errors.New("config.NumRounds must be positive")
+	}
+	if cfg.LearningRate == 0 {
+		cfg.LearningRate = 0.1
+	}
+	if cfg.LearningRate < 0 || math.IsNaN(cfg.LearningRate) {
+		return nil, errors.New("config.LearningRate cannot be negative")
+	}
+	if cfg.MaxDepth == 0 {
+		cfg.MaxDepth = 3
+	}
+	if cfg.MaxDepth < 0 || cfg.MinSamplesLeaf < 0 || cfg.EarlyStoppingRounds < 0 {
+		return nil, errors.New("config.MaxDepth, MinSamplesLeaf and EarlyStoppingRounds cannot be negative")
+	}
+
+	g := &GBDTModel{Config: cfg}
+	if cfg.Objective == ObjectiveBinary {
+		classes := make([]string, 0, 2)
+		for c := range counterUniqueValues(set, cfg.Label) {
+			classes = append(classes, c)
+		}
+		if len(classes) != 2 {
+			return nil, fmt.Errorf("binary objective needs exactly 2 classes, found %d", len(classes))
+		}
+		sort.Strings(classes)
+		g.Classes = classes
+	}
+	y, err := g.targets(set)
+	if err != nil {
+		return nil, err
+	}
+	var validY []float64
+	if len(cfg.Validation) > 0 {
+		if validY, err = g.targets(cfg.Validation); err != nil {
+			return nil, fmt.Errorf("validation: %w", err)
+		}
+	}
+
+	mean := 0.0
+	for _, v := range y {
+		mean += v
+	}
+	mean /= float64(len(y))
+	g.Base = mean
+	if cfg.Objective == ObjectiveBinary {
+		p := math.Min(math.Max(mean, 1e-6), 1-1e-6)
+		g.Base = math.Log(p / (1 - p))
+	}
+
+	raw := make([]float64, len(set))
+	for i := range raw {
+		raw[i] = g.Base
+	}
+	validRaw := make([]float64, len(validY))
+	for i := range validRaw {
+		validRaw[i] = g.Base
+	}
+
+	grad := make([]float64, len(set))
+	hess := make([]float64, len(set))
+	idx := make([]int, len(set))
+	bestRound, bestLoss, sinceBest := 0, math.Inf(1), 0
+	for r := 0; r < cfg.NumRounds; r++ {
+		for i := range set {
+			grad[i], hess[i] = g.gradient(y[i], raw[i])
+			idx[i] = i
+		}
+		tree := fitRegressionTree(set, idx, grad, hess, cfg, 0)
+		scaleLeaves(tree, cfg.LearningRate)
+		g.Trees = append(g.Trees, tree)
+		for i, item := range set {
+			raw[i] += tree.predict(item)
+		}
+		g.TrainLoss = append(g.TrainLoss, g.loss(y, raw))
+
+		if len(validY) == 0 {
+			continue
+		}
+		for i, item := range cfg.Validation {
+			validRaw[i] += tree.predict(item)
+		}
+		vl := g.loss(validY, validRaw)
+		g.ValidLoss = append(g.ValidLoss, vl)
+		if vl < bestLoss {
+			bestRound, bestLoss, sinceBest = r+1, vl, 0
+		} else if sinceBest++; cfg.EarlyStoppingRounds > 0 && sinceBest >= cfg.EarlyStoppingRounds {
+			break
+		}
+	}
+	if cfg.EarlyStoppingRounds > 0 && len(validY) > 0 {
+		g.Trees = g.Trees[:bestRound]
+		g.TrainLoss = g.TrainLoss[:bestRound]
+		g.ValidLoss = g.ValidLoss[:bestRound]
+	}
+	return g, nil
+}
+
+// targets converts the label of each item to the value being fitted: the
+// number itself for regression, 1 or 0 for the positive/negative class.
+func (g *GBDTModel) targets(set TrainingSet) ([]float64, error) {
+	y := make([]float64, len(set))
+	for i, item := range set {
+		v, ok := item[g.Config.Label]
+		if !ok || v == nil {
+			return nil, fmt.Errorf("item %d has no %q label", i, g.Config.Label)
+		}
+		if g.Config.Objective == ObjectiveRegression {
+			if !finiteNumber(v) {
+				return nil, fmt.Errorf("item %d has non-numeric or non-finite label %v", i, v)
+			}
+			y[i] = toFloat(v)
+			continue
+		}
+		switch valueKey(v) {
+		case g.Classes[1]:
+			y[i] = 1
+		case g.Classes[0]:
+		default:
+			return nil, fmt.Errorf("item %d has unknown class %v", i, v)
+		}
+	}
+	return y, nil
+}
+
+// gradient returns the negative gradient and the Hessian of the loss at raw.
+func (g *GBDTModel) gradient(y, raw float64) (float64, float64) {
+	if g.Config.Objective == ObjectiveBinary {
+		p := sigmoid(raw)
+		return y - p, math.Max(p*(1-p), 1e-6)
+	}
+	return y - raw, 1
+}
+
+// loss is the mean squared error, or the mean log loss for binary.
+func (g *GBDTModel) loss(y, raw []float64) float64 {
+	total := 0.0
+	for i := range y {
+		if g.Config.Objective == ObjectiveBinary {
+			p := math.Min(math.Max(sigmoid(raw[i]), 1e-15), 1-1e-15)
+			total -= y[i]*math.Log(p) + (1-y[i])*math.Log(1-p)
+		} else {
+			d := y[i] - raw[i]
+			total += d * d
+		}
+	}
+	return total / float64(len(y))
+}
+
+// fitRegressionTree grows a tree on the items in idx whose leaves hold the
+// Newton step sum(grad)/sum(hess). Splits maximize G_l²/H_l + G_r²/H_r.
+func fitRegressionTree(set TrainingSet, idx []int, grad, hess []float64, cfg GBDTConfig, depth int) *RegressionNode {
+	var G, H float64
+	for _, i := range idx {
+		G += grad[i]
+		H += hess[i]
+	}
+	leaf := &RegressionNode{Value: G / H}
+	if depth >= cfg.MaxDepth || len(idx) < 2 {
+		return leaf
+	}
+
+	minLeaf := cfg.MinSamplesLeaf
+	if minLeaf < 1 {
+		minLeaf = 1
+	}
+	parentScore := G * G / H
+	bestGain := 1e-12
+	var best *RegressionNode
+
+	for _, attr := range regressionAttributes(set, idx, cfg.Label) {
+		// Numeric: sweep ascending values; items >= pivot go to Match.
+		var numeric []int
+		categorical := make(map[string][2]float64)
+		catCount := make(map[string]int)
+		for _, i := range idx {
+			v, ok := set[i][attr]
+			switch {
+			case ok && finiteNumber(v):
+				numeric = append(numeric, i)
+			case ok && v != nil && !nonFinite(v):
+				k := valueKey(v)
+				gh := categorical[k]
+				categorical[k] = [2]float64{gh[0] + grad[i], gh[1] + hess[i]}
+				catCount[k]++
+			}
+		}
+		if len(numeric) > 0 {
+			sort.SliceStable(numeric, func(a, b int) bool {
+				return toFloat(set[numeric[a]][attr]) < toFloat(set[numeric[b]][attr])
+			})
+			// Items without a numeric value never match a ">=" split.
+			var gm, hm float64
+			for _, i := range numeric {
+				gm += grad[i]
+				hm += hess[i]
+			}
+			for k, i := range numeric {
+				if k > 0 {
+					prev := toFloat(set[numeric[k-1]][attr])
+					pivot := toFloat(set[i][attr])
+					matchN, noMatchN := len(numeric)-k, len(idx)-(len(numeric)-k)
+					if pivot != prev && matchN >= minLeaf && noMatchN >= minLeaf {
+						gain := gm*gm/hm + (G-gm)*(G-gm)/(H-hm) - parentScore
+						if gain > bestGain {
+							bestGain = gain
+							best = &RegressionNode{Attribute: attr, PredicateName: ">=", Pivot: pivot}
+						}
+					}
+				}
+				gm -= grad[i]
+				hm -= hess[i]
+			}
+		}
+		keys := make([]string, 0, len(categorical))
+		for k := range categorical {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			gh := categorical[k]
+			if catCount[k] < minLeaf || len(idx)-catCount[k] < minLeaf {
+				continue
+			}
+			gain := gh[0]*gh[0]/gh[1] + (G-gh[0])*(G-gh[0])/(H-gh[1]) - parentScore
+			if gain > bestGain {
+				bestGain = gain
+				best = &RegressionNode{Attribute: attr, PredicateName: "==", Pivot: k}
+			}
+		}
+	}
+	if best == nil {
+		return leaf
+	}
+	var match, noMatch []int
+	for _, i := range idx {
+		if best.matches(set[i]) {
+			match = append(match, i)
+		} else {
+			noMatch = append(noMatch, i)
+		}
+	}
+	best.Value = leaf.Value
+	best.Match = fitRegressionTree(set, match, grad, hess, cfg, depth+1)
+	best.NoMatch = fitRegressionTree(set, noMatch, grad, hess, cfg, depth+1)
+	return best
+}
+
+// regressionAttributes returns the sorted feature attributes seen in idx.
+func regressionAttributes(set TrainingSet, idx []int, label string) []string {
+	seen := make(map[string]bool)
+	for _, i := range idx {
+		for attr := range set[i] {
+			if attr != label {
+				seen[attr] = true
+			}
+		}
+	}
+	return sortedKeys(seen)
+}
+
+func scaleLeaves(n *RegressionNode, rate float64) {
+	if n == nil {
+		return
+	}
+	n.Value *= rate
+	scaleLeaves(n.Match, rate)
+	scaleLeaves(n.NoMatch, rate)
+}
+
+func (n *RegressionNode) matches(item TrainingItem) bool {
+	v := item[n.Attribute]
+	if n.PredicateName == ">=" {
+		return finiteNumber(v) && toFloat(v) >= toFloat(n.Pivot)
+	}
+	return v != nil && !isNumeric(v) && valueKey(v) == n.Pivot
+}
+
+func (n *RegressionNode) predict(item TrainingItem) float64 {
+	for n.Match != nil && n.NoMatch != nil {
+		if n.matches(item) {
+			n = n.Match
+		} else {
+			n = n.NoMatch
+		}
+	}
+	return n.Value
+}
+
+// Raw returns the summed, shrunken tree outputs before any link function.
+func (g *GBDTModel) Raw(item TrainingItem) float64 {
+	raw := g.Base
+	for _, tree := range g.Trees {
+		raw += tree.predict(item)
+	}
+	return raw
+}
+
+// Predict returns the predicted value for regression, or the probability of
+// the positive class (Classes[1]) for binary classification.
+func (g *GBDTModel) Predict(item TrainingItem) (float64, error) {
+	if g == nil {
+		return 0, errors.New("model is nil")
+	}
+	if item == nil {
+		return 0, errors.New("item cannot be nil")
+	}
+	if g.Config.Objective == ObjectiveBinary {
+		return sigmoid(g.Raw(item)), nil
+	}
+	return g.Raw(item), nil
+}
+
+// PredictClass returns the more likely class of a binary model.
+func (g *GBDTModel) PredictClass(item TrainingItem) (string, error) {
+	if g == nil || g.Config.Objective != ObjectiveBinary {
+		return "", errors.New("PredictClass requires a binary model")
+	}
+	p, err := g.Predict(item)
+	if err != nil {
+		return "", err
+	}
+	if p >= 0.5 {
+		return g.Classes[1], nil
+	}
+	return g.Classes[0], nil
+}
+
+// SaveJSON writes the model to a JSON file, gzip-compressed when the path
+// ends in ".gz".
+func (g *GBDTModel) SaveJSON(path string) error {
+	return writeJSONFile(path, g)
+}
+
+// LoadGBDTJSON reads a GBDT model from a JSON file and validates it.
+func LoadGBDTJSON(path string) (*GBDTModel, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return DecodeGBDTJSON(f)
+}
+
+// DecodeGBDTJSON decodes a GBDT model from any reader and validates it.
+func DecodeGBDTJSON(r io.Reader) (*GBDTModel, error) {
+	r, err := MaybeGunzip(r)
+	if err != nil {
+		return nil, err
+	}
+	var g GBDTModel
+	if err := json.NewDecoder(r).Decode(&g); err != nil {
+		return nil, err
+	}
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// Validate checks that the model is usable for prediction.
+func (g *GBDTModel) Validate() error {
+	if g == nil {
+		return errors.New("model is nil")
+	}
+	switch g.Config.Objective {
+	case ObjectiveRegression:
+	case ObjectiveBinary:
+		if len(g.Classes) != 2 {
+			return errors.New("binary model must have 2 classes")
+		}
+	default:
+		return fmt.Errorf("unknown objective %q", g.Config.Objective)
+	}
+	for i, tree := range g.Trees {
+		if err := validateRegressionNode(tree); err != nil {
+			return fmt.Errorf("tree %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateRegressionNode(n *RegressionNode) error {
+	if n == nil {
+		return errors.New("nil node")
+	}
+	if n.Match == nil && n.NoMatch == nil {
+		return nil
+	}
+	if n.Match == nil || n.NoMatch == nil {
+		return errors.New("internal node missing one or both children")
+	}
+	if n.Attribute == "" {
+		return errors.New("internal node missing attribute")
+	}
+	if n.PredicateName != "==" && n.PredicateName != ">=" {
+		return errors.New("internal node has invalid predicateName (must be == or >=)")
+	}
+	if err := validateRegressionNode(n.Match); err != nil {
+		return err
+	}
+	return validateRegressionNode(n.NoMatch)
+}
@@ -0,0 +1,109 @@
+package dtree
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// waveSet samples y = sin(x) + x²/10 with a little noise.
+func waveSet(n int, seed int64) TrainingSet {
+	rng := rand.New(rand.NewSource(seed))
+	ts := make(TrainingSet, n)
+	for i := range ts {
+		x := rng.Float64()*6 - 3
+		ts[i] = TrainingItem{"x": x, "y": math.Sin(x) + x*x/10 + rng.NormFloat64()*0.05}
+	}
+	return ts
+}
+
+func TestTrainGBDT_RegressionLossDecreases(t *testing.T) {
+	set := waveSet(300, 1)
+	g, err := TrainGBDT(set, GBDTConfig{Label: "y", NumRounds: 40, LearningRate: 0.2, MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if len(g.Trees) != 40 || len(g.TrainLoss) != 40 {
+		t.Fatalf("expected 40 rounds, got %d trees, %d losses", len(g.Trees), len(g.TrainLoss))
+	}
+	for i := 1; i < len(g.TrainLoss); i++ {
+		if g.TrainLoss[i] > g.TrainLoss[i-1]+1e-12 {
+			t.Fatalf("training loss rose at round %d: %v -> %v", i, g.TrainLoss[i-1], g.TrainLoss[i])
+		}
+	}
+	if g.TrainLoss[len(g.TrainLoss)-1] > g.TrainLoss[0]/5 {
+		t.Fatalf("expected a large loss reduction, got %v -> %v", g.TrainLoss[0], g.TrainLoss[len(g.TrainLoss)-1])
+	}
+	pred, _ := g.Predict(TrainingItem{"x": 1.5})
+	if want := math.Sin(1.5) + 0.225; math.Abs(pred-want) > 0.2 {
+		t.Fatalf("predicted %v at x=1.5, want about %v", pred, want)
+	}
+}
+
+func TestTrainGBDT_Binary(t *testing.T) {
+	train, test := diagonalSet(300, 31), diagonalSet(300, 32)
+	g, err := TrainGBDT(train, GBDTConfig{Label: "label", Objective: ObjectiveBinary, NumRounds: 50, MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if g.Classes[1] != "pos" {
+		t.Fatalf("expected positive class pos, got %v", g.Classes)
+	}
+	p, _ := g.Predict(TrainingItem{"x": 0.9, "y": 0.9})
+	if p <= 0.5 || p >= 1 {
+		t.Fatalf("expected a high positive probability, got %v", p)
+	}
+	if acc := accuracy(t, g.PredictClass, test); acc < 0.85 {
+		t.Fatalf("accuracy %.3f too low", acc)
+	}
+}
+
+func TestTrainGBDT_EarlyStopping(t *testing.T) {
+	train := waveSet(200, 2)
+	// A validation target unrelated to x stops improving almost at once.
+	valid := waveSet(100, 3)
+	rng := rand.New(rand.NewSource(4))
+	for _, item := range valid {
+		item["y"] = rng.NormFloat64()
+	}
+	g, err := TrainGBDT(train, GBDTConfig{Label: "y", NumRounds: 200, Validation: valid, EarlyStoppingRounds: 5})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if len(g.Trees) >= 200 || len(g.ValidLoss) != len(g.Trees) {
+		t.Fatalf("expected early stop, kept %d trees", len(g.Trees))
+	}
+}
+
+func TestGBDTModel_SaveLoad(t *testing.T) {
+	set := waveSet(100, 5)
+	g, err := TrainGBDT(set, GBDTConfig{Label: "y", NumRounds: 10})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "gbdt.json.gz")
+	if err := g.SaveJSON(path); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	loaded, err := LoadGBDTJSON(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	for _, item := range set {
+		want, _ := g.Predict(item)
+		got, _ := loaded.Predict(item)
+		if math.Abs(got-want) > 1e-12 {
+			t.Fatalf("loaded model predicts %v, want %v", got, want)
+		}
+	}
+}
+
+func TestTrainGBDT_Errors(t *testing.T) {
+	if _, err := TrainGBDT(playTennisSet(), GBDTConfig{Label: "Play", NumRounds: 5}); err == nil {
+		t.Error("expected error for non-numeric regression label")
+	}
+	if _, err := TrainGBDT(syntheticSet(50), GBDTConfig{Label: "label", Objective: ObjectiveBinary, NumRounds: 5}); err == nil {
+		t.Error("expected error for multi-class binary objective")
+	}
+}
@@ -0,0 +1,118 @@
+package dtree
+
+import "sort"
+
+// histogramThresholds bins every numeric attribute of set once, for
+// Config.HistogramBins: the returned thresholds, ascending, are the bin
+// boundaries and the only pivots tried for that attribute. Ordinal and
+// monotone-constrained attributes are left to exact split search.
+func histogramThresholds(set TrainingSet, cfg Config) map[string][]float64 {
+	var attrs []string
+	b := &builder{cfg: cfg}
+	for _, attr := range numericAttributes(set, b.splittableAttributes(set, nil)) {
+		if _, ok := cfg.OrdinalFeatures[attr]; ok || cfg.MonotoneConstraints[attr] != 0 {
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+	out := make(map[string][]float64, len(attrs))
+	for attr, pivots := range quantilePivots(set, attrs, cfg.HistogramBins-1) {
+		th := make([]float64, 0, len(pivots))
+		for p := range pivots {
+			th = append(th, p)
+		}
+		sort.Float64s(th)
+		out[attr] = th
+	}
+	return out
+}
+
+// evalHistogram scores "attr >= t" for every bin boundary t in thresholds
+// with one pass over set, accumulating per-bin class weights and sweeping
+// them from the top bin down. Items without a numeric value always go to
+// NoMatch. With MonotoneConstraints, boundaries whose children fall outside
+// bnd are skipped, as in exact search. It reports false when no boundary
+// splits set usably.
+func (b *builder) evalHistogram(set TrainingSet, attr string, thresholds []float64, counts map[string]int, initImpurity, size float64, bnd bounds) (splitResult, bool) {
+	cfg := b.cfg
+	classes := make([]string, 0, len(counts))
+	for c := range counts {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+	classIdx := make(map[string]int, len(classes))
+	for i, c := range classes {
+		classIdx[c] = i
+	}
+
+	// Bin i holds values v with exactly i thresholds <= v.
+	nb, k := len(thresholds)+1, len(classes)
+	hist := make([]float64, nb*k)
+	binN := make([]int, nb)
+	noMatchW := make(map[string]float64, k)
+	noMatchT := 0.0
+	for _, item := range set {
+		w := b.weight(item)
+		class := valueKey(item[cfg.CategoryAttr])
+		ci := classIdx[class]
+		noMatchW[class] += w
+		noMatchT += w
+		if v := item[attr]; finiteNumber(v) {
+			f := toFloat(v)
+			bin := sort.Search(len(thresholds), func(i int) bool { return thresholds[i] > f })
+			hist[bin*k+ci] += w
+			binN[bin]++
+		}
+	}
+
+	// Only the best boundary is partitioned; under monotone constraints the
+	// next best ones are tried in turn until one fits bnd.
+	var cands []splitResult
+	matchW := make(map[string]float64, k)
+	matchT, nMatch := 0.0, 0
+	for j := len(thresholds) - 1; j >= 0; j-- {
+		// Threshold j matches bins j+1 and up.
+		bin := hist[(j+1)*k : (j+2)*k]
+		for c, w := range bin {
+			matchW[classes[c]] += w
+			noMatchW[classes[c]] -= w
+			matchT += w
+			noMatchT -= w
+		}
+		nMatch += binN[j+1]
+		if binN[j+1] == 0 {
+			continue // same partition as the boundary above
+		}
+		if !b.usablePartition(nMatch, len(set)) {
+			continue
+		}
+		if cfg.Observer != nil {
+			cfg.Observer.OnSplitEvaluated(attr)
+		}
+		newI := (b.weightedImpurity(matchW, matchT)*matchT + b.weightedImpurity(noMatchW, noMatchT)*noMatchT) / size
+		cands = append(cands, splitResult{Gain: initImpurity - newI, Attribute: attr, Pivot: thresholds[j], PredicateName: ">="})
+	}
+	// Stable, so equal gains keep preferring the higher boundary.
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].Gain > cands[j].Gain })
+	for _, best := range cands {
+		pivot := best.Pivot.(float64)
+		for _, item := range set {
+			if v := item[attr]; finiteNumber(v) && toFloat(v) >= pivot {
+				best.Match = append(best.Match, item)
+			} else {
+				best.NoMatch = append(best.NoMatch, item)
+			}
+		}
+		best.MatchCounts = counterUniqueValues(best.Match, cfg.CategoryAttr)
+		best.NoMatchCounts = counterUniqueValues(best.NoMatch, cfg.CategoryAttr)
+		if len(cfg.MonotoneConstraints) > 0 {
+			if _, _, ok := b.monotoneChildBounds(best, bnd); !ok {
+				continue
+			}
+		}
+		pred := Predicate(predicateGte)
+		best.Predicate = &pred
+		return best, true
+	}
+	return splitResult{}, false
+}
@@ -0,0 +1,92 @@
+package dtree
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHistogramBins_AccuracyCloseToExact(t *testing.T) {
+	train, test := diagonalSet(2000, 1), diagonalSet(1000, 2)
+	exact, err := Train(train, Config{CategoryAttr: "label", MaxDepth: 8})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	hist, err := Train(train, Config{CategoryAttr: "label", MaxDepth: 8, HistogramBins: 64})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	exactAcc, histAcc := accuracy(t, exact.Predict, test), accuracy(t, hist.Predict, test)
+	if histAcc < exactAcc-0.03 {
+		t.Errorf("histogram accuracy %.3f more than 0.03 below exact %.3f", histAcc, exactAcc)
+	}
+	if err := hist.Validate(); err != nil {
+		t.Fatalf("histogram-trained model is invalid: %v", err)
+	}
+}
+
+func TestHistogramBins_PivotsAreBinBoundaries(t *testing.T) {
+	set := diagonalSet(500, 3)
+	cfg := Config{CategoryAttr: "label", HistogramBins: 4}
+	bounds := histogramThresholds(set, cfg)
+	if len(bounds["x"]) == 0 || len(bounds["x"]) > 3 {
+		t.Fatalf("expected 1-3 boundaries for x, got %v", bounds["x"])
+	}
+	model, err := Train(set, cfg)
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	var check func(n *TreeItem)
+	check = func(n *TreeItem) {
+		if n == nil || n.isLeaf() {
+			return
+		}
+		ok := false
+		for _, th := range bounds[n.Attribute] {
+			ok = ok || th == n.Pivot
+		}
+		if !ok {
+			t.Errorf("pivot %v of %s is not a bin boundary %v", n.Pivot, n.Attribute, bounds[n.Attribute])
+		}
+		check(n.Match)
+		check(n.NoMatch)
+	}
+	check(model.Root)
+}
+
+func TestHistogramBins_MonotoneConstraints(t *testing.T) {
+	// x itself is searched exactly, but histogram splits on y must still
+	// respect the bounds x's splits put on their subtrees.
+	for seed := int64(0); seed < 5; seed++ {
+		cfg := Config{CategoryAttr: "label", HistogramBins: 8, MonotoneConstraints: map[string]int{"x": 1}}
+		model, err := Train(noisyMonotoneSet(seed, 300, "y"), cfg)
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		if n := monotoneViolations(t, model, "y"); n > 0 {
+			t.Errorf("seed %d: positive probability decreases in x %d times", seed, n)
+		}
+	}
+}
+
+func TestHistogramBins_Validate(t *testing.T) {
+	for _, bins := range []int{-1, 1} {
+		_, err := Train(diagonalSet(50, 1), Config{CategoryAttr: "label", HistogramBins: bins})
+		if err == nil {
+			t.Errorf("expected error for HistogramBins=%d", bins)
+		}
+	}
+}
+
+func BenchmarkTrain_HistogramBins(b *testing.B) {
+	set := diagonalSet(3000, 1)
+	for _, n := range []int{0, 256, 32} {
+		cfg := Config{CategoryAttr: "label", MaxDepth: 10, HistogramBins: n}
+		b.Run(fmt.Sprintf("bins=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := Train(set, cfg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
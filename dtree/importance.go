@@ -0,0 +1,226 @@
+package dtree
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// FeatureImportance returns the impurity-based importance of each feature
+// used by the tree: the total impurity decrease of the splits on it,
+// weighted by the number of samples reaching each split, normalized to sum
+// to 1. Impurity uses the model's Criterion. A single-leaf tree returns an
+// empty map.
+func (m *Model) FeatureImportance() map[string]float64 {
+	imp := make(map[string]float64)
+	if m == nil || m.Root == nil {
+		return imp
+	}
+	b := &builder{cfg: m.Config}
+	var walk func(n *TreeItem) map[string]int
+	walk = func(n *TreeItem) map[string]int {
+		if n.isLeaf() {
+			return n.ClassCounts
+		}
+		var childCounts []map[string]int
+		for _, br := range n.branches() {
+			childCounts = append(childCounts, walk(br.node))
+		}
+		counts := n.ClassCounts
+		if counts == nil {
+			// Older models only carry counts at the leaves.
+			counts = make(map[string]int)
+			for _, cc := range childCounts {
+				for c, k := range cc {
+					counts[c] += k
+				}
+			}
+		}
+		total := countTotal(counts)
+		decrease := float64(total) * b.impurity(counts, total)
+		for _, cc := range childCounts {
+			t := countTotal(cc)
+			decrease -= float64(t) * b.impurity(cc, t)
+		}
+		if decrease > 0 {
+			// Oblique splits credit their attributes equally.
+			attrs := n.attributes()
+			for _, a := range attrs {
+				imp[a] += decrease / float64(len(attrs))
+			}
+		}
+		return counts
+	}
+	walk(m.Root)
+
+	sum := 0.0
+	for _, v := range imp {
+		sum += v
+	}
+	for k := range imp {
+		imp[k] /= sum
+	}
+	return imp
+}
+
+func countTotal(counts map[string]int) int {
+	total := 0
+	for _, k := range counts {
+		total += k
+	}
+	return total
+}
+
+// TopFeatures returns up to n features in decreasing order of
+// FeatureImportance, breaking ties by name. If n exceeds the number of
+// features with positive importance, all of them are returned.
+func (m *Model) TopFeatures(n int) []string {
+	imp := m.FeatureImportance()
+	names := make([]string, 0, len(imp))
+	for k := range imp {
+		names = append(names, k)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if imp[names[i]] != imp[names[j]] {
+			return imp[names[i]] > imp[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if n < 0 {
+		n = 0
+	}
+	if n < len(names) {
+		names = names[:n]
+	}
+	return names
+}
+
+// SelectFeatures returns a copy of set in which every item keeps only the
+// attributes in keep plus label. Attributes missing from an item stay
+// missing; set itself is not modified.
+func SelectFeatures(set TrainingSet, keep []string, label string) TrainingSet {
+	out := make(TrainingSet, len(set))
+	for i, item := range set {
+		cp := make(TrainingItem, len(keep)+1)
+		for _, k := range keep {
+			if v, ok := item[k]; ok {
+				cp[k] = v
+			}
+		}
+		if v, ok := item[label]; ok {
+			cp[label] = v
+		}
+		out[i] = cp
+	}
+	return out
+}
+
+// PermutationImportance measures how much the model relies on each feature
+// it splits on: the drop in accuracy on set when that feature's values are
+// shuffled across items, averaged over repeats shuffles. Features the tree
+// never splits on are omitted, as shuffling them cannot change a prediction.
+// Values can be negative when shuffling happens to help. The shuffles are
+// reproducible for a given seed. Every item in set must carry a label.
+func (m *Model) PermutationImportance(set TrainingSet, repeats int, seed int64) (map[string]float64, error) {
+	if m == nil || m.Root == nil {
+		return nil, errors.New("model is nil")
+	}
+	if repeats <= 0 {
+		return nil, errors.New("repeats must be positive")
+	}
+	base, err := m.Evaluate(set)
+	if err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(seed))
+	shuffled := make(TrainingSet, len(set))
+	imp := make(map[string]float64)
+	for _, attr := range treeAttributes(m.Root) {
+		total := 0.0
+		for r := 0; r < repeats; r++ {
+			perm := rng.Perm(len(set))
+			for i, item := range set {
+				cp := make(TrainingItem, len(item))
+				for k, v := range item {
+					cp[k] = v
+				}
+				if v, ok := set[perm[i]][attr]; ok {
+					cp[attr] = v
+				} else {
+					delete(cp, attr)
+				}
+				shuffled[i] = cp
+			}
+			report, err := m.Evaluate(shuffled)
+			if err != nil {
+				return nil, err
+			}
+			total += base.Accuracy - report.Accuracy
+		}
+		imp[attr] = total / float64(repeats)
+	}
+	return imp, nil
+}
+
+// PartialDependence returns the partial dependence of a binary model's
+// positive class (the lexicographically larger class) on feature: for each
+// value in grid, feature is set to that value on every item of set and the
+// PredictProba probabilities of the positive class are averaged. The result
+// has one entry per grid value. set is not modified. feature must be one the
+// model was trained on (or, for models without metadata, present in set).
+func (m *Model) PartialDependence(set TrainingSet, feature string, grid []float64) ([]float64, error) {
+	if m == nil || m.Root == nil {
+		return nil, errors.New("model is nil")
+	}
+	classes := m.classUniverse()
+	if len(classes) != 2 {
+		return nil, fmt.Errorf("partial dependence supports binary classification only, model has %d classes", len(classes))
+	}
+	if len(set) == 0 {
+		return nil, errors.New("set cannot be empty")
+	}
+	if !m.knownFeature(set, feature) {
+		return nil, fmt.Errorf("unknown feature %q", feature)
+	}
+
+	positive := classes[1]
+	out := make([]float64, len(grid))
+	cp := make(TrainingItem)
+	for g, v := range grid {
+		sum := 0.0
+		for i, item := range set {
+			for k := range cp {
+				delete(cp, k)
+			}
+			for k, val := range item {
+				cp[k] = val
+			}
+			cp[feature] = v
+			proba, err := m.PredictProba(cp)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			sum += proba[positive]
+		}
+		out[g] = sum / float64(len(set))
+	}
+	return out, nil
+}
+
+// knownFeature reports whether feature is a training feature of m: listed in
+// Metadata.FeatureNames, or for models saved without them, present in set.
+func (m *Model) knownFeature(set TrainingSet, feature string) bool {
+	if feature == "" || m.Config.isLabel(feature) || feature == m.Config.WeightAttr {
+		return false
+	}
+	if m.Metadata != nil && len(m.Metadata.FeatureNames) > 0 {
+		return stringInSlice(feature, m.Metadata.FeatureNames)
+	}
+	for _, item := range set {
+		if _, ok := item[feature]; ok {
+			return true
+		}
+	}
+	return false
+}
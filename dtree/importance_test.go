@@ -0,0 +1,147 @@
+package dtree
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestFeatureImportance_SumsToOne(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	imp := model.FeatureImportance()
+	sum := 0.0
+	for attr, v := range imp {
+		if v <= 0 {
+			t.Errorf("importance of %q = %v, want positive", attr, v)
+		}
+		sum += v
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Fatalf("importances sum to %v, want 1", sum)
+	}
+	if _, ok := imp[model.Root.Attribute]; !ok {
+		t.Fatalf("root attribute %q missing from %v", model.Root.Attribute, imp)
+	}
+}
+
+func TestTopFeatures_MatchesImportance(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	imp := model.FeatureImportance()
+	all := model.TopFeatures(100)
+	if len(all) != len(imp) {
+		t.Fatalf("TopFeatures(100) = %v, want all %d features", all, len(imp))
+	}
+	for i := 1; i < len(all); i++ {
+		if imp[all[i-1]] < imp[all[i]] {
+			t.Fatalf("TopFeatures not in importance order: %v (%v)", all, imp)
+		}
+	}
+	if top := model.TopFeatures(1); !reflect.DeepEqual(top, all[:1]) {
+		t.Fatalf("TopFeatures(1) = %v, want %v", top, all[:1])
+	}
+	if top := model.TopFeatures(0); len(top) != 0 {
+		t.Fatalf("TopFeatures(0) = %v, want none", top)
+	}
+}
+
+func TestSelectFeatures(t *testing.T) {
+	set := playTennisSet()
+	selected := SelectFeatures(set, []string{"Outlook", "Nope"}, "Play")
+	if len(selected) != len(set) {
+		t.Fatalf("got %d items, want %d", len(selected), len(set))
+	}
+	for i, item := range selected {
+		if len(item) != 2 || item["Outlook"] != set[i]["Outlook"] || item["Play"] != set[i]["Play"] {
+			t.Fatalf("item %d = %v", i, item)
+		}
+	}
+	if _, ok := set[0]["Humidity"]; !ok {
+		t.Fatal("SelectFeatures modified the input set")
+	}
+}
+
+func TestPermutationImportance(t *testing.T) {
+	// x and y decide the label; z is noise the deeper splits may pick up.
+	set := diagonalSet(400, 3)
+	rng := rand.New(rand.NewSource(4))
+	for _, item := range set {
+		item["z"] = rng.Float64()
+	}
+	model, err := Train(set, Config{CategoryAttr: "label", MaxDepth: 6})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	imp, err := model.PermutationImportance(set, 3, 1)
+	if err != nil {
+		t.Fatalf("permutation importance failed: %v", err)
+	}
+	for _, attr := range treeAttributes(model.Root) {
+		if _, ok := imp[attr]; !ok {
+			t.Errorf("missing importance for split attribute %q", attr)
+		}
+	}
+	if imp["x"] < 0.1 || imp["y"] < 0.1 || imp["z"] >= imp["x"] || imp["z"] >= imp["y"] {
+		t.Errorf("expected x and y to dominate the noise feature: %v", imp)
+	}
+	again, _ := model.PermutationImportance(set, 3, 1)
+	if !reflect.DeepEqual(imp, again) {
+		t.Fatalf("same seed gave %v then %v", imp, again)
+	}
+	if _, err := model.PermutationImportance(set, 0, 1); err == nil {
+		t.Fatal("expected error for non-positive repeats")
+	}
+	if _, err := model.PermutationImportance(TrainingSet{{"x": 1.0}}, 1, 1); err == nil {
+		t.Fatal("expected error for unlabelled items")
+	}
+}
+
+func TestPartialDependence_MonotoneFeature(t *testing.T) {
+	set := diagonalSet(400, 5)
+	model, err := Train(set, Config{CategoryAttr: "label", MaxDepth: 6})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	grid := []float64{0, 0.2, 0.4, 0.6, 0.8, 1}
+	firstX := set[0]["x"]
+	pd, err := model.PartialDependence(set, "x", grid)
+	if err != nil {
+		t.Fatalf("PartialDependence failed: %v", err)
+	}
+	if len(pd) != len(grid) {
+		t.Fatalf("got %d values, want %d", len(pd), len(grid))
+	}
+	// "pos" becomes likelier as x grows; allow small wiggles from the noise.
+	for i := 1; i < len(pd); i++ {
+		if pd[i] < pd[i-1]-0.05 {
+			t.Errorf("PD not monotone: %v", pd)
+			break
+		}
+	}
+	if pd[len(pd)-1]-pd[0] < 0.5 {
+		t.Errorf("PD rises from %.2f to %.2f, want a clear increase", pd[0], pd[len(pd)-1])
+	}
+	if set[0]["x"] != firstX {
+		t.Error("PartialDependence modified the input set")
+	}
+
+	if _, err := model.PartialDependence(set, "z", grid); err == nil {
+		t.Error("expected error for an unknown feature")
+	}
+	if _, err := model.PartialDependence(set, "label", grid); err == nil {
+		t.Error("expected error for the label attribute")
+	}
+	multi, err := Train(syntheticSet(60), Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if _, err := multi.PartialDependence(syntheticSet(60), "x", grid); err == nil {
+		t.Error("expected error for a multi-class model")
+	}
+}
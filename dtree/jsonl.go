@@ -0,0 +1,100 @@
+package dtree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// maxJSONLLine bounds a single JSONL record, well above bufio.Scanner's
+// 64 KiB default so wide rows still parse.
+const maxJSONLLine = 16 << 20
+
+// ReadJSONLStream parses JSON Lines, one object per line, into a training
+// set. The returned header is the union of the keys of every row: keys of
+// the first row in sorted order, then keys first seen on later rows in the
+// order they appear. Blank lines are skipped. Errors name the offending
+// 1-based line. Use MissingKeys to find rows lacking keys that others have.
+func ReadJSONLStream(r io.Reader) (TrainingSet, []string, error) {
+	var set TrainingSet
+	header, err := ReadJSONLFunc(r, func(line int, item TrainingItem) error {
+		set = append(set, item)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(set) == 0 {
+		return nil, nil, errors.New("JSONL file is empty")
+	}
+	return set, header, nil
+}
+
+// ReadJSONLFunc parses JSON Lines and calls fn for each object without
+// retaining it, as ReadCSVFunc does for CSV. line is the 1-based line
+// number. Returning an error from fn stops reading and returns that error.
+// The header, the union of all keys as described for ReadJSONLStream, is
+// returned on success.
+func ReadJSONLFunc(r io.Reader, fn func(line int, item TrainingItem) error) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxJSONLLine)
+	var header []string
+	seen := make(map[string]bool)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var item TrainingItem
+		if err := json.Unmarshal(sc.Bytes(), &item); err != nil {
+			return nil, fmt.Errorf("invalid JSON on line %d: %w", line, err)
+		}
+		if item == nil {
+			return nil, fmt.Errorf("line %d is not a JSON object", line)
+		}
+		var fresh []string
+		for k := range item {
+			if !seen[k] {
+				seen[k] = true
+				fresh = append(fresh, k)
+			}
+		}
+		sort.Strings(fresh)
+		header = append(header, fresh...)
+		if err := fn(line, item); err != nil {
+			return nil, err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("error reading JSONL: %w", err)
+	}
+	return header, nil
+}
+
+// MissingKeys reports, for each header key absent from some items, how many
+// items lack it and the first one (0-based), one warning per key in header
+// order. A key present with a null value is not missing. It returns nil when
+// every item has every key.
+func MissingKeys(set TrainingSet, header []string) []string {
+	var warnings []string
+	for _, key := range header {
+		missing, first := 0, -1
+		for i, item := range set {
+			if _, ok := item[key]; !ok {
+				if missing == 0 {
+					first = i
+				}
+				missing++
+			}
+		}
+		if missing > 0 {
+			warnings = append(warnings, "key "+strconv.Quote(key)+" is missing from "+
+				strconv.Itoa(missing)+" of "+strconv.Itoa(len(set))+" items; first is item "+strconv.Itoa(first))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,50 @@
+package dtree
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadJSONLStream_Ragged(t *testing.T) {
+	in := `{"b": 1, "a": "x", "label": "yes"}
+{"a": "y", "label": "no"}
+
+{"a": "z", "c": true, "label": "no"}
+{"b": 2, "c": null, "label": "yes"}
+`
+	set, header, err := ReadJSONLStream(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ReadJSONLStream failed: %v", err)
+	}
+	if len(set) != 4 {
+		t.Fatalf("got %d items, want 4", len(set))
+	}
+	if want := []string{"a", "b", "label", "c"}; !reflect.DeepEqual(header, want) {
+		t.Fatalf("header = %v, want %v", header, want)
+	}
+	want := []string{
+		`key "a" is missing from 1 of 4 items; first is item 3`,
+		`key "b" is missing from 2 of 4 items; first is item 1`,
+		`key "c" is missing from 2 of 4 items; first is item 0`,
+	}
+	if got := MissingKeys(set, header); !reflect.DeepEqual(got, want) {
+		t.Fatalf("MissingKeys = %q, want %q", got, want)
+	}
+	if got := MissingKeys(set[:1], []string{"a", "b", "label"}); got != nil {
+		t.Fatalf("expected no warnings for a complete set, got %q", got)
+	}
+}
+
+func TestReadJSONLStream_Errors(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"{\"a\":1}\n{oops\n", "invalid JSON on line 2"},
+		{"{\"a\":1}\nnull\n", "line 2 is not a JSON object"},
+		{"\n\n", "JSONL file is empty"},
+	} {
+		_, _, err := ReadJSONLStream(strings.NewReader(tc.in))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("input %q: got error %v, want %q", tc.in, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package dtree
+
+import (
+	"context"
+	"strconv"
+)
+
+// leakageGainShare is the share of the root impurity a single split must
+// remove for its feature to be flagged as possible leakage.
+const leakageGainShare = 0.99
+
+// DetectLeakage flags features that on their own separate the classes of
+// set almost perfectly, which usually means the feature was derived from
+// the label: one split on the feature removes at least 99% of the root
+// impurity, or, for a categorical feature with no more values than there
+// are classes, every value holds a single class. Each finding is a warning
+// like `feature "x" perfectly predicts the label (possible leakage)`; with
+// Config.WarnLeakage, Train adds them to Metadata.Warnings. It validates set
+// and cfg like Train.
+func DetectLeakage(set TrainingSet, cfg Config) ([]string, error) {
+	b, err := newTrainingBuilder(context.Background(), set, cfg, false)
+	if err != nil {
+		return nil, err
+	}
+	return b.leakageWarnings(set), nil
+}
+
+// leakageWarnings implements DetectLeakage with b's resolved config. The
+// search runs on its own builder, so it neither draws from b's random
+// generators nor reports to the Observer.
+func (b *builder) leakageWarnings(set TrainingSet) []string {
+	cfg := b.cfg
+	if len(cfg.CategoryAttrs) > 0 {
+		return nil
+	}
+	counts := counterUniqueValues(set, cfg.CategoryAttr)
+	initImpurity, size := b.sideImpurity(set, counts)
+	if initImpurity <= minGain {
+		return nil
+	}
+
+	lcfg := cfg
+	lcfg.Observer, lcfg.Verbose = nil, false
+	lcfg.MaxFeatures, lcfg.TieBreak = 0, ""
+	lcfg.RandomSplits, lcfg.MultiwaySplits, lcfg.AllowObliqueSplits = false, false, false
+	lcfg.MonotoneConstraints = nil
+	lb := newBuilder(lcfg)
+	lb.ctx, lb.hist, lb.wide = b.ctx, b.hist, b.wide
+
+	var warnings []string
+	for _, attr := range b.splittableAttributes(set, nil) {
+		if lb.separates(set, attr, counts, initImpurity, size) {
+			warnings = append(warnings, "feature "+strconv.Quote(attr)+" perfectly predicts the label (possible leakage)")
+		}
+	}
+	return warnings
+}
+
+// separates reports whether attr alone nearly separates the classes of set.
+func (b *builder) separates(set TrainingSet, attr string, counts map[string]int, initImpurity, size float64) bool {
+	label := b.cfg.CategoryAttr
+	only := make(TrainingSet, len(set))
+	for i, item := range set {
+		o := TrainingItem{label: item[label]}
+		if v, ok := item[attr]; ok {
+			o[attr] = v
+		}
+		if w, ok := item[b.cfg.WeightAttr]; ok {
+			o[b.cfg.WeightAttr] = w
+		}
+		only[i] = o
+	}
+	if best, found := b.findSplit(only, counts, initImpurity, size, fullBounds); found && best.Gain >= leakageGainShare*initImpurity {
+		return true
+	}
+
+	// One binary split cannot separate three or more classes; a categorical
+	// relabeling of the label can still.
+	classOf := make(map[string]string)
+	for _, item := range set {
+		v := item[attr]
+		if v == nil || isNumeric(v) {
+			return false
+		}
+		key, class := valueKey(v), valueKey(item[label])
+		if c, ok := classOf[key]; ok && c != class {
+			return false
+		}
+		classOf[key] = class
+	}
+	return len(counts) > 2 && len(classOf) <= len(counts)
+}
@@ -0,0 +1,47 @@
+package dtree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectLeakage(t *testing.T) {
+	set := syntheticSet(300)
+	for i, item := range set {
+		item["leak"] = "copy-" + item["label"].(string)
+		item["code"] = float64(len(item["label"].(string))*10 + i%3)
+	}
+	// "code" is 10..12 for every class here, so it says nothing.
+	want := []string{`feature "leak" perfectly predicts the label (possible leakage)`}
+	got, err := DetectLeakage(set, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("DetectLeakage failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("DetectLeakage = %q, want %q", got, want)
+	}
+
+	model, err := Train(set, Config{CategoryAttr: "label", WarnLeakage: true})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if !reflect.DeepEqual(model.Metadata.Warnings, want) {
+		t.Fatalf("warnings = %q, want %q", model.Metadata.Warnings, want)
+	}
+	model, _ = Train(set, Config{CategoryAttr: "label"})
+	if len(model.Metadata.Warnings) != 0 {
+		t.Fatalf("leakage warned without WarnLeakage: %q", model.Metadata.Warnings)
+	}
+
+	// A two-class numeric threshold that splits cleanly is caught too.
+	two := diagonalSet(200, 1)
+	for _, item := range two {
+		item["score"] = 0.0
+		if item["label"] == "pos" {
+			item["score"] = 1.0
+		}
+	}
+	if got, _ := DetectLeakage(two, Config{CategoryAttr: "label"}); !reflect.DeepEqual(got, []string{`feature "score" perfectly predicts the label (possible leakage)`}) {
+		t.Fatalf("two-class leakage = %q", got)
+	}
+}
@@ -0,0 +1,76 @@
+package dtree
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// leafPath is a leaf together with the conditions leading to it from the root.
+type leafPath struct {
+	conditions []string
+	leaf       *TreeItem
+}
+
+// collectLeafPaths appends every leaf under n to out, in branches() order.
+// A "no" branch is written as the negated condition, a multiway child as
+// "Attr == value".
+func collectLeafPaths(n *TreeItem, conds []string, out []leafPath) []leafPath {
+	if n == nil {
+		return out
+	}
+	if n.isLeaf() {
+		return append(out, leafPath{conditions: cloneStrings(conds), leaf: n})
+	}
+	for _, b := range n.branches() {
+		var cond string
+		switch {
+		case b.node == n.Missing:
+			cond = n.Attribute + " is missing"
+		case len(n.Children) > 0:
+			cond = n.Attribute + " == " + b.label
+		case b.label == "yes":
+			cond = n.condition()
+		default:
+			cond = "NOT (" + n.condition() + ")"
+		}
+		out = collectLeafPaths(b.node, append(conds, cond), out)
+	}
+	return out
+}
+
+// LeavesCSV writes one row per leaf with the path from the root (conditions
+// joined by " AND ", empty for a single-leaf tree), the predicted class, the
+// number of training samples and then one count column per class, in the
+// order of Classes().
+func (m *Model) LeavesCSV(w io.Writer) error {
+	if m == nil || m.Root == nil {
+		return errors.New("model is nil")
+	}
+	classes := m.Classes()
+	header := []string{"path", "class", "samples"}
+	for _, c := range classes {
+		header = append(header, "count_"+c)
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, p := range collectLeafPaths(m.Root, nil, nil) {
+		row := []string{
+			strings.Join(p.conditions, " AND "),
+			p.leaf.Category,
+			strconv.Itoa(countTotal(p.leaf.ClassCounts)),
+		}
+		for _, c := range classes {
+			row = append(row, strconv.Itoa(p.leaf.ClassCounts[c]))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,58 @@
+package dtree
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLeavesCSV(t *testing.T) {
+	for _, cfg := range []Config{
+		{CategoryAttr: "Play"},
+		{CategoryAttr: "Play", MultiwaySplits: true},
+	} {
+		model, err := Train(playTennisSet(), cfg)
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := model.LeavesCSV(&buf); err != nil {
+			t.Fatalf("LeavesCSV failed: %v", err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("output is not valid CSV: %v", err)
+		}
+		if got, want := len(rows)-1, model.Stats().LeafNodes; got != want {
+			t.Fatalf("got %d leaf rows, want %d", got, want)
+		}
+		want := []string{"path", "class", "samples"}
+		for _, c := range model.Classes() {
+			want = append(want, "count_"+c)
+		}
+		if strings.Join(rows[0], ",") != strings.Join(want, ",") {
+			t.Fatalf("header = %v, want %v", rows[0], want)
+		}
+		total := 0
+		for _, row := range rows[1:] {
+			if row[0] == "" {
+				t.Errorf("empty path for leaf %v", row)
+			}
+			n, _ := strconv.Atoi(row[2])
+			sum := 0
+			for _, c := range row[3:] {
+				k, _ := strconv.Atoi(c)
+				sum += k
+			}
+			if n != sum {
+				t.Errorf("samples %d do not match class counts in %v", n, row)
+			}
+			total += n
+		}
+		if total != len(playTennisSet()) {
+			t.Errorf("leaf samples sum to %d, want %d", total, len(playTennisSet()))
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package dtree
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// obliqueDirections is the number of evenly spaced angles in [0, pi) tried
+// per attribute pair; the two axis-aligned ones are skipped, as ordinary
+// splits already cover them.
+const obliqueDirections = 12
+
+// String renders the split, e.g. "0.8*x - 0.35*y >= 1.2".
+func (o *ObliqueSplit) String() string {
+	var b strings.Builder
+	for i, attr := range o.Attributes {
+		w := o.Weights[i]
+		switch {
+		case i == 0 && w < 0:
+			b.WriteString("-")
+		case i > 0 && w < 0:
+			b.WriteString(" - ")
+		case i > 0:
+			b.WriteString(" + ")
+		}
+		b.WriteString(strconv.FormatFloat(math.Abs(w), 'g', 4, 64) + "*" + attr)
+	}
+	b.WriteString(" >= " + strconv.FormatFloat(o.Threshold, 'g', 4, 64))
+	return b.String()
+}
+
+// score returns the weighted sum for item. If an attribute is absent or not
+// numeric it reports that attribute and false.
+func (o *ObliqueSplit) score(item attrSource) (float64, string, bool) {
+	s := 0.0
+	for i, attr := range o.Attributes {
+		v, _ := item.lookup(attr)
+		if !finiteNumber(v) {
+			return 0, attr, false
+		}
+		s += o.Weights[i] * toFloat(v)
+	}
+	return s, "", true
+}
+
+// numericAttributes returns the attributes of attrs that have at least two
+// numeric values in set and no non-numeric ones.
+func numericAttributes(set TrainingSet, attrs []string) []string {
+	var out []string
+	for _, attr := range attrs {
+		n, ok := 0, true
+		for _, item := range set {
+			v := item[attr]
+			if v == nil || nonFinite(v) {
+				continue
+			}
+			if !isNumeric(v) {
+				ok = false
+				break
+			}
+			n++
+		}
+		if ok && n >= 2 {
+			out = append(out, attr)
+		}
+	}
+	return out
+}
+
+// evalOblique finds the best oblique split over pairs of numeric attributes.
+// Each pair is standardized on the items that have both values, then every
+// direction is scored by sweeping thresholds over the sorted projections.
+// Items lacking either value always go to NoMatch, as for ordinary splits.
+// Pairs with a monotone-constrained attribute are skipped, and under
+// MonotoneConstraints a split is only kept if its children fit bnd.
+func (b *builder) evalOblique(set TrainingSet, attrs []string, initImpurity, size float64, bnd bounds) (splitResult, bool) {
+	cfg := b.cfg
+	numeric := numericAttributes(set, attrs)
+	var best splitResult
+	found := false
+	type proj struct {
+		v float64
+		i int
+	}
+	for i := 0; i < len(numeric); i++ {
+		for j := i + 1; j < len(numeric); j++ {
+			a, c := numeric[i], numeric[j]
+			if cfg.MonotoneConstraints[a] != 0 || cfg.MonotoneConstraints[c] != 0 {
+				continue
+			}
+			var idx []int
+			var xs, ys []float64
+			for k, item := range set {
+				if finiteNumber(item[a]) && finiteNumber(item[c]) {
+					idx = append(idx, k)
+					xs = append(xs, toFloat(item[a]))
+					ys = append(ys, toFloat(item[c]))
+				}
+			}
+			sx, sy := stddev(xs), stddev(ys)
+			if len(idx) < 2 || sx == 0 || sy == 0 {
+				continue
+			}
+
+			projs := make([]proj, len(idx))
+			for d := 1; d < obliqueDirections; d++ {
+				if 2*d == obliqueDirections {
+					continue
+				}
+				if b.cancelled() {
+					return best, false
+				}
+				if cfg.Observer != nil {
+					cfg.Observer.OnSplitEvaluated(a + "," + c)
+				}
+				theta := math.Pi * float64(d) / obliqueDirections
+				o := &ObliqueSplit{
+					Attributes: []string{a, c},
+					Weights:    []float64{math.Cos(theta) / sx, math.Sin(theta) / sy},
+				}
+				for k, si := range idx {
+					s, _, _ := o.score(set[si])
+					projs[k] = proj{s, si}
+				}
+				sort.SliceStable(projs, func(p, q int) bool { return projs[p].v > projs[q].v })
+
+				// Sweep from the largest projection down: the first k items
+				// match a threshold equal to the k-th projection.
+				noMatchW, noMatchT := b.tally(set)
+				matchW, matchT := make(map[string]float64, len(noMatchW)), 0.0
+				for k, p := range projs {
+					item := set[p.i]
+					w := b.weight(item)
+					class := valueKey(item[cfg.CategoryAttr])
+					matchW[class] += w
+					noMatchW[class] -= w
+					matchT += w
+					noMatchT -= w
+					if k+1 < len(projs) && projs[k+1].v == p.v {
+						continue
+					}
+					if !b.usablePartition(k+1, len(set)) {
+						continue
+					}
+					newI := (b.weightedImpurity(matchW, matchT)*matchT + b.weightedImpurity(noMatchW, noMatchT)*noMatchT) / size
+					if gain := initImpurity - newI; !found || gain > best.Gain {
+						split := *o
+						split.Threshold = p.v
+						curr := splitResult{Gain: gain, Oblique: &split, PredicateName: "oblique"}
+						if len(cfg.MonotoneConstraints) > 0 {
+							b.partitionOblique(set, &curr)
+							if _, _, ok := b.monotoneChildBounds(curr, bnd); !ok {
+								continue
+							}
+						}
+						best, found = curr, true
+					}
+				}
+			}
+		}
+	}
+	if !found {
+		return best, false
+	}
+	if len(cfg.MonotoneConstraints) == 0 {
+		b.partitionOblique(set, &best)
+	}
+	return best, true
+}
+
+// partitionOblique fills in the Match and NoMatch sides of an oblique split.
+func (b *builder) partitionOblique(set TrainingSet, s *splitResult) {
+	for _, item := range set {
+		if v, _, ok := s.Oblique.score(item); ok && v >= s.Oblique.Threshold {
+			s.Match = append(s.Match, item)
+		} else {
+			s.NoMatch = append(s.NoMatch, item)
+		}
+	}
+	s.MatchCounts = counterUniqueValues(s.Match, b.cfg.CategoryAttr)
+	s.NoMatchCounts = counterUniqueValues(s.NoMatch, b.cfg.CategoryAttr)
+}
+
+// weightedImpurity scores per-class weight sums with the configured criterion.
+func (b *builder) weightedImpurity(weights map[string]float64, total float64) float64 {
+	if b.cfg.Criterion == CriterionGini {
+		return giniFromWeights(weights, total)
+	}
+	return entropyFromWeights(weights, total)
+}
+
+func stddev(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	mean := 0.0
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	v := 0.0
+	for _, x := range xs {
+		v += (x - mean) * (x - mean)
+	}
+	return math.Sqrt(v / float64(len(xs)))
+}
@@ -0,0 +1,125 @@
+package dtree
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// cleanDiagonalSet labels uniform points in the unit square by x+y >= 1,
+// with no label noise. The y column is scaled to check standardization.
+func cleanDiagonalSet(n int, seed int64) TrainingSet {
+	rng := rand.New(rand.NewSource(seed))
+	ts := make(TrainingSet, n)
+	for i := range ts {
+		x, y := rng.Float64(), rng.Float64()
+		label := "neg"
+		if x+y >= 1 {
+			label = "pos"
+		}
+		ts[i] = TrainingItem{"x": x, "y": 100 * y, "label": label}
+	}
+	return ts
+}
+
+func TestObliqueSplits_ShallowerOnDiagonal(t *testing.T) {
+	train, test := cleanDiagonalSet(400, 1), cleanDiagonalSet(400, 2)
+	axis, err := Train(train, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	oblique, err := Train(train, Config{CategoryAttr: "label", AllowObliqueSplits: true})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if oblique.Root.Oblique == nil {
+		t.Fatalf("expected an oblique root split, got %s", textLabel(oblique.Root))
+	}
+	if got, want := oblique.Stats().TreeDepth, axis.Stats().TreeDepth; got >= want {
+		t.Fatalf("oblique tree depth %d, want less than axis-aligned depth %d", got, want)
+	}
+	axisAcc := accuracy(t, axis.Predict, test)
+	obliqueAcc := accuracy(t, oblique.Predict, test)
+	if obliqueAcc < axisAcc {
+		t.Errorf("oblique accuracy %.3f below axis-aligned %.3f", obliqueAcc, axisAcc)
+	}
+	if !strings.Contains(oblique.ToText(), "*x") {
+		t.Errorf("text rendering should show the linear combination:\n%s", oblique.ToText())
+	}
+}
+
+func TestObliqueSplits_RoundTripAndClone(t *testing.T) {
+	model, err := Train(cleanDiagonalSet(200, 3), Config{CategoryAttr: "label", AllowObliqueSplits: true})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := encodeIndented(&buf, model); err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	loaded, err := DecodeJSON(&buf)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if !model.Equal(loaded) {
+		t.Fatalf("round trip changed the model: %v", model.Diff(loaded))
+	}
+	for _, item := range cleanDiagonalSet(100, 4) {
+		a, _ := model.Predict(item)
+		b, _ := loaded.Predict(item)
+		if a != b {
+			t.Fatalf("prediction changed after round trip for %v", item)
+		}
+	}
+
+	cp := model.Clone()
+	cp.Root.Oblique.Weights[0] = 42
+	if model.Root.Oblique.Weights[0] == 42 {
+		t.Fatal("Clone shares oblique weights with the original")
+	}
+	if model.Equal(cp) {
+		t.Fatal("Equal should notice changed oblique weights")
+	}
+}
+
+func TestObliqueSplits_MissingAndValidate(t *testing.T) {
+	model, err := Train(cleanDiagonalSet(200, 5), Config{CategoryAttr: "label", AllowObliqueSplits: true})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if _, err := model.Predict(TrainingItem{"x": 0.5}); err != nil {
+		t.Fatalf("missing attribute should follow the missing strategy: %v", err)
+	}
+	strict := model.Clone()
+	strict.Config.StrictPredict = true
+	if _, err := strict.Predict(TrainingItem{"x": 0.5}); err == nil || !strings.Contains(err.Error(), `"y"`) {
+		t.Fatalf("expected strict error naming y, got %v", err)
+	}
+
+	bad := model.Clone()
+	bad.Root.Oblique.Weights = bad.Root.Oblique.Weights[:1]
+	if err := bad.Validate(); err == nil {
+		t.Fatal("expected error for mismatched oblique weights")
+	}
+	bad = model.Clone()
+	bad.Root.PredicateName = ">="
+	if err := bad.Validate(); err == nil {
+		t.Fatal("expected error for oblique node with wrong predicateName")
+	}
+}
+
+func TestObliqueSplits_MonotoneConstraints(t *testing.T) {
+	// Oblique splits on y and z never involve x, but they must still keep
+	// within the bounds x's splits put on their subtrees.
+	for seed := int64(0); seed < 5; seed++ {
+		cfg := Config{CategoryAttr: "label", AllowObliqueSplits: true, MonotoneConstraints: map[string]int{"x": 1}}
+		model, err := Train(noisyMonotoneSet(seed, 300, "y", "z"), cfg)
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		if n := monotoneViolations(t, model, "y", "z"); n > 0 {
+			t.Errorf("seed %d: positive probability decreases in x %d times", seed, n)
+		}
+	}
+}
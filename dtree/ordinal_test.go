@@ -0,0 +1,146 @@
+package dtree
+
+import (
+	"bytes"
+	"testing"
+)
+
+// sizeSet labels items "big" exactly when size is high or huge, so one
+// ordinal threshold separates the classes.
+func sizeSet() TrainingSet {
+	var set TrainingSet
+	for i, size := range []string{"low", "medium", "high", "huge", "low", "medium", "high", "huge"} {
+		label := "small"
+		if size == "high" || size == "huge" {
+			label = "big"
+		}
+		set = append(set, TrainingItem{"size": size, "id": float64(i % 2), "label": label})
+	}
+	return set
+}
+
+var sizeLevels = map[string][]string{"size": {"low", "medium", "high", "huge"}}
+
+func TestOrdinalFeatures_SingleThresholdSplit(t *testing.T) {
+	nominal, err := Train(sizeSet(), Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if nominal.Stats().InternalNodes < 2 {
+		t.Fatalf("expected a chain of equality splits without ordinal levels:\n%s", nominal)
+	}
+
+	model, err := Train(sizeSet(), Config{CategoryAttr: "label", OrdinalFeatures: sizeLevels})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	root := model.Root
+	if model.Stats().InternalNodes != 1 || root.Attribute != "size" || root.PredicateName != ">=" || root.Pivot != "high" {
+		t.Fatalf("expected a single size >= high split, got:\n%s", model)
+	}
+	for level, want := range map[string]string{"low": "small", "medium": "small", "high": "big", "huge": "big"} {
+		if got, err := model.Predict(TrainingItem{"size": level}); err != nil || got != want {
+			t.Errorf("Predict(%s) = %q, %v; want %q", level, got, err, want)
+		}
+	}
+}
+
+func TestOrdinalFeatures_UnknownLevelIsMissing(t *testing.T) {
+	model, err := Train(sizeSet(), Config{CategoryAttr: "label", OrdinalFeatures: sizeLevels, MissingStrategy: MissingFail})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if _, err := model.Predict(TrainingItem{"size": "gigantic"}); err == nil {
+		t.Fatal("expected an unknown level to be treated as missing")
+	}
+	model.Config.MissingStrategy = MissingMatch
+	if got, err := model.Predict(TrainingItem{"size": "gigantic"}); err != nil || got != "big" {
+		t.Fatalf("Predict = %q, %v; want the match branch", got, err)
+	}
+}
+
+func TestOrdinalFeatures_Validate(t *testing.T) {
+	for _, levels := range []map[string][]string{
+		{"size": {}},
+		{"size": {"low", "low"}},
+	} {
+		if _, err := Train(sizeSet(), Config{CategoryAttr: "label", OrdinalFeatures: levels}); err == nil {
+			t.Errorf("expected error for levels %v", levels)
+		}
+	}
+	model, _ := Train(sizeSet(), Config{CategoryAttr: "label", OrdinalFeatures: sizeLevels})
+	cp := model.Clone()
+	cp.Config.OrdinalFeatures["size"][0] = "tiny"
+	if model.Config.OrdinalFeatures["size"][0] != "low" {
+		t.Fatal("Clone shares ordinal levels with the original")
+	}
+	cp.Config.OrdinalFeatures["size"] = nil
+	if err := cp.Validate(); err == nil {
+		t.Fatal("expected Validate to reject empty ordinal levels")
+	}
+}
+
+// releaseSet labels items "new" exactly when release sorts at or after
+// "2024-03", so one lexicographic threshold separates the classes.
+func releaseSet() TrainingSet {
+	var set TrainingSet
+	for i, release := range []string{"2023-01", "2023-07", "2023-11", "2024-03", "2024-06", "2025-01"} {
+		label := "old"
+		if release >= "2024-03" {
+			label = "new"
+		}
+		set = append(set, TrainingItem{"release": release, "id": float64(i % 2), "label": label})
+	}
+	return set
+}
+
+func TestLexicographicFeatures_OrderedSplit(t *testing.T) {
+	nominal, err := Train(releaseSet(), Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if nominal.Root.PredicateName != "==" {
+		t.Fatalf("expected an equality split without LexicographicFeatures:\n%s", nominal)
+	}
+
+	cfg := Config{CategoryAttr: "label", LexicographicFeatures: []string{"release"}, MissingStrategy: MissingFail}
+	model, err := Train(releaseSet(), cfg)
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	root := model.Root
+	if model.Stats().InternalNodes != 1 || root.Attribute != "release" || root.PredicateName != ">=" || root.Pivot != "2024-03" {
+		t.Fatalf("expected a single release >= 2024-03 split, got:\n%s", model)
+	}
+	// Unseen values are ordered too.
+	for release, want := range map[string]string{"2022-12": "old", "2024-02": "old", "2024-04": "new", "2030-01": "new"} {
+		if got, err := model.Predict(TrainingItem{"release": release}); err != nil || got != want {
+			t.Errorf("Predict(%s) = %q, %v; want %q", release, got, err, want)
+		}
+	}
+	if _, err := model.Predict(TrainingItem{"release": 2024.0}); err == nil {
+		t.Error("expected a non-string value to be treated as missing")
+	}
+
+	var buf bytes.Buffer
+	if err := encodeIndented(&buf, model); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := DecodeJSON(&buf)
+	if err != nil {
+		t.Fatalf("DecodeJSON failed: %v", err)
+	}
+	if got, _ := loaded.Predict(TrainingItem{"release": "2024-04"}); got != "new" {
+		t.Errorf("reloaded model predicts %q", got)
+	}
+
+	for _, names := range [][]string{{""}, {"release", "release"}} {
+		if _, err := Train(releaseSet(), Config{CategoryAttr: "label", LexicographicFeatures: names}); err == nil {
+			t.Errorf("expected error for LexicographicFeatures %q", names)
+		}
+	}
+	both := Config{CategoryAttr: "label", LexicographicFeatures: []string{"size"}, OrdinalFeatures: sizeLevels}
+	if err := both.Validate(); err == nil {
+		t.Error("expected error for a feature both ordinal and lexicographic")
+	}
+}
@@ -0,0 +1,204 @@
+package dtree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// PartialFit returns a copy of m refreshed with newData without changing the
+// tree structure: every new item is routed down the tree as in Predict, and
+// the class counts of each node it passes are incremented, along with the
+// branch sizes of the binary splits it follows. Each touched node's Category
+// is then recomputed as the majority of its updated counts, so a leaf whose
+// majority flips predicts the new class. Multi-output models update the
+// OutputCounts of every label the same way. Metadata.NumSamples and
+// Metadata.Classes are updated to match. m is left unchanged.
+//
+// Weighted models are not supported, since the weights behind the existing
+// counts are not stored. An item that cannot be routed, for example because
+// StrictPredict is set and it lacks a split attribute, fails the whole call.
+func (m *Model) PartialFit(newData TrainingSet) (*Model, error) {
+	if m == nil || m.Root == nil {
+		return nil, errors.New("model is nil")
+	}
+	if m.Config.WeightAttr != "" {
+		return nil, errors.New("PartialFit does not support weighted models")
+	}
+	label := m.Config.CategoryAttr
+	for i, item := range newData {
+		for _, attr := range m.Config.outputs() {
+			if item == nil || !validLabel(item[attr]) {
+				return nil, fmt.Errorf("item %d has no valid %q label", i, attr)
+			}
+		}
+	}
+
+	cp := m.Clone()
+	touched := make(map[*TreeItem]bool)
+	seen := make(map[string]bool)
+	for i, item := range newData {
+		class := valueKey(item[label])
+		seen[class] = true
+		node := cp.Root
+		for node != nil {
+			if node.ClassCounts == nil {
+				node.ClassCounts = make(map[string]int)
+			}
+			node.ClassCounts[class]++
+			for _, attr := range m.Config.CategoryAttrs {
+				if node.OutputCounts == nil {
+					node.OutputCounts = make(map[string]map[string]int)
+				}
+				if node.OutputCounts[attr] == nil {
+					node.OutputCounts[attr] = make(map[string]int)
+				}
+				node.OutputCounts[attr][valueKey(item[attr])]++
+			}
+			touched[node] = true
+			if node.isLeaf() {
+				break
+			}
+			next, err := cp.nextNode(node, item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			switch {
+			case next == nil:
+			case next == node.Match:
+				node.MatchedCount++
+			case next == node.NoMatch:
+				node.NoMatchedCount++
+			}
+			node = next
+		}
+	}
+	for node := range touched {
+		node.Category = mostFrequentValue(node.ClassCounts)
+	}
+
+	if cp.Metadata != nil {
+		cp.Metadata.NumSamples += len(newData)
+		if len(cp.Metadata.Classes) > 0 {
+			for _, c := range cp.Metadata.Classes {
+				seen[c] = true
+			}
+			cp.Metadata.Classes = sortedKeys(seen)
+		}
+	}
+	return cp, nil
+}
+
+// RefitOptions controls RefitLeaves. Zero limits keep the model's own.
+type RefitOptions struct {
+	// Impurity is the impurity, in the model's Criterion units, above which
+	// a leaf's refit items are grown into a new subtree. 0 only recounts.
+	Impurity float64
+	// MaxDepth, MinSamples and MinSamplesLeaf override the model's Config
+	// limits for regrown subtrees. MaxDepth is counted from the root.
+	MaxDepth       int
+	MinSamples     int
+	MinSamplesLeaf int
+}
+
+// RefitLeaves returns a copy of m whose splits are kept but whose counts come
+// from set alone: every item is routed down the tree as in Predict, each
+// node's ClassCounts, OutputCounts and branch sizes are replaced by those of
+// the items reaching it, and each leaf's Category is rederived from them. A
+// leaf no item reaches keeps an empty count and category. With
+// opts.Impurity set, a leaf whose items are more impure than that is
+// replaced by a subtree grown from them as Train grows one with m.Config,
+// so new splits are read at predict time under the settings they were made
+// with. This is cheaper than retraining when the data drifts but the split
+// structure still holds.
+//
+// set is validated like Train's. The result keeps m's Config and updates
+// Metadata.NumSamples and Metadata.Classes to set. m is left unchanged.
+func (m *Model) RefitLeaves(set TrainingSet, opts RefitOptions) (*Model, error) {
+	if m == nil || m.Root == nil {
+		return nil, errors.New("model is nil")
+	}
+	if opts.Impurity < 0 || math.IsNaN(opts.Impurity) {
+		return nil, errors.New("opts.Impurity cannot be negative")
+	}
+	cfg := m.Config
+	if opts.MaxDepth != 0 {
+		cfg.MaxDepth = opts.MaxDepth
+	}
+	if opts.MinSamples != 0 {
+		cfg.MinSamples = opts.MinSamples
+	}
+	if opts.MinSamplesLeaf != 0 {
+		cfg.MinSamplesLeaf = opts.MinSamplesLeaf
+	}
+	b, err := newTrainingBuilder(context.Background(), set, cfg, false)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := m.Clone()
+	reached := make(map[*TreeItem]TrainingSet)
+	matched := make(map[*TreeItem][2]int)
+	for i, item := range set {
+		node := cp.Root
+		for node != nil {
+			reached[node] = append(reached[node], item)
+			if node.isLeaf() {
+				break
+			}
+			next, err := cp.nextNode(node, item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			n := matched[node]
+			switch {
+			case next == nil:
+			case next == node.Match:
+				n[0]++
+			case next == node.NoMatch:
+				n[1]++
+			}
+			matched[node] = n
+			node = next
+		}
+	}
+
+	var refit func(node *TreeItem, depth int)
+	refit = func(node *TreeItem, depth int) {
+		items := reached[node]
+		counts := counterUniqueValues(items, b.cfg.CategoryAttr)
+		if !node.isLeaf() {
+			node.ClassCounts = counts
+			node.OutputCounts = b.outputCounts(items)
+			node.Category = mostFrequentValue(counts)
+			node.MatchedCount, node.NoMatchedCount = matched[node][0], matched[node][1]
+			for _, br := range node.branches() {
+				refit(br.node, depth+1)
+			}
+			return
+		}
+		var fresh *TreeItem
+		if impurity, _ := b.sideImpurity(items, counts); len(items) > 0 && opts.Impurity > 0 && impurity > opts.Impurity {
+			fresh = b.makeTrainingTree(items, depth, fullBounds)
+		} else if len(items) > 0 {
+			fresh = b.leaf(items, counts)
+		} else {
+			fresh = &TreeItem{ClassCounts: map[string]int{}}
+		}
+		if fresh != nil {
+			*node = *fresh
+		}
+	}
+	refit(cp.Root, 0)
+	if b.err != nil {
+		return nil, b.err
+	}
+	cp.AssignIDs()
+
+	if cp.Metadata != nil {
+		cp.Metadata.NumSamples = len(set)
+		cp.Metadata.Classes = sortedKeys(classSet(set, b.cfg.CategoryAttr))
+	}
+	return cp, nil
+}
@@ -0,0 +1,208 @@
+package dtree
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sameStructure reports whether a and b have the same splits and leaves,
+// ignoring counts and categories.
+func sameStructure(a, b *TreeItem) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.isLeaf() != b.isLeaf() || (!a.isLeaf() && !sameSplit(a, b)) {
+		return false
+	}
+	ab, bb := a.branches(), b.branches()
+	if len(ab) != len(bb) {
+		return false
+	}
+	for i := range ab {
+		if ab[i].label != bb[i].label || !sameStructure(ab[i].node, bb[i].node) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPartialFit_FlipsLeafMajority(t *testing.T) {
+	set := playTennisSet()
+	model, err := Train(set, Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	var probe TrainingItem
+	for _, item := range set {
+		if item["Play"] == "no" {
+			probe = item
+			break
+		}
+	}
+	leafID, before, err := model.PredictLeaf(probe)
+	if err != nil || before != "no" {
+		t.Fatalf("probe predicted %q, %v; want no", before, err)
+	}
+	leaf := findByID(model.Root, leafID)
+
+	var extra TrainingSet
+	for i := 0; i <= leaf.ClassCounts["no"]; i++ {
+		item := TrainingItem{}
+		for k, v := range probe {
+			item[k] = v
+		}
+		item["Play"] = "yes"
+		extra = append(extra, item)
+	}
+	updated, err := model.PartialFit(extra)
+	if err != nil {
+		t.Fatalf("PartialFit failed: %v", err)
+	}
+	if err := updated.Validate(); err != nil {
+		t.Fatalf("updated model is invalid: %v", err)
+	}
+	if !sameStructure(model.Root, updated.Root) {
+		t.Fatal("PartialFit changed the tree structure")
+	}
+	if got, _ := updated.Predict(probe); got != "yes" {
+		t.Errorf("updated leaf predicts %q, want yes", got)
+	}
+	if got, _ := model.Predict(probe); got != "no" {
+		t.Errorf("original model changed: predicts %q", got)
+	}
+	if got, want := countTotal(updated.Root.ClassCounts), len(set)+len(extra); got != want {
+		t.Errorf("root counts total %d, want %d", got, want)
+	}
+	if got, want := updated.Metadata.NumSamples, len(set)+len(extra); got != want {
+		t.Errorf("NumSamples = %d, want %d", got, want)
+	}
+}
+
+func TestPartialFit_NewClassAndErrors(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	item := TrainingItem{"Outlook": "rain", "Temperature": 70.0, "Humidity": 70.0, "Wind": false, "Play": "maybe"}
+	updated, err := model.PartialFit(TrainingSet{item})
+	if err != nil {
+		t.Fatalf("PartialFit failed: %v", err)
+	}
+	if got := strings.Join(updated.Classes(), ","); got != "maybe,no,yes" {
+		t.Errorf("Classes() = %s, want maybe,no,yes", got)
+	}
+
+	if _, err := model.PartialFit(TrainingSet{{"Outlook": "rain"}}); err == nil {
+		t.Error("expected error for an item without a label")
+	}
+	strict := model.Clone()
+	strict.Config.StrictPredict = true
+	if _, err := strict.PartialFit(TrainingSet{{"Play": "yes"}}); err == nil {
+		t.Error("expected error for an item that cannot be routed in strict mode")
+	}
+	weighted := model.Clone()
+	weighted.Config.WeightAttr = "w"
+	if _, err := weighted.PartialFit(TrainingSet{item}); err == nil {
+		t.Error("expected error for a weighted model")
+	}
+}
+
+func TestRefitLeaves_RegrowsShiftedLeaf(t *testing.T) {
+	var before, after TrainingSet
+	for i := 0; i < 40; i++ {
+		x := float64(i) / 4
+		label := "a"
+		if x >= 5 {
+			label = "b"
+		}
+		before = append(before, TrainingItem{"x": x, "label": label})
+		if x >= 2.5 && x < 5 {
+			label = "c"
+		}
+		after = append(after, TrainingItem{"x": x, "label": label})
+	}
+	cfg := Config{CategoryAttr: "label"}
+	model, err := Train(before, cfg)
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if !model.Root.NoMatch.isLeaf() {
+		t.Fatal("expected the x < 5 side to start as a pure leaf")
+	}
+
+	// Recounting alone keeps the structure; the leaf is now mixed.
+	recounted, err := model.RefitLeaves(after, RefitOptions{})
+	if err != nil {
+		t.Fatalf("RefitLeaves failed: %v", err)
+	}
+	if !sameStructure(model.Root, recounted.Root) {
+		t.Fatal("RefitLeaves without Impurity changed the structure")
+	}
+	if got := recounted.Root.NoMatch.ClassCounts; got["a"] != 10 || got["c"] != 10 {
+		t.Fatalf("refit leaf counts = %v, want a=10 c=10", got)
+	}
+	if model.Root.NoMatch.ClassCounts["c"] != 0 {
+		t.Fatal("RefitLeaves modified the original model")
+	}
+
+	regrown, err := model.RefitLeaves(after, RefitOptions{Impurity: 0.5})
+	if err != nil {
+		t.Fatalf("RefitLeaves failed: %v", err)
+	}
+	if !sameSplit(model.Root, regrown.Root) || !regrown.Root.Match.isLeaf() {
+		t.Fatal("the root split and its pure side should be kept")
+	}
+	if regrown.Root.NoMatch.isLeaf() {
+		t.Fatal("the now impure leaf was not re-grown")
+	}
+	if acc, err := regrown.Score(after); err != nil || acc != 1 {
+		t.Fatalf("accuracy on shifted data = %v, %v; want 1", acc, err)
+	}
+	if err := regrown.Validate(); err != nil {
+		t.Fatalf("refit model is invalid: %v", err)
+	}
+	if regrown.Metadata.NumSamples != len(after) || len(regrown.Metadata.Classes) != 3 {
+		t.Fatalf("metadata not updated: %+v", regrown.Metadata)
+	}
+
+	if _, err := model.RefitLeaves(after, RefitOptions{Impurity: -1}); err == nil {
+		t.Fatal("expected an error for a negative Impurity")
+	}
+}
+
+func TestRefitLeaves_RegrowsWithModelConfig(t *testing.T) {
+	// An ordinal split compared as text would put "large" below "medium".
+	levels := []string{"small", "medium", "large"}
+	var before, after TrainingSet
+	for i := 0; i < 8; i++ {
+		for _, size := range levels {
+			label := "big"
+			if size == "small" {
+				label = "little"
+			}
+			before = append(before, TrainingItem{"size": size, "label": label})
+			if size == "medium" {
+				label = "mid"
+			}
+			after = append(after, TrainingItem{"size": size, "label": label})
+		}
+	}
+	model, err := Train(before, Config{CategoryAttr: "label", OrdinalFeatures: map[string][]string{"size": levels}})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	refit, err := model.RefitLeaves(after, RefitOptions{Impurity: 0.1})
+	if err != nil {
+		t.Fatalf("RefitLeaves failed: %v", err)
+	}
+	if sameStructure(model.Root, refit.Root) {
+		t.Fatal("the mixed big leaf was not re-grown")
+	}
+	if acc, err := refit.Score(after); err != nil || acc != 1 {
+		t.Fatalf("accuracy on the refit data = %v, %v; want 1", acc, err)
+	}
+	if !reflect.DeepEqual(refit.Config, model.Config) {
+		t.Fatalf("refit config = %+v, want the model's %+v", refit.Config, model.Config)
+	}
+}
@@ -1,6 +1,11 @@
 package dtree
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+)
 
 // calculateProba is a helper to compute probabilities from a class counts map.
 func calculateProba(counts map[string]int) map[string]float64 {
@@ -18,157 +23,511 @@ func calculateProba(counts map[string]int) map[string]float64 {
 	return out
 }
 
+// smoothProba applies Laplace (additive) smoothing with alpha over classes:
+// p(c) = (count(c) + alpha) / (total + alpha*len(classes)).
+func smoothProba(counts map[string]int, classes []string, alpha float64) map[string]float64 {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	denom := float64(total) + alpha*float64(len(classes))
+	out := make(map[string]float64, len(classes))
+	for _, c := range classes {
+		out[c] = (float64(counts[c]) + alpha) / denom
+	}
+	return out
+}
+
 // Predict returns the hard class prediction for an item.
 // Returns an error if the model is invalid or prediction fails.
 func (m *Model) Predict(item TrainingItem) (string, error) {
-	if m == nil {
+	node, err := m.findNode(item)
+	if err != nil {
+		return "", err
+	}
+	return m.predictAt(node), nil
+}
+
+// predictAt is the class predicted by routing that ended at node, falling
+// back to Config.DefaultClass when node gives no class.
+func (m *Model) predictAt(node *TreeItem) string {
+	var class string
+	switch {
+	case m.Config.LeafStrategy == LeafProportional:
+		class = m.drawClass(node.ClassCounts)
+	case node.isLeaf():
+		class = node.Category
+	default:
+		// The path was blocked; predict using the node's majority class.
+		class = mostFrequentValue(node.ClassCounts)
+	}
+	if m.Config.DefaultClass != "" && (class == "" || len(node.ClassCounts) == 0) {
+		return m.Config.DefaultClass
+	}
+	return class
+}
+
+// drawClass picks a class with probability proportional to counts, from
+// the model's LeafProportional generator.
+func (m *Model) drawClass(counts map[string]int) string {
+	classes := make([]string, 0, len(counts))
+	total := 0
+	for c, n := range counts {
+		if n > 0 {
+			classes = append(classes, c)
+			total += n
+		}
+	}
+	if total == 0 {
+		return mostFrequentValue(counts)
+	}
+	sort.Strings(classes)
+	m.drawMu.Lock()
+	if m.drawRng == nil {
+		m.drawRng = rand.New(rand.NewSource(m.Config.Seed))
+	}
+	r := m.drawRng.Intn(total)
+	m.drawMu.Unlock()
+	for _, c := range classes {
+		if r -= counts[c]; r < 0 {
+			return c
+		}
+	}
+	return classes[len(classes)-1]
+}
+
+// PredictMulti predicts every label of a multi-output model (see
+// Config.CategoryAttrs), keyed by label attribute. Each label is the majority
+// of its OutputCounts at the reached node, except CategoryAttr, which is
+// predicted as by Predict. Single-output models return their Predict result
+// under CategoryAttr.
+func (m *Model) PredictMulti(item TrainingItem) (map[string]string, error) {
+	if m == nil || len(m.Config.CategoryAttrs) == 0 {
+		pred, err := m.Predict(item)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{m.Config.CategoryAttr: pred}, nil
+	}
+	node, err := m.findNode(item)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(m.Config.CategoryAttrs))
+	for _, attr := range m.Config.CategoryAttrs {
+		out[attr] = mostFrequentValue(node.OutputCounts[attr])
+	}
+	out[m.Config.CategoryAttr] = m.predictAt(node)
+	return out, nil
+}
+
+// PredictProba returns class probabilities at the reached leaf.
+// With Config.LaplaceAlpha > 0 the result is smoothed and covers every class
+// in Classes(); otherwise it covers only the leaf's classes.
+// Returns an error if the model is invalid or prediction fails.
+func (m *Model) PredictProba(item TrainingItem) (map[string]float64, error) {
+	node, err := m.findNode(item)
+	if err != nil {
+		return nil, err
+	}
+	return m.probaAt(node), nil
+}
+
+// probaAt is PredictProba for routing that ended at node.
+func (m *Model) probaAt(node *TreeItem) map[string]float64 {
+	if m.Config.LaplaceAlpha > 0 {
+		return smoothProba(node.ClassCounts, m.classUniverse(), m.Config.LaplaceAlpha)
+	}
+	return calculateProba(node.ClassCounts)
+}
+
+// PredictProbaFull is PredictProba with a probability for every class in
+// Classes(), so all results share the same keys. Classes absent from the
+// reached leaf get 0, or their smoothed value with Config.LaplaceAlpha > 0.
+func (m *Model) PredictProbaFull(item TrainingItem) (map[string]float64, error) {
+	proba, err := m.PredictProba(item)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range m.classUniverse() {
+		if _, ok := proba[c]; !ok {
+			proba[c] = 0
+		}
+	}
+	return proba, nil
+}
+
+// PredictProbaOrdered is PredictProbaFull as a slice with one entry per
+// class in Classes() order, so serialized results list classes in the same
+// order every time.
+func (m *Model) PredictProbaOrdered(item TrainingItem) ([]ClassProba, error) {
+	node, err := m.findNode(item)
+	if err != nil {
+		return nil, err
+	}
+	return m.orderedProba(m.probaAt(node)), nil
+}
+
+// orderedProba lists proba in Classes() order, with 0 for absent classes.
+func (m *Model) orderedProba(proba map[string]float64) []ClassProba {
+	classes := m.classUniverse()
+	out := make([]ClassProba, len(classes))
+	for i, c := range classes {
+		out[i] = ClassProba{Class: c, Proba: proba[c]}
+	}
+	return out
+}
+
+// PredictWithThreshold classifies item with a binary model, returning
+// positiveClass when its PredictProba probability is at least threshold and
+// the other class otherwise. Lower thresholds trade precision for recall.
+func (m *Model) PredictWithThreshold(item TrainingItem, positiveClass string, threshold float64) (string, error) {
+	if !(threshold >= 0 && threshold <= 1) {
+		return "", errors.New("threshold must be between 0 and 1")
+	}
+	if m == nil || m.Root == nil {
 		return "", errors.New("model is nil")
 	}
-	if m.Root == nil {
-		return "", errors.New("model has nil root node")
+	classes := m.classUniverse()
+	if len(classes) != 2 {
+		return "", fmt.Errorf("threshold prediction requires a binary model, found %d classes", len(classes))
+	}
+	negative := classes[0]
+	switch positiveClass {
+	case classes[0]:
+		negative = classes[1]
+	case classes[1]:
+	default:
+		return "", fmt.Errorf("unknown class %q", positiveClass)
 	}
-	if item == nil {
-		return "", errors.New("item cannot be nil")
+	proba, err := m.PredictProba(item)
+	if err != nil {
+		return "", err
 	}
+	if proba[positiveClass] >= threshold {
+		return positiveClass, nil
+	}
+	return negative, nil
+}
 
-	node := m.Root
-	for node != nil {
-		// Leaf detection should be structural only; labels may be empty strings.
-		if node.Match == nil && node.NoMatch == nil {
-			return node.Category, nil
+// PredictLeaf is like Predict but also returns the ID of the node that
+// answered: the reached leaf, or the internal node where routing stopped.
+// IDs are 0 unless assigned by Train or AssignIDs.
+func (m *Model) PredictLeaf(item TrainingItem) (int, string, error) {
+	node, err := m.findNode(item)
+	if err != nil {
+		return 0, "", err
+	}
+	return node.ID, m.predictAt(node), nil
+}
+
+// AssignIDs numbers every node from 1 in a deterministic pre-order walk
+// (match before noMatch, multiway children by value). Train assigns IDs
+// automatically; call this after building or editing a tree by hand. IDs are
+// saved with the model, so they are stable across reloads.
+func (m *Model) AssignIDs() {
+	if m == nil {
+		return
+	}
+	next := 0
+	var walk func(n *TreeItem)
+	walk = func(n *TreeItem) {
+		if n == nil {
+			return
+		}
+		next++
+		n.ID = next
+		for _, b := range n.branches() {
+			walk(b.node)
 		}
+	}
+	walk(m.Root)
+}
 
-		// Decide which child to visit next.
-		var nextNode *TreeItem
-		val, ok := item[node.Attribute]
+// Classes returns the sorted set of classes the model can predict: the
+// training labels recorded in Metadata, or for models saved without them,
+// the union of the classes in the leaves. Leaves left empty by training
+// contribute nothing. The result is computed once and cached; the returned
+// slice is a copy.
+func (m *Model) Classes() []string {
+	if m == nil {
+		return nil
+	}
+	return cloneStrings(m.classUniverse())
+}
 
-		if !ok { // attribute truly missing
-			if node.MatchedCount >= node.NoMatchedCount {
-				nextNode = node.Match
-			} else {
-				nextNode = node.NoMatch
+// classUniverse is Classes without the copy, for internal callers.
+func (m *Model) classUniverse() []string {
+	m.classesOnce.Do(func() {
+		if m.Metadata != nil && len(m.Metadata.Classes) > 0 {
+			m.classes = cloneStrings(m.Metadata.Classes)
+			sort.Strings(m.classes)
+			return
+		}
+		seen := make(map[string]bool)
+		var walk func(n *TreeItem)
+		walk = func(n *TreeItem) {
+			if n == nil {
+				return
 			}
-		} else {
-			// Attribute present; handle comparator specifics.
-			var goMatch bool
-			if node.PredicateName == ">=" {
-				// For numeric comparator, treat nil value as missing.
-				if val == nil {
-					if node.MatchedCount >= node.NoMatchedCount {
-						nextNode = node.Match
-					} else {
-						nextNode = node.NoMatch
-					}
-				} else {
-					goMatch = predicateGte(toComparable(val), node.Pivot)
-					if goMatch {
-						nextNode = node.Match
-					} else {
-						nextNode = node.NoMatch
-					}
+			if n.isLeaf() {
+				for c := range n.ClassCounts {
+					seen[c] = true
 				}
-			} else { // equality comparator "=="
-				// Evaluate equality even if val == nil so that nil==nil can match.
-				goMatch = predicateEq(val, node.Pivot)
-				if goMatch {
-					nextNode = node.Match
-				} else {
-					nextNode = node.NoMatch
+				if n.Category != "" {
+					seen[n.Category] = true
 				}
+				return
 			}
+			for _, b := range n.branches() {
+				walk(b.node)
+			}
+		}
+		walk(m.Root)
+		for c := range seen {
+			m.classes = append(m.classes, c)
 		}
+		sort.Strings(m.classes)
+	})
+	return m.classes
+}
 
-		// If the next step is a dead end, predict using the current node's majority class.
-		if nextNode == nil {
-			return mostFrequentValue(node.ClassCounts), nil
+// ClassPriors returns the class distribution of the training data, summing
+// to 1: the root's ClassCounts, which hold every training sample, or for
+// models saved without root counts the sum of the leaves' counts. It
+// returns nil for a nil model, a nil root or a tree without counts.
+func (m *Model) ClassPriors() map[string]float64 {
+	if m == nil || m.Root == nil {
+		return nil
+	}
+	counts := m.Root.ClassCounts
+	if len(counts) == 0 {
+		counts = make(map[string]int)
+		var walk func(n *TreeItem)
+		walk = func(n *TreeItem) {
+			if n.isLeaf() {
+				for c, k := range n.ClassCounts {
+					counts[c] += k
+				}
+				return
+			}
+			for _, b := range n.branches() {
+				walk(b.node)
+			}
 		}
-		node = nextNode
+		walk(m.Root)
+	}
+	if countTotal(counts) == 0 {
+		return nil
 	}
+	return calculateProba(counts)
+}
 
-	// Should never reach here if model is valid
-	return "", errors.New("reached end of tree without finding leaf node")
+// attrSource supplies attribute values to tree routing: a TrainingItem, or
+// a positional row of a PreparedModel.
+type attrSource interface {
+	// lookup returns the value of attr and whether attr is present.
+	lookup(attr string) (interface{}, bool)
 }
 
-// PredictProba returns class probabilities at the reached leaf.
-// Returns an error if the model is invalid or prediction fails.
-func (m *Model) PredictProba(item TrainingItem) (map[string]float64, error) {
+func (item TrainingItem) lookup(attr string) (interface{}, bool) {
+	v, ok := item[attr]
+	return v, ok
+}
+
+// findNode routes item down the tree and returns the node that answers the
+// prediction: the reached leaf, or the last internal node when the next child
+// is missing (a dead end).
+func (m *Model) findNode(item TrainingItem) (*TreeItem, error) {
+	if item == nil && m != nil && m.Root != nil {
+		return nil, errors.New("item cannot be nil")
+	}
+	return m.route(item)
+}
+
+// maxRouteSteps caps how many nodes a prediction visits, so a hand-built
+// tree with a cycle fails instead of looping forever. Loaded models cannot
+// get near it: encoding/json stops at 10000 levels of nesting, two per node.
+const maxRouteSteps = 10000
+
+// route is findNode for any attrSource.
+func (m *Model) route(item attrSource) (*TreeItem, error) {
 	if m == nil {
 		return nil, errors.New("model is nil")
 	}
 	if m.Root == nil {
 		return nil, errors.New("model has nil root node")
 	}
-	if item == nil {
-		return nil, errors.New("item cannot be nil")
-	}
 
 	node := m.Root
-	for node != nil {
-		// Leaf detection should be structural only.
-		if node.Match == nil && node.NoMatch == nil {
-			return calculateProba(node.ClassCounts), nil
+	for steps := 0; ; steps++ {
+		if node.isLeaf() {
+			return node, nil
 		}
+		if steps == maxRouteSteps {
+			return nil, fmt.Errorf("prediction visited %d nodes without reaching a leaf; the tree may contain a cycle", maxRouteSteps)
+		}
+		next, err := m.nextNode(node, item)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return node, nil
+		}
+		node = next
+	}
+}
 
-		// Decide which child to visit next.
-		var nextNode *TreeItem
-		val, ok := item[node.Attribute]
-
-		if !ok { // attribute truly missing
-			if node.MatchedCount >= node.NoMatchedCount {
-				nextNode = node.Match
-			} else {
-				nextNode = node.NoMatch
-			}
-		} else {
-			// Attribute present; handle comparator specifics.
-			var goMatch bool
-			if node.PredicateName == ">=" {
-				if val == nil {
-					if node.MatchedCount >= node.NoMatchedCount {
-						nextNode = node.Match
-					} else {
-						nextNode = node.NoMatch
-					}
-				} else {
-					goMatch = predicateGte(toComparable(val), node.Pivot)
-					if goMatch {
-						nextNode = node.Match
-					} else {
-						nextNode = node.NoMatch
-					}
-				}
-			} else { // equality comparator
-				goMatch = predicateEq(val, node.Pivot)
-				if goMatch {
-					nextNode = node.Match
-				} else {
-					nextNode = node.NoMatch
-				}
+// nextNode decides which child of an internal node the item should visit.
+// A nil result with no error means the item cannot go further.
+func (m *Model) nextNode(node *TreeItem, item attrSource) (*TreeItem, error) {
+	if node.Oblique != nil {
+		s, attr, ok := node.Oblique.score(item)
+		if !ok {
+			if _, present := item.lookup(attr); !present && m.Config.StrictPredict {
+				return nil, fmt.Errorf("item is missing attribute %q required by the model", attr)
 			}
+			return m.missingChild(node, attr)
+		}
+		if s >= node.Oblique.Threshold {
+			return node.Match, nil
+		}
+		return node.NoMatch, nil
+	}
+
+	val, ok := item.lookup(node.Attribute)
+	if !ok { // attribute truly missing
+		if m.Config.StrictPredict {
+			return nil, fmt.Errorf("item is missing attribute %q required by the model", node.Attribute)
+		}
+		return m.missingChild(node, node.Attribute)
+	}
+	if nonFinite(val) { // NaN and infinities count as missing
+		return m.missingChild(node, node.Attribute)
+	}
+
+	// Multiway node: follow the child for this value. Unseen values stop here
+	// so the node's majority class answers; nil values are missing.
+	if len(node.Children) > 0 {
+		if val == nil {
+			return m.missingChild(node, node.Attribute)
+		}
+		return node.Children[valueKey(val)], nil
+	}
+
+	// Attribute present; handle comparator specifics.
+	if levels, ok := m.Config.OrdinalFeatures[node.Attribute]; ok && node.PredicateName == ">=" {
+		i := ordinalIndex(levels, val)
+		if i < 0 {
+			return m.missingChild(node, node.Attribute)
+		}
+		if i >= ordinalIndex(levels, node.Pivot) {
+			return node.Match, nil
+		}
+		return node.NoMatch, nil
+	}
+	if node.PredicateName == ">=" && stringInSlice(node.Attribute, m.Config.LexicographicFeatures) {
+		if _, ok := val.(string); !ok {
+			return m.missingChild(node, node.Attribute)
 		}
+		if predicateGte(val, node.Pivot) {
+			return node.Match, nil
+		}
+		return node.NoMatch, nil
+	}
+	if node.PredicateName == ">=" {
+		// For numeric comparator, treat nil value as missing.
+		if val == nil {
+			return m.missingChild(node, node.Attribute)
+		}
+		if predicateGte(toComparable(val), toComparable(node.Pivot)) {
+			return node.Match, nil
+		}
+		return node.NoMatch, nil
+	}
 
-		// If the next step is a dead end, predict using the current node's probabilities.
-		if nextNode == nil {
-			return calculateProba(node.ClassCounts), nil
+	// A nil value is missing, unless the split tests for nil itself.
+	if val == nil && node.Pivot != nil && node.PredicateName == "==" {
+		return m.missingChild(node, node.Attribute)
+	}
+
+	// A categorical value the node never saw in training says nothing about
+	// either branch; treat it as missing.
+	if node.Categories != nil && val != nil && !isNumeric(val) && !predicateIn(val, node.Categories) {
+		return m.missingChild(node, node.Attribute)
+	}
+
+	// Subset split: values outside the group, missing ones included, go to
+	// NoMatch as in training.
+	if node.PredicateName == "subset" {
+		if predicateIn(val, node.PivotSet) {
+			return node.Match, nil
 		}
-		node = nextNode
+		return node.NoMatch, nil
+	}
+
+	// Equality comparator "==": a nil pivot matches nil values.
+	if predicateEq(val, node.Pivot) {
+		return node.Match, nil
+	}
+	return node.NoMatch, nil
+}
+
+// missingChild picks the child for an item with no usable value for attr at
+// node, according to Config.MissingStrategy.
+// Multiway nodes follow their Missing child, which training grew from the
+// items lacking a value; without one, every strategy other than MissingFail
+// stops at the node and predicts its majority class.
+func (m *Model) missingChild(node *TreeItem, attr string) (*TreeItem, error) {
+	if len(node.Children) > 0 && m.Config.MissingStrategy != MissingFail {
+		return node.Missing, nil
+	}
+	switch m.Config.MissingStrategy {
+	case MissingMatch:
+		return node.Match, nil
+	case MissingNoMatch:
+		return node.NoMatch, nil
+	case MissingFail:
+		return nil, fmt.Errorf("item has no value for attribute %q", attr)
+	default:
+		return majorityChild(node), nil
 	}
+}
 
-	// Should never reach here if model is valid
-	return nil, errors.New("reached end of tree without finding leaf node")
+// majorityChild returns the child that received more training samples.
+func majorityChild(node *TreeItem) *TreeItem {
+	if node.MatchedCount >= node.NoMatchedCount {
+		return node.Match
+	}
+	return node.NoMatch
 }
 
 // PredictBatch predicts classes for multiple items.
 // Returns predictions and an error if any prediction fails.
 // On error, returns partial results up to the point of failure.
 func (m *Model) PredictBatch(items []TrainingItem) ([]string, error) {
+	return m.PredictBatchProgress(items, nil)
+}
+
+// PredictBatchProgress is PredictBatch with progress reporting: cb, if not
+// nil, is called with the number of items predicted so far and the total,
+// about every 1% of the input and once more when all items are done. It is
+// not called for an empty input or for an item whose prediction fails.
+func (m *Model) PredictBatchProgress(items []TrainingItem, cb func(done, total int)) ([]string, error) {
 	out := make([]string, len(items))
+	step := len(items) / 100
+	if step == 0 {
+		step = 1
+	}
 	for i, it := range items {
 		pred, err := m.Predict(it)
 		if err != nil {
 			return out[:i], err
 		}
 		out[i] = pred
+		if done := i + 1; cb != nil && (done%step == 0 || done == len(items)) {
+			cb(done, len(items))
+		}
 	}
 	return out, nil
 }
@@ -188,6 +547,68 @@ func (m *Model) PredictProbaBatch(items []TrainingItem) ([]map[string]float64, e
 	return out, nil
 }
 
+// CheckFeatures compares item's keys with the features the model was trained
+// on and returns one message per discrepancy: `missing feature "x"` for each
+// training feature absent from item, then `unexpected feature "y"` for each key
+// not seen in training. The label attribute is never reported. The training
+// features come from Metadata.FeatureNames, or for models without metadata,
+// from the attributes the tree splits on. An empty result means no drift.
+func (m *Model) CheckFeatures(item TrainingItem) []string {
+	if m == nil {
+		return nil
+	}
+	var known []string
+	if m.Metadata != nil && len(m.Metadata.FeatureNames) > 0 {
+		known = m.Metadata.FeatureNames
+	} else {
+		known = treeAttributes(m.Root)
+	}
+
+	var issues []string
+	knownSet := make(map[string]bool, len(known))
+	for _, f := range known {
+		knownSet[f] = true
+		if _, ok := item[f]; !ok {
+			issues = append(issues, fmt.Sprintf("missing feature %q", f))
+		}
+	}
+	var extra []string
+	for k := range item {
+		if !m.Config.isLabel(k) && k != m.Config.WeightAttr && !knownSet[k] {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	for _, k := range extra {
+		issues = append(issues, fmt.Sprintf("unexpected feature %q", k))
+	}
+	return issues
+}
+
+// treeAttributes returns the sorted set of attributes the tree splits on.
+func treeAttributes(root *TreeItem) []string {
+	seen := make(map[string]bool)
+	var walk func(n *TreeItem)
+	walk = func(n *TreeItem) {
+		if n == nil || n.isLeaf() {
+			return
+		}
+		for _, a := range n.attributes() {
+			seen[a] = true
+		}
+		for _, b := range n.branches() {
+			walk(b.node)
+		}
+	}
+	walk(root)
+	attrs := make([]string, 0, len(seen))
+	for a := range seen {
+		attrs = append(attrs, a)
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
 // normalize numeric values to float64 for comparison
 func toComparable(v interface{}) interface{} {
 	if isNumeric(v) {
@@ -0,0 +1,126 @@
+package dtree
+
+import "fmt"
+
+// PreparedModel predicts from positional rows of raw CSV text, such as the
+// records of a csv.Reader, without building a TrainingItem per row. Only the
+// cells the tree visits are parsed, as they are reached, so wide rows cost
+// little more than narrow ones. Cells are parsed as ReadCSVFunc would parse
+// them, so PredictRow(rec) equals Predict of the item ReadCSVFunc builds from
+// rec. A PreparedModel is safe for concurrent use.
+type PreparedModel struct {
+	model *Model
+	// index maps attributes to their column; a repeated header name maps to
+	// its last column, as in ReadCSVFunc items.
+	index map[string]int
+	width int
+	na    map[string]bool
+	types map[string]string
+}
+
+// Prepare returns a PreparedModel for rows with the given column header,
+// parsing cells with the default CSVOptions.
+func (m *Model) Prepare(header []string) *PreparedModel {
+	p, _ := m.PrepareCSV(header, CSVOptions{})
+	return p
+}
+
+// PrepareCSV is Prepare with the NA values and column Types of opts, which
+// should match the options the rows would be read with. It fails on an
+// unknown column type.
+func (m *Model) PrepareCSV(header []string, opts CSVOptions) (*PreparedModel, error) {
+	for col, typ := range opts.Types {
+		if !validColumnType(typ) {
+			return nil, fmt.Errorf("column %q has unknown type %q", col, typ)
+		}
+	}
+	p := &PreparedModel{
+		model: m,
+		index: make(map[string]int, len(header)),
+		width: len(header),
+		na:    make(map[string]bool, len(opts.NA)),
+		types: opts.Types,
+	}
+	for i, h := range header {
+		p.index[h] = i
+	}
+	for _, tok := range opts.NA {
+		p.na[tok] = true
+	}
+	return p, nil
+}
+
+// PredictRow predicts the class of one row, given as cell text in header
+// order.
+func (p *PreparedModel) PredictRow(values []string) (string, error) {
+	node, err := p.route(values)
+	if err != nil {
+		return "", err
+	}
+	return p.model.predictAt(node), nil
+}
+
+// PredictProbaRow is PredictProba for one row, given as cell text in header
+// order.
+func (p *PreparedModel) PredictProbaRow(values []string) (map[string]float64, error) {
+	node, err := p.route(values)
+	if err != nil {
+		return nil, err
+	}
+	return p.model.probaAt(node), nil
+}
+
+// PredictProbaOrderedRow is PredictProbaOrdered for one row, given as cell
+// text in header order.
+func (p *PreparedModel) PredictProbaOrderedRow(values []string) ([]ClassProba, error) {
+	node, err := p.route(values)
+	if err != nil {
+		return nil, err
+	}
+	return p.model.orderedProba(p.model.probaAt(node)), nil
+}
+
+func (p *PreparedModel) route(values []string) (*TreeItem, error) {
+	if len(values) != p.width {
+		return nil, fmt.Errorf("row has %d values but header has %d", len(values), p.width)
+	}
+	row := &preparedRow{p: p, values: values}
+	node, err := p.model.route(row)
+	if row.err != nil {
+		return nil, row.err
+	}
+	return node, err
+}
+
+// preparedRow is the attrSource of one PredictRow call. A cell that fails
+// to convert to its column type is recorded in err and reads as missing.
+type preparedRow struct {
+	p      *PreparedModel
+	values []string
+	err    error
+}
+
+func (r *preparedRow) lookup(attr string) (interface{}, bool) {
+	i, ok := r.p.index[attr]
+	if !ok {
+		return nil, false
+	}
+	s := r.values[i]
+	typ, typed := r.p.types[attr]
+	switch {
+	case typed && typ == ColumnIgnore:
+		return nil, false
+	case !typed:
+		return parseCSVValue(s, r.p.na), true
+	case s == "" || r.p.na[s]:
+		return nil, true
+	}
+	v, err := coerceValue(s, typ)
+	if err != nil {
+		if r.err == nil {
+			r.err = fmt.Errorf("column %q: %w", attr, err)
+		}
+		return nil, true
+	}
+	return v, true
+}
@@ -0,0 +1,166 @@
+package dtree
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// wideRows renders syntheticSet(n) as CSV records with pad extra unused
+// columns, blanking some x cells so missing values are exercised.
+func wideRows(n, pad int) ([]string, [][]string) {
+	header := []string{"x", "y", "color", "label"}
+	for j := 0; j < pad; j++ {
+		header = append(header, "pad"+strconv.Itoa(j))
+	}
+	var rows [][]string
+	for i, item := range syntheticSet(n) {
+		rec := []string{
+			strconv.FormatFloat(item["x"].(float64), 'f', -1, 64),
+			strconv.FormatFloat(item["y"].(float64), 'f', -1, 64),
+			item["color"].(string),
+			item["label"].(string),
+		}
+		if i%13 == 0 {
+			rec[0] = ""
+		}
+		for j := 0; j < pad; j++ {
+			rec = append(rec, strconv.Itoa(i*j))
+		}
+		rows = append(rows, rec)
+	}
+	return header, rows
+}
+
+// rowItem builds the item ReadCSVFunc would produce for rec.
+func rowItem(t testing.TB, header, rec []string, opts CSVOptions) TrainingItem {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write(header)
+	w.Write(rec)
+	w.Flush()
+	set, err := ReadCSVStream(strings.NewReader(b.String()), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set[0]
+}
+
+func TestPreparedModel_MatchesPredict(t *testing.T) {
+	header, rows := wideRows(300, 5)
+	for _, cfg := range []Config{
+		{CategoryAttr: "label"},
+		{CategoryAttr: "label", MultiwaySplits: true, MissingStrategy: MissingNoMatch},
+		{CategoryAttr: "label", LaplaceAlpha: 1},
+	} {
+		var set TrainingSet
+		for _, rec := range rows {
+			set = append(set, rowItem(t, header, rec, CSVOptions{}))
+		}
+		model, err := Train(set, cfg)
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		p := model.Prepare(header)
+		for i, rec := range rows {
+			item := set[i]
+			want, _ := model.Predict(item)
+			got, err := p.PredictRow(rec)
+			if err != nil || got != want {
+				t.Fatalf("row %d: PredictRow = %q, %v; want %q", i, got, err, want)
+			}
+			wantP, _ := model.PredictProba(item)
+			gotP, err := p.PredictProbaRow(rec)
+			if err != nil || fmt.Sprint(gotP) != fmt.Sprint(wantP) {
+				t.Fatalf("row %d: PredictProbaRow = %v, %v; want %v", i, gotP, err, wantP)
+			}
+		}
+	}
+}
+
+func TestPreparedModel_CSVOptions(t *testing.T) {
+	header := []string{"zip", "label"}
+	opts := CSVOptions{NA: []string{"NA"}, Types: map[string]string{"zip": ColumnString}}
+	var set TrainingSet
+	for _, zip := range []string{"02134", "10001", "02134", "10001"} {
+		label := "west"
+		if zip == "02134" {
+			label = "east"
+		}
+		set = append(set, TrainingItem{"zip": zip, "label": label})
+	}
+	model, err := Train(set, Config{CategoryAttr: "label", MissingStrategy: MissingFail})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	p, err := model.PrepareCSV(header, opts)
+	if err != nil {
+		t.Fatalf("PrepareCSV failed: %v", err)
+	}
+	if got, err := p.PredictRow([]string{"02134", ""}); err != nil || got != "east" {
+		t.Errorf("PredictRow = %q, %v; want east", got, err)
+	}
+	// An NA cell is a nil value, which the fail strategy rejects in both.
+	if _, err := model.Predict(rowItem(t, header, []string{"NA", ""}, opts)); err == nil {
+		t.Error("Predict of an NA cell: expected the fail strategy's error")
+	}
+	if got, err := p.PredictRow([]string{"NA", ""}); err == nil {
+		t.Errorf("PredictRow of an NA cell = %q; expected the fail strategy's error", got)
+	}
+	if _, err := p.PredictRow([]string{"02134"}); err == nil {
+		t.Error("expected error for a short row")
+	}
+	if _, err := model.PrepareCSV(header, CSVOptions{Types: map[string]string{"zip": "date"}}); err == nil {
+		t.Error("expected error for an unknown type")
+	}
+
+	typed, _ := model.PrepareCSV([]string{"zip"}, CSVOptions{Types: map[string]string{"zip": ColumnNumber}})
+	if _, err := typed.PredictRow([]string{"abc"}); err == nil || !strings.Contains(err.Error(), `column "zip"`) {
+		t.Errorf("expected a conversion error, got %v", err)
+	}
+}
+
+func benchmarkWideModel(b *testing.B) (*Model, []string, [][]string) {
+	header, rows := wideRows(500, 200)
+	var set TrainingSet
+	for _, rec := range rows {
+		set = append(set, rowItem(b, header, rec, CSVOptions{}))
+	}
+	model, err := Train(set, Config{CategoryAttr: "label", IgnoredAttributes: header[4:]})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return model, header, rows
+}
+
+// BenchmarkPredict_WideMap builds a map per row, as ReadCSVFunc does,
+// before predicting.
+func BenchmarkPredict_WideMap(b *testing.B) {
+	model, header, rows := benchmarkWideModel(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rec := rows[i%len(rows)]
+		item := make(TrainingItem, len(header))
+		for j, h := range header {
+			item[h] = parseCSVValue(rec[j], nil)
+		}
+		if _, err := model.Predict(item); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPredict_WidePrepared(b *testing.B) {
+	model, header, rows := benchmarkWideModel(b)
+	p := model.Prepare(header)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.PredictRow(rows[i%len(rows)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
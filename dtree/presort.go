@@ -0,0 +1,60 @@
+package dtree
+
+import "sort"
+
+// numericGain is the score of one "attr >= pivot" split found by
+// numericGains: its impurity decrease and how many items match.
+type numericGain struct {
+	gain   float64
+	nMatch int
+}
+
+// presorts reports whether findSplit may score numeric pivots with
+// numericGains. Weighted, multi-output and monotone-constrained training
+// need the partitioned items of every candidate and use the exhaustive scan.
+func (b *builder) presorts() bool {
+	return !b.exhaustive && b.cfg.WeightAttr == "" && len(b.cfg.CategoryAttrs) == 0 && len(b.cfg.MonotoneConstraints) == 0
+}
+
+// numericGains scores every "attr >= pivot" split of set at once, keyed by
+// pivot. The finite numeric values are sorted once, in descending order,
+// and swept while keeping running class counts for each side, so every
+// pivot costs O(classes) instead of a pass over set. Items without a finite
+// number for attr never match, as with predicateGte. Gains are computed as
+// findSplit computes them for a partitioned split.
+func (b *builder) numericGains(set TrainingSet, attr string, initImpurity, size float64) map[float64]numericGain {
+	type point struct {
+		v     float64
+		class string
+	}
+	label := b.cfg.CategoryAttr
+	pts := make([]point, 0, len(set))
+	noMatch := make(map[string]int)
+	for _, item := range set {
+		class := valueKey(item[label])
+		noMatch[class]++
+		if v := item[attr]; finiteNumber(v) {
+			pts = append(pts, point{toFloat(v), class})
+		}
+	}
+	sort.Slice(pts, func(i, j int) bool { return pts[i].v > pts[j].v })
+
+	out := make(map[float64]numericGain)
+	match := make(map[string]int)
+	n := len(set)
+	for i := 0; i < len(pts); {
+		// Move every item equal to the pivot across before scoring it.
+		v := pts[i].v
+		for ; i < len(pts) && pts[i].v == v; i++ {
+			c := pts[i].class
+			match[c]++
+			if noMatch[c]--; noMatch[c] == 0 {
+				delete(noMatch, c)
+			}
+		}
+		matchN, noMatchN := float64(i), float64(n-i)
+		newI := (b.impurity(match, i)*matchN + b.impurity(noMatch, n-i)*noMatchN) / size
+		out[v] = numericGain{gain: initImpurity - newI, nMatch: i}
+	}
+	return out
+}
@@ -0,0 +1,111 @@
+package dtree
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// numericSet has n items with several numeric features, a few of them
+// integer-valued so pivots repeat, and a three-class label.
+func numericSet(n int, seed int64) TrainingSet {
+	rng := rand.New(rand.NewSource(seed))
+	set := make(TrainingSet, n)
+	for i := range set {
+		item := TrainingItem{}
+		for f := 0; f < 8; f++ {
+			v := rng.NormFloat64()
+			if f%3 == 0 {
+				v = float64(rng.Intn(20))
+			}
+			item[fmt.Sprintf("f%d", f)] = v
+		}
+		s := item["f0"].(float64)/10 + item["f1"].(float64) - item["f2"].(float64)
+		switch {
+		case s > 1:
+			item["label"] = "high"
+		case s > 0:
+			item["label"] = "mid"
+		default:
+			item["label"] = "low"
+		}
+		if rng.Float64() < 0.1 {
+			item["f4"] = nil
+		}
+		set[i] = item
+	}
+	return set
+}
+
+// exhaustiveTree grows a tree with presorted search turned off.
+func exhaustiveTree(t *testing.T, set TrainingSet, cfg Config) *TreeItem {
+	t.Helper()
+	b, err := newTrainingBuilder(context.Background(), set, cfg, false)
+	if err != nil {
+		t.Fatalf("builder failed: %v", err)
+	}
+	b.exhaustive = true
+	return b.makeTrainingTree(set, 0, fullBounds)
+}
+
+// sameSplits fails unless a and b make the same splits with the same counts.
+func sameSplits(t *testing.T, path string, a, b *TreeItem) {
+	t.Helper()
+	if (a == nil) != (b == nil) {
+		t.Fatalf("%s: one tree has a node the other lacks", path)
+	}
+	if a == nil {
+		return
+	}
+	if a.Attribute != b.Attribute || a.PredicateName != b.PredicateName || a.Pivot != b.Pivot ||
+		a.Category != b.Category || !reflect.DeepEqual(a.ClassCounts, b.ClassCounts) {
+		t.Fatalf("%s: presorted split %q (%v), exhaustive split %q (%v)", path, a.condition(), a.ClassCounts, b.condition(), b.ClassCounts)
+	}
+	sameSplits(t, path+"/yes", a.Match, b.Match)
+	sameSplits(t, path+"/no", a.NoMatch, b.NoMatch)
+}
+
+func TestPresortedSplits_MatchExhaustive(t *testing.T) {
+	cases := []struct {
+		name string
+		set  TrainingSet
+		cfg  Config
+	}{
+		{"diagonal", diagonalSet(400, 2), Config{CategoryAttr: "label"}},
+		{"diagonal min leaf", diagonalSet(400, 3), Config{CategoryAttr: "label", MinSamplesLeaf: 7}},
+		{"mixed", syntheticSet(600), Config{CategoryAttr: "label", TieBreak: TieBreakDeterministic}},
+		{"numeric", numericSet(500, 4), Config{CategoryAttr: "label", TieBreak: TieBreakDeterministic}},
+		{"numeric gini", numericSet(500, 5), Config{CategoryAttr: "label", Criterion: CriterionGini, TieBreak: TieBreakDeterministic}},
+		{"numeric thresholds", numericSet(500, 6), Config{CategoryAttr: "label", MaxThresholds: 16, TieBreak: TieBreakDeterministic}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			model, err := Train(tc.set, tc.cfg)
+			if err != nil {
+				t.Fatalf("training failed: %v", err)
+			}
+			if model.Root.isLeaf() {
+				t.Fatal("tree has no splits; the test proves nothing")
+			}
+			sameSplits(t, "root", model.Root, exhaustiveTree(t, tc.set, tc.cfg))
+		})
+	}
+}
+
+func BenchmarkTrain_Presorted(b *testing.B) {
+	set := numericSet(3000, 1)
+	for _, exhaustive := range []bool{false, true} {
+		b.Run(fmt.Sprintf("exhaustive=%v", exhaustive), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tb, err := newTrainingBuilder(context.Background(), set, Config{CategoryAttr: "label", MaxDepth: 8}, false)
+				if err != nil {
+					b.Fatal(err)
+				}
+				tb.exhaustive = exhaustive
+				tb.makeTrainingTree(set, 0, fullBounds)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package dtree
+
+// randomSplittable reports whether attr takes a random threshold under
+// Config.RandomSplits: it is not ordinal or lexicographic, whose values
+// are not numbers, nor histogram-binned.
+func (b *builder) randomSplittable(attr string) bool {
+	if _, ok := b.cfg.OrdinalFeatures[attr]; ok {
+		return false
+	}
+	return b.hist[attr] == nil && !stringInSlice(attr, b.cfg.LexicographicFeatures)
+}
+
+// evalRandomThreshold scores one "attr >= t" split of set, with t drawn
+// uniformly from (min, max] of the finite numeric values of attr so both
+// sides are non-empty. It returns false when attr has fewer than two
+// distinct values here or the split is not usable.
+func (b *builder) evalRandomThreshold(set TrainingSet, attr string, initImpurity, size float64, bnd bounds) (splitResult, bool) {
+	lo, hi, seen := 0.0, 0.0, false
+	for _, item := range set {
+		v := item[attr]
+		if !finiteNumber(v) {
+			continue
+		}
+		f := toFloat(v)
+		if !seen || f < lo {
+			lo = f
+		}
+		if !seen || f > hi {
+			hi = f
+		}
+		seen = true
+	}
+	if !seen || lo == hi {
+		return splitResult{}, false
+	}
+	if b.cfg.Observer != nil {
+		b.cfg.Observer.OnSplitEvaluated(attr)
+	}
+	pivot := hi - b.rng.Float64()*(hi-lo)
+
+	curr := splitCounted(set, attr, b.cfg.CategoryAttr, predicateGte, pivot)
+	if !b.usablePartition(len(curr.Match), len(set)) {
+		return splitResult{}, false
+	}
+	matchI, matchN := b.sideImpurity(curr.Match, curr.MatchCounts)
+	noMatchI, noMatchN := b.sideImpurity(curr.NoMatch, curr.NoMatchCounts)
+	curr.Gain = initImpurity - (matchI*matchN+noMatchI*noMatchN)/size
+	curr.Attribute = attr
+	curr.Pivot = pivot
+	pred := Predicate(predicateGte)
+	curr.Predicate = &pred
+	curr.PredicateName = ">="
+	if len(b.cfg.MonotoneConstraints) > 0 {
+		if _, _, ok := b.monotoneChildBounds(curr, bnd); !ok {
+			return splitResult{}, false
+		}
+	}
+	return curr, true
+}
@@ -0,0 +1,55 @@
+package dtree
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestTrain_RandomSplits(t *testing.T) {
+	set := playTennisSet()
+	exact := &countingObserver{}
+	if _, err := Train(set, Config{CategoryAttr: "Play", Observer: exact}); err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	random := &countingObserver{}
+	cfg := Config{CategoryAttr: "Play", RandomSplits: true, Seed: 3, Observer: random}
+	model, err := Train(set, cfg)
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+
+	// One threshold per numeric attribute and node instead of one per value.
+	for _, attr := range []string{"Temperature", "Humidity"} {
+		if random.splits[attr] > random.nodes || random.splits[attr] >= exact.splits[attr] {
+			t.Errorf("%s: %d random candidates over %d nodes, %d exact", attr, random.splits[attr], random.nodes, exact.splits[attr])
+		}
+	}
+	if err := model.Validate(); err != nil {
+		t.Fatalf("random-split model is invalid: %v", err)
+	}
+	if acc, err := model.Score(set); err != nil || acc != 1 {
+		t.Fatalf("training accuracy = %v, %v; want 1", acc, err)
+	}
+
+	cfg.Observer = nil
+	a, _ := Train(set, cfg)
+	b, _ := Train(set, cfg)
+	if !reflect.DeepEqual(a.Root, b.Root) {
+		t.Fatal("same seed grew different trees")
+	}
+}
+
+func BenchmarkTrain_RandomSplits(b *testing.B) {
+	set := numericSet(3000, 1)
+	for _, random := range []bool{false, true} {
+		cfg := Config{CategoryAttr: "label", MaxDepth: 8, RandomSplits: random, Seed: 1}
+		b.Run(fmt.Sprintf("random=%v", random), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := Train(set, cfg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
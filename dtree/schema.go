@@ -0,0 +1,111 @@
+package dtree
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// schemaEnums lists the allowed values of string fields, keyed by
+// "Type.Field".
+var schemaEnums = map[string][]string{
+	"TreeItem.PredicateName": {"==", ">=", "in", "subset", "oblique"},
+	"Config.Criterion":       {CriterionEntropy, CriterionGini},
+	"Config.MissingStrategy": {MissingMajority, MissingMatch, MissingNoMatch, MissingFail},
+	"Config.TieBreak":        {TieBreakDeterministic, TieBreakRandom},
+	"Config.LeafStrategy":    {LeafMajority, LeafProportional},
+}
+
+// ModelJSONSchema returns a JSON Schema (draft 2020-12) document describing
+// the model files written by SaveJSON, for linting hand-built or generated
+// models before loading them. It is derived from the JSON tags of Model and
+// the types it contains, so it follows the struct definitions: fields
+// without omitempty are required and unknown fields are rejected. The schema
+// checks the shape of a model only; Validate still checks that the tree is
+// consistent.
+func ModelJSONSchema() string {
+	g := &schemaGen{defs: make(map[string]interface{})}
+	schema := map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "dtree model",
+		"$ref":    g.ref(reflect.TypeOf(Model{})),
+		"$defs":   g.defs,
+	}
+	out, _ := json.MarshalIndent(schema, "", "  ")
+	return string(out)
+}
+
+// schemaGen collects one definition per struct type under "$defs".
+type schemaGen struct {
+	defs map[string]interface{}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ref returns the reference to the definition of struct type t, adding the
+// definition first if needed.
+func (g *schemaGen) ref(t reflect.Type) string {
+	if _, ok := g.defs[t.Name()]; !ok {
+		g.defs[t.Name()] = nil // reserve the name, for recursive types
+		g.defs[t.Name()] = g.object(t)
+	}
+	return "#/$defs/" + t.Name()
+}
+
+func (g *schemaGen) object(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		prop := g.typeSchema(f.Type)
+		if enum, ok := schemaEnums[t.Name()+"."+f.Name]; ok {
+			prop["enum"] = enum
+		}
+		props[name] = prop
+		if opts != "omitempty" {
+			required = append(required, name)
+		}
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func (g *schemaGen) typeSchema(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.typeSchema(t.Elem())
+	case reflect.Struct:
+		return map[string]interface{}{"$ref": g.ref(t)}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.typeSchema(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	// Pivots are interface{}: a category, number or boolean.
+	return map[string]interface{}{"type": []string{"string", "number", "boolean"}}
+}
@@ -0,0 +1,167 @@
+package dtree
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// schemaValidator checks a decoded JSON document against the subset of JSON
+// Schema that ModelJSONSchema emits: $ref into $defs, type, enum, properties,
+// required, additionalProperties and items.
+type schemaValidator struct {
+	defs map[string]interface{}
+	errs []string
+}
+
+func (v *schemaValidator) validate(schema map[string]interface{}, doc interface{}, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		def, ok := v.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+		if !ok {
+			v.errs = append(v.errs, path+": unresolved $ref "+ref)
+			return
+		}
+		v.validate(def, doc, path)
+	}
+	if t, ok := schema["type"]; ok && !schemaTypeMatches(t, doc) {
+		v.errs = append(v.errs, path+": wrong type")
+		return
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || e == doc
+		}
+		if !found {
+			v.errs = append(v.errs, path+": value not in enum")
+		}
+	}
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		if req, ok := schema["required"].([]interface{}); ok {
+			for _, r := range req {
+				if _, ok := d[r.(string)]; !ok {
+					v.errs = append(v.errs, path+": missing required "+r.(string))
+				}
+			}
+		}
+		for k, val := range d {
+			if p, ok := props[k].(map[string]interface{}); ok {
+				v.validate(p, val, path+"."+k)
+				continue
+			}
+			switch ap := schema["additionalProperties"].(type) {
+			case bool:
+				if !ap {
+					v.errs = append(v.errs, path+": unexpected property "+k)
+				}
+			case map[string]interface{}:
+				v.validate(ap, val, path+"."+k)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for _, e := range d {
+				v.validate(items, e, path+"[]")
+			}
+		}
+	}
+}
+
+func schemaTypeMatches(t interface{}, doc interface{}) bool {
+	if list, ok := t.([]interface{}); ok {
+		for _, e := range list {
+			if schemaTypeMatches(e, doc) {
+				return true
+			}
+		}
+		return false
+	}
+	switch t {
+	case "object":
+		_, ok := doc.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := doc.([]interface{})
+		return ok
+	case "string":
+		_, ok := doc.(string)
+		return ok
+	case "boolean":
+		_, ok := doc.(bool)
+		return ok
+	case "number":
+		_, ok := doc.(float64)
+		return ok
+	case "integer":
+		f, ok := doc.(float64)
+		return ok && f == math.Trunc(f)
+	}
+	return false
+}
+
+// validateAgainstSchema decodes data and returns the schema violations.
+func validateAgainstSchema(t *testing.T, data []byte) []string {
+	t.Helper()
+	var schema, doc map[string]interface{}
+	if err := json.Unmarshal([]byte(ModelJSONSchema()), &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("model is not valid JSON: %v", err)
+	}
+	v := &schemaValidator{defs: schema["$defs"].(map[string]interface{})}
+	v.validate(schema, doc, "$")
+	return v.errs
+}
+
+func TestModelJSONSchema_ValidatesSavedModels(t *testing.T) {
+	for _, cfg := range []Config{
+		{CategoryAttr: "Play"},
+		{CategoryAttr: "Play", MultiwaySplits: true, Criterion: CriterionGini, MissingStrategy: MissingMatch},
+		{CategoryAttr: "Play", OrdinalFeatures: map[string][]string{"Outlook": {"overcast", "rain", "sunny"}}},
+	} {
+		model, err := Train(playTennisSet(), cfg)
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "model.json")
+		if err := model.SaveJSON(path); err != nil {
+			t.Fatalf("SaveJSON failed: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if errs := validateAgainstSchema(t, data); len(errs) > 0 {
+			t.Errorf("saved model does not match the schema: %v", errs)
+		}
+	}
+
+	oblique, err := Train(cleanDiagonalSet(200, 1), Config{CategoryAttr: "label", AllowObliqueSplits: true})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	data, _ := json.Marshal(oblique)
+	if errs := validateAgainstSchema(t, data); len(errs) > 0 {
+		t.Errorf("oblique model does not match the schema: %v", errs)
+	}
+}
+
+func TestModelJSONSchema_RejectsBadModels(t *testing.T) {
+	for name, doc := range map[string]string{
+		"missing root":      `{"config": {"categoryAttr": "y"}}`,
+		"bad predicate":     `{"root": {"attribute": "x", "predicateName": "<", "pivot": 1, "match": {"category": "a"}, "noMatch": {"category": "b"}}, "config": {"categoryAttr": "y"}}`,
+		"unknown field":     `{"root": {"category": "a", "colour": "red"}, "config": {"categoryAttr": "y"}}`,
+		"non-integer count": `{"root": {"category": "a", "classCounts": {"a": 1.5}}, "config": {"categoryAttr": "y"}}`,
+		"bad criterion":     `{"root": {"category": "a"}, "config": {"categoryAttr": "y", "criterion": "mse"}}`,
+	} {
+		if errs := validateAgainstSchema(t, []byte(doc)); len(errs) == 0 {
+			t.Errorf("%s: expected schema violations", name)
+		}
+	}
+}
@@ -1,22 +1,67 @@
 package dtree
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
 	"encoding/json"
 	"errors"
 	"io"
+	"math"
 	"os"
+	"strings"
 )
 
-// SaveJSON writes the model to a JSON file.
+// gzipMagic is the two-byte header that starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// SaveJSON writes the model to a JSON file, gzip-compressed when the path
+// ends in ".gz" (e.g. "model.json.gz").
 func (m *Model) SaveJSON(path string) error {
+	return writeJSONFile(path, m)
+}
+
+// writeJSONFile encodes v as indented JSON into path, compressing it when the
+// path ends in ".gz".
+func writeJSONFile(path string, v interface{}) error {
 	f, err := os.Create(path)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	enc := json.NewEncoder(f)
+	if strings.HasSuffix(path, ".gz") {
+		zw := gzip.NewWriter(f)
+		if err = encodeIndented(zw, v); err == nil {
+			err = zw.Close()
+		}
+	} else {
+		err = encodeIndented(f, v)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func encodeIndented(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
 	enc.SetIndent("", "  ")
-	return enc.Encode(m)
+	return enc.Encode(v)
+}
+
+// MaybeGunzip returns a reader that decompresses r if it starts with the
+// gzip magic bytes, and otherwise reads r unchanged.
+func MaybeGunzip(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(head, gzipMagic) {
+		return gzip.NewReader(br)
+	}
+	return br, nil
 }
 
 // LoadJSON reads a model from a JSON file and validates it.
@@ -29,8 +74,13 @@ func LoadJSON(path string) (*Model, error) {
 	return DecodeJSON(f)
 }
 
-// DecodeJSON decodes a model from any reader and validates it.
+// DecodeJSON decodes a model from any reader and validates it. Gzip-compressed
+// input is detected from its magic bytes and decompressed first.
 func DecodeJSON(r io.Reader) (*Model, error) {
+	r, err := MaybeGunzip(r)
+	if err != nil {
+		return nil, err
+	}
 	dec := json.NewDecoder(r)
 	var m Model
 	if err := dec.Decode(&m); err != nil {
@@ -45,6 +95,43 @@ func DecodeJSON(r io.Reader) (*Model, error) {
 	return &m, nil
 }
 
+// SaveGob writes the model to a file in Go's gob encoding, which is more
+// compact and faster to load than JSON but readable only from Go. Pivots keep
+// their exact Go types.
+func (m *Model) SaveGob(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadGob reads a model written by SaveGob and validates it.
+func LoadGob(path string) (*Model, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return DecodeGob(f)
+}
+
+// DecodeGob decodes a gob-encoded model from any reader and validates it.
+func DecodeGob(r io.Reader) (*Model, error) {
+	var m Model
+	if err := gob.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
 // Validate checks if the model is structurally sound and ready for use.
 // Returns an error if the model has invalid configuration or tree structure.
 func (m *Model) Validate() error {
@@ -61,6 +148,10 @@ func (m *Model) Validate() error {
 		return errors.New("model config missing categoryAttr")
 	}
 
+	if len(m.Config.CategoryAttrs) > 0 && m.Config.CategoryAttrs[0] != m.Config.CategoryAttr {
+		return errors.New("model config has invalid categoryAttrs")
+	}
+
 	if m.Config.MaxDepth < 0 {
 		return errors.New("model config has negative maxDepth")
 	}
@@ -69,7 +160,51 @@ func (m *Model) Validate() error {
 		return errors.New("model config has negative minSamples")
 	}
 
-	// Validate tree structure
+	if m.Config.MaxCategories < 0 {
+		return errors.New("model config has negative maxCategories")
+	}
+
+	if !validCriterion(m.Config.Criterion) {
+		return errors.New("model config has invalid criterion")
+	}
+
+	if !validMissingStrategy(m.Config.MissingStrategy) {
+		return errors.New("model config has invalid missingStrategy")
+	}
+
+	if !validLeafStrategy(m.Config.LeafStrategy) {
+		return errors.New("model config has invalid leafStrategy")
+	}
+
+	if !validTieBreak(m.Config.TieBreak) {
+		return errors.New("model config has invalid tieBreak")
+	}
+
+	if !validMonotone(m.Config.MonotoneConstraints) {
+		return errors.New("model config has invalid monotoneConstraints")
+	}
+
+	if !validOrdinal(m.Config.OrdinalFeatures) {
+		return errors.New("model config has invalid ordinalFeatures")
+	}
+
+	if !validLexicographic(m.Config.LexicographicFeatures, m.Config.OrdinalFeatures) {
+		return errors.New("model config has invalid lexicographicFeatures")
+	}
+
+	if m.Config.LaplaceAlpha < 0 || math.IsNaN(m.Config.LaplaceAlpha) {
+		return errors.New("model config has negative laplaceAlpha")
+	}
+
+	if !(m.Config.ChiSquarePValue >= 0 && m.Config.ChiSquarePValue <= 1) {
+		return errors.New("model config has invalid chiSquarePValue")
+	}
+
+	// Validate tree structure; a cycle would send validateNode, and every
+	// other walk of the tree, around it forever.
+	if findCycle(m.Root, make(map[*TreeItem]bool)) {
+		return errors.New("tree contains a cycle")
+	}
 	if err := validateNode(m.Root); err != nil {
 		return err
 	}
@@ -77,16 +212,36 @@ func (m *Model) Validate() error {
 	return nil
 }
 
+// findCycle reports whether a node below node, or node itself, links back
+// to a node on the path from the root, which onPath holds.
+func findCycle(node *TreeItem, onPath map[*TreeItem]bool) bool {
+	if node == nil {
+		return false
+	}
+	if onPath[node] {
+		return true
+	}
+	onPath[node] = true
+	for _, b := range node.branches() {
+		if findCycle(b.node, onPath) {
+			return true
+		}
+	}
+	delete(onPath, node)
+	return false
+}
+
 // validateNode recursively checks if a tree node is valid.
 func validateNode(node *TreeItem) error {
 	if node == nil {
 		return nil // nil nodes are allowed as children
 	}
+	if node.Missing != nil && len(node.Children) == 0 {
+		return errors.New("node has a missing child but no multiway children")
+	}
 
 	// Check if it's a leaf node
-	isLeaf := node.Match == nil && node.NoMatch == nil
-
-	if isLeaf {
+	if node.isLeaf() {
 		// Leaf nodes must have class counts
 		if node.ClassCounts == nil {
 			return errors.New("leaf node missing classCounts")
@@ -95,13 +250,27 @@ func validateNode(node *TreeItem) error {
 		return nil
 	}
 
+	// Gain is optional (older models lack it) but never negative or NaN.
+	if node.Gain < 0 || math.IsNaN(node.Gain) {
+		return errors.New("internal node has invalid gain")
+	}
+
+	if len(node.Children) > 0 {
+		return validateMultiwayNode(node)
+	}
+	if node.Oblique != nil {
+		if err := validateObliqueSplit(node); err != nil {
+			return err
+		}
+	}
+
 	// Internal nodes must have both children
 	if node.Match == nil || node.NoMatch == nil {
 		return errors.New("internal node missing one or both children")
 	}
 
 	// Internal nodes must have split metadata
-	if node.Attribute == "" {
+	if node.Attribute == "" && node.Oblique == nil {
 		return errors.New("internal node missing attribute")
 	}
 
@@ -110,8 +279,20 @@ func validateNode(node *TreeItem) error {
 	}
 
 	// Validate predicate name
-	if node.PredicateName != "==" && node.PredicateName != ">=" {
-		return errors.New("internal node has invalid predicateName (must be == or >=)")
+	if node.Oblique != nil {
+		if node.PredicateName != "oblique" {
+			return errors.New("oblique node has invalid predicateName (must be oblique)")
+		}
+	} else if node.PredicateName == "subset" {
+		if err := validatePivotSet(node.PivotSet); err != nil {
+			return err
+		}
+	} else if node.PredicateName != "==" && node.PredicateName != ">=" {
+		return errors.New("internal node has invalid predicateName (must be ==, >= or subset)")
+	}
+
+	if !sortedUnique(node.Categories) {
+		return errors.New("internal node categories must be sorted without duplicates")
 	}
 
 	// Internal nodes should have class counts for fallback prediction
@@ -130,3 +311,79 @@ func validateNode(node *TreeItem) error {
 
 	return nil
 }
+
+// validatePivotSet checks the value group of a subset node: non-empty,
+// sorted and without duplicates, as predicateIn searches it.
+func validatePivotSet(set []string) error {
+	if len(set) == 0 {
+		return errors.New("subset node has an empty pivotSet")
+	}
+	for i := 1; i < len(set); i++ {
+		if set[i-1] >= set[i] {
+			return errors.New("subset node pivotSet must be sorted without duplicates")
+		}
+	}
+	return nil
+}
+
+// sortedUnique reports whether set is sorted without duplicates, as
+// predicateIn needs to search node categories.
+func sortedUnique(set []string) bool {
+	for i := 1; i < len(set); i++ {
+		if set[i-1] >= set[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// validateObliqueSplit checks the linear combination of an oblique node.
+func validateObliqueSplit(node *TreeItem) error {
+	o := node.Oblique
+	if len(o.Attributes) == 0 || len(o.Attributes) != len(o.Weights) {
+		return errors.New("oblique node must have one weight per attribute")
+	}
+	for _, a := range o.Attributes {
+		if a == "" {
+			return errors.New("oblique node has an empty attribute")
+		}
+	}
+	finite := func(f float64) bool { return !math.IsNaN(f) && !math.IsInf(f, 0) }
+	if !finite(o.Threshold) {
+		return errors.New("oblique node has a non-finite threshold")
+	}
+	for _, w := range o.Weights {
+		if !finite(w) {
+			return errors.New("oblique node has a non-finite weight")
+		}
+	}
+	return nil
+}
+
+// validateMultiwayNode checks a node that branches through Children.
+func validateMultiwayNode(node *TreeItem) error {
+	if node.Match != nil || node.NoMatch != nil {
+		return errors.New("multiway node cannot also have match/noMatch children")
+	}
+
+	if node.Attribute == "" {
+		return errors.New("internal node missing attribute")
+	}
+	if node.PredicateName != "in" {
+		return errors.New("multiway node has invalid predicateName (must be in)")
+	}
+	if node.ClassCounts == nil {
+		return errors.New("internal node missing classCounts")
+	}
+	for _, child := range node.Children {
+		if child == nil {
+			return errors.New("multiway node has nil child")
+		}
+	}
+	for _, b := range node.branches() {
+		if err := validateNode(b.node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,7 +2,11 @@ package dtree
 
 import (
 	"bytes"
+	"encoding/json"
+	"math"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -96,6 +100,25 @@ func TestValidate_NegativeMinSamples(t *testing.T) {
 	}
 }
 
+func TestValidate_InvalidChiSquarePValue(t *testing.T) {
+	for _, p := range []float64{-0.5, 1.5, math.NaN()} {
+		m := &Model{
+			Root: &TreeItem{
+				Category:    "yes",
+				ClassCounts: map[string]int{"yes": 1},
+			},
+			Config: Config{CategoryAttr: "label", ChiSquarePValue: p},
+		}
+		err := m.Validate()
+		if err == nil {
+			t.Fatalf("expected error for chiSquarePValue %v", p)
+		}
+		if err.Error() != "model config has invalid chiSquarePValue" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
 func TestValidate_LeafMissingClassCounts(t *testing.T) {
 	m := &Model{
 		Root: &TreeItem{
@@ -137,6 +160,36 @@ func TestValidate_InternalNodeMissingChild(t *testing.T) {
 	}
 }
 
+func TestValidate_Cycle(t *testing.T) {
+	leaf := &TreeItem{Category: "no", ClassCounts: map[string]int{"no": 1}}
+	root := &TreeItem{
+		Attribute:     "feature",
+		PredicateName: "==",
+		Pivot:         "a",
+		ClassCounts:   map[string]int{"yes": 1, "no": 1},
+		NoMatch:       leaf,
+	}
+	root.Match = root // hand-edited back edge
+	m := &Model{Root: root, Config: Config{CategoryAttr: "label"}}
+
+	if err := m.Validate(); err == nil || err.Error() != "tree contains a cycle" {
+		t.Fatalf("Validate error = %v, want the cycle reported", err)
+	}
+	_, err := m.Predict(TrainingItem{"feature": "a"})
+	if err == nil || !strings.Contains(err.Error(), "may contain a cycle") {
+		t.Fatalf("Predict error = %v, want the step cap reported", err)
+	}
+	if got, err := m.Predict(TrainingItem{"feature": "b"}); err != nil || got != "no" {
+		t.Fatalf("an item off the cycle predicted %q, %v; want no", got, err)
+	}
+
+	// A node shared by two branches is not a cycle.
+	root.Match = leaf
+	if err := m.Validate(); err != nil {
+		t.Fatalf("shared leaf should validate: %v", err)
+	}
+}
+
 func TestValidate_InternalNodeMissingAttribute(t *testing.T) {
 	m := &Model{
 		Root: &TreeItem{
@@ -329,6 +382,70 @@ func TestLoadJSON_RejectsInvalidModel(t *testing.T) {
 	}
 }
 
+func TestValidate_InvalidMissingStrategy(t *testing.T) {
+	m := skewedModel("sideways")
+	err := m.Validate()
+	if err == nil {
+		t.Fatal("expected error for invalid missing strategy")
+	}
+	if err.Error() != "model config has invalid missingStrategy" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDecodeJSON_PreservesMissingStrategy(t *testing.T) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(skewedModel(MissingMatch)); err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	loaded, err := DecodeJSON(&buf)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if loaded.Config.MissingStrategy != MissingMatch {
+		t.Fatalf("missingStrategy not preserved: %q", loaded.Config.MissingStrategy)
+	}
+	if pred, _ := loaded.Predict(TrainingItem{}); pred != "hi" {
+		t.Fatalf("reloaded model should route missing values to match, got %q", pred)
+	}
+}
+
+func TestPredict_IntegerPivot(t *testing.T) {
+	for _, pivot := range []interface{}{75, int32(75), int64(75), float32(75)} {
+		model := &Model{
+			Config: Config{CategoryAttr: "Play"},
+			Root: &TreeItem{
+				Attribute: "Humidity", PredicateName: ">=", Pivot: pivot,
+				ClassCounts: map[string]int{"no": 1, "yes": 1},
+				Match:       &TreeItem{Category: "no", ClassCounts: map[string]int{"no": 1}},
+				NoMatch:     &TreeItem{Category: "yes", ClassCounts: map[string]int{"yes": 1}},
+			},
+		}
+		if err := model.Validate(); err != nil {
+			t.Fatalf("pivot %T: %v", pivot, err)
+		}
+		for value, want := range map[interface{}]string{80.0: "no", 75: "no", int64(70): "yes", 74.5: "yes"} {
+			if got, err := model.Predict(TrainingItem{"Humidity": value}); err != nil || got != want {
+				t.Errorf("pivot %T: Predict(%v) = %q, %v; want %q", pivot, value, got, err, want)
+			}
+		}
+	}
+
+	// An externally written model with an integral pivot loads and predicts.
+	doc := `{"root": {"attribute": "Humidity", "predicateName": ">=", "pivot": 75,
+		"classCounts": {"no": 1, "yes": 1},
+		"match": {"category": "no", "classCounts": {"no": 1}},
+		"noMatch": {"category": "yes", "classCounts": {"yes": 1}}},
+		"config": {"categoryAttr": "Play"}}`
+	loaded, err := DecodeJSON(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("DecodeJSON failed: %v", err)
+	}
+	if got, err := loaded.Predict(TrainingItem{"Humidity": 90}); err != nil || got != "no" {
+		t.Errorf("Predict = %q, %v; want no", got, err)
+	}
+}
+
 func TestSaveJSON_RoundTrip(t *testing.T) {
 	// Create a model
 	ts := TrainingSet{
@@ -375,3 +492,127 @@ func TestSaveJSON_RoundTrip(t *testing.T) {
 		t.Errorf("predictions differ: original=%s, loaded=%s", pred1, pred2)
 	}
 }
+
+func TestSaveJSON_MetadataRoundTrip(t *testing.T) {
+	original, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	meta := original.Metadata
+	if meta == nil {
+		t.Fatal("Train should populate metadata")
+	}
+	if got := strings.Join(meta.FeatureNames, ","); got != "Humidity,Outlook,Temperature,Wind" {
+		t.Fatalf("unexpected feature names: %s", got)
+	}
+	if meta.NumSamples != 7 || meta.LibVersion != Version || meta.TrainedAt.IsZero() {
+		t.Fatalf("unexpected metadata: %+v", meta)
+	}
+
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := original.SaveJSON(path); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	loaded, err := LoadJSON(path)
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	got := loaded.Metadata
+	if got == nil {
+		t.Fatal("metadata lost on reload")
+	}
+	if !reflect.DeepEqual(got.FeatureNames, meta.FeatureNames) || got.NumSamples != meta.NumSamples ||
+		got.LibVersion != meta.LibVersion || !got.TrainedAt.Equal(meta.TrainedAt) {
+		t.Fatalf("metadata changed on reload: %+v vs %+v", got, meta)
+	}
+}
+
+func TestDecodeJSON_WithoutMetadata(t *testing.T) {
+	// Models saved before metadata existed must still load.
+	legacy := `{"root": {"category": "yes", "classCounts": {"yes": 2}}, "config": {"categoryAttr": "label"}}`
+	m, err := DecodeJSON(strings.NewReader(legacy))
+	if err != nil {
+		t.Fatalf("legacy model failed to load: %v", err)
+	}
+	if m.Metadata != nil {
+		t.Fatalf("expected nil metadata, got %+v", m.Metadata)
+	}
+}
+
+func TestSaveJSON_GzipRoundTrip(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	dir := t.TempDir()
+	gzPath := filepath.Join(dir, "model.json.gz")
+	if err := model.SaveJSON(gzPath); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	raw, err := os.ReadFile(gzPath)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if !bytes.HasPrefix(raw, gzipMagic) {
+		t.Fatal("expected .json.gz file to be gzip-compressed")
+	}
+
+	// A gzipped file loads regardless of its extension.
+	plainName := filepath.Join(dir, "model.json")
+	if err := os.WriteFile(plainName, raw, 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	for _, path := range []string{gzPath, plainName} {
+		loaded, err := LoadJSON(path)
+		if err != nil {
+			t.Fatalf("load %s failed: %v", path, err)
+		}
+		for _, item := range playTennisSet() {
+			want, _ := model.Predict(item)
+			got, _ := loaded.Predict(item)
+			if got != want {
+				t.Fatalf("%s: predicted %q, want %q", path, got, want)
+			}
+		}
+	}
+}
+
+func TestLoadJSON_GzipValidates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.json.gz")
+	bad := &Model{Root: &TreeItem{Category: "x", ClassCounts: map[string]int{"x": 1}}}
+	if err := bad.SaveJSON(path); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if _, err := LoadJSON(path); err == nil || !strings.Contains(err.Error(), "categoryAttr") {
+		t.Fatalf("expected validation error after decompression, got %v", err)
+	}
+}
+
+func TestSaveGob_RoundTrip(t *testing.T) {
+	for _, cfg := range []Config{
+		{CategoryAttr: "Play"},
+		{CategoryAttr: "Play", MultiwaySplits: true},
+	} {
+		model, err := Train(playTennisSet(), cfg)
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "model.gob")
+		if err := model.SaveGob(path); err != nil {
+			t.Fatalf("save failed: %v", err)
+		}
+		loaded, err := LoadGob(path)
+		if err != nil {
+			t.Fatalf("load failed: %v", err)
+		}
+		if !model.Equal(loaded) {
+			t.Fatalf("gob round trip changed the model: %v", model.Diff(loaded))
+		}
+		if !loaded.Metadata.TrainedAt.Equal(model.Metadata.TrainedAt) {
+			t.Errorf("TrainedAt changed from %v to %v", model.Metadata.TrainedAt, loaded.Metadata.TrainedAt)
+		}
+	}
+	if _, err := DecodeGob(strings.NewReader("not gob")); err == nil {
+		t.Error("expected error decoding garbage")
+	}
+}
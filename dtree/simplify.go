@@ -0,0 +1,91 @@
+package dtree
+
+import "strings"
+
+// Simplify returns a copy of m in which every internal node whose whole
+// subtree always yields the same prediction is collapsed into a single leaf.
+// The leaf keeps the node's ID and carries the summed ClassCounts (and
+// OutputCounts) of the leaves it replaces, so PredictProba reports the
+// combined distribution. Predict, and PredictMulti for multi-output models,
+// return the same result as m for every input. A multiway node only
+// collapses if its own majority, which answers unseen values, agrees too.
+//
+// A removed split can no longer reject an item: with StrictPredict or
+// MissingFail, items lacking its attribute are predicted instead of failing.
+// m is left unchanged.
+func (m *Model) Simplify() *Model {
+	if m == nil {
+		return nil
+	}
+	cp := m.Clone()
+	if cp.Root != nil {
+		cp.simplifyNode(cp.Root)
+	}
+	return cp
+}
+
+// simplifyNode collapses constant subtrees below and at n, bottom up.
+func (m *Model) simplifyNode(n *TreeItem) {
+	if n.isLeaf() {
+		return
+	}
+	branches := n.branches()
+	if len(branches) == 0 {
+		return
+	}
+	for _, b := range branches {
+		m.simplifyNode(b.node)
+	}
+
+	// Routing can stop at multiway nodes (unseen values) and at nodes with a
+	// missing child, where the node's own majority answers.
+	var want string
+	if len(n.Children) > 0 || n.Match == nil || n.NoMatch == nil {
+		want = m.nodeSignature(n)
+	} else {
+		want = m.nodeSignature(branches[0].node)
+	}
+	for _, b := range branches {
+		if !b.node.isLeaf() || m.nodeSignature(b.node) != want {
+			return
+		}
+	}
+
+	counts := make(map[string]int)
+	var outputs map[string]map[string]int
+	for _, b := range branches {
+		for c, k := range b.node.ClassCounts {
+			counts[c] += k
+		}
+		for attr, oc := range b.node.OutputCounts {
+			if outputs == nil {
+				outputs = make(map[string]map[string]int)
+			}
+			if outputs[attr] == nil {
+				outputs[attr] = make(map[string]int)
+			}
+			for c, k := range oc {
+				outputs[attr][c] += k
+			}
+		}
+	}
+	category := branches[0].node.Category
+	*n = TreeItem{ID: n.ID, Category: category, ClassCounts: counts, OutputCounts: outputs}
+}
+
+// nodeSignature encodes what prediction routing ends at n returns: the
+// Predict result followed, for multi-output models, by the other labels.
+func (m *Model) nodeSignature(n *TreeItem) string {
+	parts := make([]string, 0, 1+len(m.Config.CategoryAttrs))
+	if n.isLeaf() {
+		parts = append(parts, n.Category)
+	} else {
+		parts = append(parts, mostFrequentValue(n.ClassCounts))
+	}
+	for _, attr := range m.Config.CategoryAttrs {
+		if attr != m.Config.CategoryAttr {
+			parts = append(parts, mostFrequentValue(n.OutputCounts[attr]))
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
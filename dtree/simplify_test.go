@@ -0,0 +1,96 @@
+package dtree
+
+import "testing"
+
+// redundantModel splits on Humidity below a root split although both of its
+// leaves predict "yes".
+func redundantModel() *Model {
+	m := &Model{
+		Config: Config{CategoryAttr: "Play"},
+		Root: &TreeItem{
+			Attribute: "Outlook", PredicateName: "==", Pivot: "sunny",
+			ClassCounts: map[string]int{"yes": 5, "no": 3}, MatchedCount: 3, NoMatchedCount: 5,
+			Match: &TreeItem{Category: "no", ClassCounts: map[string]int{"no": 3}},
+			NoMatch: &TreeItem{
+				Attribute: "Humidity", PredicateName: ">=", Pivot: 80.0,
+				ClassCounts: map[string]int{"yes": 5}, MatchedCount: 2, NoMatchedCount: 3,
+				Match:   &TreeItem{Category: "yes", ClassCounts: map[string]int{"yes": 2}},
+				NoMatch: &TreeItem{Category: "yes", ClassCounts: map[string]int{"yes": 3}},
+			},
+		},
+	}
+	m.AssignIDs()
+	return m
+}
+
+func TestSimplify_CollapsesConstantSubtrees(t *testing.T) {
+	m := redundantModel()
+	simple := m.Simplify()
+	if err := simple.Validate(); err != nil {
+		t.Fatalf("simplified model is invalid: %v", err)
+	}
+	if got, want := simple.Stats().TotalNodes, m.Stats().TotalNodes-2; got != want {
+		t.Errorf("TotalNodes = %d, want %d", got, want)
+	}
+	leaf := simple.Root.NoMatch
+	if !leaf.isLeaf() || leaf.Category != "yes" || leaf.ClassCounts["yes"] != 5 || leaf.ID != m.Root.NoMatch.ID {
+		t.Errorf("collapsed node = %+v, want leaf yes with 5 samples and the original ID", leaf)
+	}
+	if m.Stats().TotalNodes != 5 {
+		t.Error("Simplify modified the original model")
+	}
+
+	for _, outlook := range []string{"sunny", "rain", "overcast"} {
+		for _, humidity := range []float64{60, 80, 95} {
+			item := TrainingItem{"Outlook": outlook, "Humidity": humidity}
+			want, _ := m.Predict(item)
+			got, err := simple.Predict(item)
+			if err != nil || got != want {
+				t.Errorf("Predict(%v) = %q, %v; want %q", item, got, err, want)
+			}
+		}
+	}
+}
+
+func TestSimplify_TrainedModelKeepsPredictions(t *testing.T) {
+	set := diagonalSet(300, 3)
+	m, err := Train(set, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	simple := m.Simplify()
+	if err := simple.Validate(); err != nil {
+		t.Fatalf("simplified model is invalid: %v", err)
+	}
+	if simple.Stats().TotalNodes > m.Stats().TotalNodes {
+		t.Error("Simplify added nodes")
+	}
+	for _, item := range diagonalSet(500, 4) {
+		want, _ := m.Predict(item)
+		if got, _ := simple.Predict(item); got != want {
+			t.Fatalf("Predict(%v) = %q, want %q", item, got, want)
+		}
+	}
+}
+
+func TestSimplify_KeepsMultiwayWithDifferentMajority(t *testing.T) {
+	m := &Model{
+		Config: Config{CategoryAttr: "Play", MultiwaySplits: true},
+		Root: &TreeItem{
+			Attribute: "Outlook", PredicateName: "in",
+			ClassCounts: map[string]int{"no": 4, "yes": 2},
+			Children: map[string]*TreeItem{
+				"rain":  {Category: "yes", ClassCounts: map[string]int{"yes": 1, "no": 2}},
+				"sunny": {Category: "yes", ClassCounts: map[string]int{"yes": 1, "no": 2}},
+			},
+		},
+	}
+	// Unseen values predict the root's majority, "no", so the split matters.
+	if simple := m.Simplify(); simple.Root.isLeaf() {
+		t.Error("multiway node whose majority differs from its children was collapsed")
+	}
+	m.Root.ClassCounts = map[string]int{"yes": 4}
+	if simple := m.Simplify(); !simple.Root.isLeaf() {
+		t.Error("constant multiway node was not collapsed")
+	}
+}
@@ -0,0 +1,137 @@
+package dtree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// sklearnUndefined marks the feature and threshold of a leaf, as
+// scikit-learn's TREE_UNDEFINED does.
+const sklearnUndefined = -2
+
+// sklearnTree mirrors the arrays of a fitted sklearn.tree.DecisionTreeClassifier
+// tree_ attribute, plus the names needed to build its input.
+type sklearnTree struct {
+	// FeatureNames maps column i of the input matrix X to an attribute.
+	FeatureNames  []string      `json:"feature_names"`
+	Classes       []string      `json:"classes"`
+	NFeatures     int           `json:"n_features"`
+	NClasses      int           `json:"n_classes"`
+	NOutputs      int           `json:"n_outputs"`
+	NodeCount     int           `json:"node_count"`
+	MaxDepth      int           `json:"max_depth"`
+	ChildrenLeft  []int         `json:"children_left"`
+	ChildrenRight []int         `json:"children_right"`
+	Feature       []int         `json:"feature"`
+	Threshold     []float64     `json:"threshold"`
+	NNodeSamples  []int         `json:"n_node_samples"`
+	Value         [][][]float64 `json:"value"`
+}
+
+// ToSklearnJSON exports the tree as the node arrays scikit-learn's tree
+// uses: children_left, children_right, feature, threshold, value (class
+// counts, shape node_count x 1 x n_classes, classes in Classes() order) and
+// n_node_samples, with nodes in depth-first order and -1/-2 marking leaves
+// as in sklearn. feature_names gives the attribute of each input column;
+// only attributes the tree splits on are included.
+//
+// scikit-learn sends X[feature] <= threshold left, so the NoMatch child of
+// a "value >= pivot" split is the left child and the threshold is the
+// largest float64 below the pivot. scikit-learn trees are numeric-only:
+// models with categorical, ordinal, lexicographic, multiway or oblique
+// splits, and multi-output models, are rejected; one-hot encode
+// categorical attributes (OneHotEncode) before training instead. Missing
+// values are not represented.
+func (m *Model) ToSklearnJSON() (string, error) {
+	if m == nil || m.Root == nil {
+		return "", errors.New("model is nil")
+	}
+	if len(m.Config.CategoryAttrs) > 1 {
+		return "", errors.New("multi-output models cannot be exported to scikit-learn")
+	}
+	features, err := sklearnFeatures(m.Root)
+	if err != nil {
+		return "", err
+	}
+	classes := m.Classes()
+	t := &sklearnTree{
+		FeatureNames: features,
+		Classes:      classes,
+		NFeatures:    len(features),
+		NClasses:     len(classes),
+		NOutputs:     1,
+	}
+	index := make(map[string]int, len(features))
+	for i, f := range features {
+		index[f] = i
+	}
+	t.add(m.Root, m.Root.ClassCounts, 0, index)
+	t.NodeCount = len(t.Feature)
+	out, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// sklearnFeatures returns the sorted attributes the tree under n splits on,
+// or an error naming the first split scikit-learn cannot represent.
+func sklearnFeatures(n *TreeItem) ([]string, error) {
+	seen := make(map[string]bool)
+	var walk func(n *TreeItem) error
+	walk = func(n *TreeItem) error {
+		if n == nil || n.isLeaf() {
+			return nil
+		}
+		if n.PredicateName != ">=" || n.Oblique != nil || len(n.Children) > 0 || !isNumeric(n.Pivot) {
+			return fmt.Errorf("split %q is not numeric; scikit-learn trees only support numeric splits, so one-hot encode categorical attributes before training", n.condition())
+		}
+		seen[n.Attribute] = true
+		if err := walk(n.Match); err != nil {
+			return err
+		}
+		return walk(n.NoMatch)
+	}
+	if err := walk(n); err != nil {
+		return nil, err
+	}
+	return sortedKeys(seen), nil
+}
+
+// add appends n and its subtree in depth-first order, left (NoMatch) child
+// first, and returns n's index. A nil n, a missing child, becomes a leaf
+// holding its parent's counts.
+func (t *sklearnTree) add(n *TreeItem, parentCounts map[string]int, depth int, index map[string]int) int {
+	id := len(t.Feature)
+	if depth > t.MaxDepth {
+		t.MaxDepth = depth
+	}
+	counts := parentCounts
+	if n != nil {
+		counts = n.ClassCounts
+	}
+	row := make([]float64, len(t.Classes))
+	samples := 0
+	for i, c := range t.Classes {
+		row[i] = float64(counts[c])
+		samples += counts[c]
+	}
+	t.ChildrenLeft = append(t.ChildrenLeft, -1)
+	t.ChildrenRight = append(t.ChildrenRight, -1)
+	t.Feature = append(t.Feature, sklearnUndefined)
+	t.Threshold = append(t.Threshold, sklearnUndefined)
+	t.NNodeSamples = append(t.NNodeSamples, samples)
+	t.Value = append(t.Value, [][]float64{row})
+	if n == nil || n.isLeaf() {
+		return id
+	}
+	t.Feature[id] = index[n.Attribute]
+	t.Threshold[id] = math.Nextafter(toFloat(n.Pivot), math.Inf(-1))
+	left := t.add(n.NoMatch, counts, depth+1, index)
+	right := t.add(n.Match, counts, depth+1, index)
+	t.ChildrenLeft[id] = left
+	t.ChildrenRight[id] = right
+	return id
+}
@@ -0,0 +1,87 @@
+package dtree
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToSklearnJSON(t *testing.T) {
+	set := diagonalSet(300, 1)
+	model, err := Train(set, Config{CategoryAttr: "label", MaxDepth: 5})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	out, err := model.ToSklearnJSON()
+	if err != nil {
+		t.Fatalf("ToSklearnJSON failed: %v", err)
+	}
+	var tree sklearnTree
+	if err := json.Unmarshal([]byte(out), &tree); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	n := model.Stats().TotalNodes
+	if tree.NodeCount != n {
+		t.Fatalf("node_count = %d, want %d", tree.NodeCount, n)
+	}
+	for name, l := range map[string]int{
+		"children_left":  len(tree.ChildrenLeft),
+		"children_right": len(tree.ChildrenRight),
+		"feature":        len(tree.Feature),
+		"threshold":      len(tree.Threshold),
+		"n_node_samples": len(tree.NNodeSamples),
+		"value":          len(tree.Value),
+	} {
+		if l != n {
+			t.Errorf("len(%s) = %d, want %d", name, l, n)
+		}
+	}
+
+	leafTotal := 0
+	for i, row := range tree.Value {
+		sum := 0.0
+		for _, v := range row[0] {
+			sum += v
+		}
+		if int(sum) != tree.NNodeSamples[i] {
+			t.Errorf("node %d: value sums to %v, n_node_samples is %d", i, sum, tree.NNodeSamples[i])
+		}
+		if tree.ChildrenLeft[i] == -1 {
+			leafTotal += int(sum)
+		}
+	}
+	if leafTotal != len(set) {
+		t.Errorf("leaf values sum to %d, want %d", leafTotal, len(set))
+	}
+
+	// Walking the arrays the way scikit-learn does gives the same class.
+	for i, item := range set {
+		node := 0
+		for tree.ChildrenLeft[node] != -1 {
+			if item[tree.FeatureNames[tree.Feature[node]]].(float64) <= tree.Threshold[node] {
+				node = tree.ChildrenLeft[node]
+			} else {
+				node = tree.ChildrenRight[node]
+			}
+		}
+		best := 0
+		for c, v := range tree.Value[node][0] {
+			if v > tree.Value[node][0][best] {
+				best = c
+			}
+		}
+		if want, _ := model.Predict(item); tree.Classes[best] != want {
+			t.Fatalf("item %d: sklearn walk gives %q, Predict gives %q", i, tree.Classes[best], want)
+		}
+	}
+}
+
+func TestToSklearnJSON_Categorical(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if _, err := model.ToSklearnJSON(); err == nil {
+		t.Fatal("expected error for a model with categorical splits")
+	}
+}
@@ -0,0 +1,97 @@
+package dtree
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// TrainTestSplit randomly partitions set into a training and a test subset,
+// with round(len(set)*testFrac) items going to test. Both subsets keep the
+// original item order and the split is deterministic for a given seed.
+func TrainTestSplit(set TrainingSet, testFrac float64, seed int64) (train, test TrainingSet, err error) {
+	if err := validateSplit(set, testFrac); err != nil {
+		return nil, nil, err
+	}
+	rng := rand.New(rand.NewSource(seed))
+	indices := make([]int, len(set))
+	for i := range indices {
+		indices[i] = i
+	}
+	isTest := make([]bool, len(set))
+	for _, i := range pickTest(indices, testFrac, rng) {
+		isTest[i] = true
+	}
+	return partition(set, isTest)
+}
+
+// StratifiedSplit is like TrainTestSplit but splits each class of the label
+// attribute (normally Config.CategoryAttr) separately, so both subsets keep
+// the class proportions of set. Classes with a single item cannot be split
+// and are placed entirely in the training subset.
+func StratifiedSplit(set TrainingSet, label string, testFrac float64, seed int64) (train, test TrainingSet, err error) {
+	if err := validateSplit(set, testFrac); err != nil {
+		return nil, nil, err
+	}
+	groups := make(map[string][]int)
+	for i, item := range set {
+		key := valueKey(item[label])
+		groups[key] = append(groups[key], i)
+	}
+	classes := make([]string, 0, len(groups))
+	for c := range groups {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+
+	rng := rand.New(rand.NewSource(seed))
+	isTest := make([]bool, len(set))
+	for _, c := range classes {
+		if len(groups[c]) < 2 {
+			continue
+		}
+		for _, i := range pickTest(groups[c], testFrac, rng) {
+			isTest[i] = true
+		}
+	}
+	return partition(set, isTest)
+}
+
+func validateSplit(set TrainingSet, testFrac float64) error {
+	if len(set) < 2 {
+		return errors.New("training set must contain at least 2 items to split")
+	}
+	if !(testFrac > 0 && testFrac < 1) {
+		return errors.New("testFrac must be between 0 and 1 (exclusive)")
+	}
+	return nil
+}
+
+// pickTest shuffles indices and returns the ones assigned to the test
+// subset, keeping at least one index on each side.
+func pickTest(indices []int, testFrac float64, rng *rand.Rand) []int {
+	n := int(math.Round(float64(len(indices)) * testFrac))
+	if n < 1 {
+		n = 1
+	}
+	if n > len(indices)-1 {
+		n = len(indices) - 1
+	}
+	out := make([]int, 0, n)
+	for _, p := range rng.Perm(len(indices))[:n] {
+		out = append(out, indices[p])
+	}
+	return out
+}
+
+func partition(set TrainingSet, isTest []bool) (train, test TrainingSet, err error) {
+	for i, item := range set {
+		if isTest[i] {
+			test = append(test, item)
+		} else {
+			train = append(train, item)
+		}
+	}
+	return train, test, nil
+}
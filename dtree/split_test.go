@@ -0,0 +1,102 @@
+package dtree
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func imbalancedSet() TrainingSet {
+	var ts TrainingSet
+	for i := 0; i < 100; i++ {
+		label := "a"
+		if i%5 == 0 {
+			label = "b" // 20% minority class
+		}
+		ts = append(ts, TrainingItem{"id": float64(i), "label": label})
+	}
+	return ts
+}
+
+func TestTrainTestSplit_Sizes(t *testing.T) {
+	ts := imbalancedSet()
+	train, test, err := TrainTestSplit(ts, 0.25, 1)
+	if err != nil {
+		t.Fatalf("split failed: %v", err)
+	}
+	if len(train) != 75 || len(test) != 25 {
+		t.Fatalf("expected 75/25, got %d/%d", len(train), len(test))
+	}
+	seen := make(map[float64]bool)
+	for _, item := range append(append(TrainingSet{}, train...), test...) {
+		seen[item["id"].(float64)] = true
+	}
+	if len(seen) != 100 {
+		t.Fatalf("expected every item exactly once, got %d distinct", len(seen))
+	}
+}
+
+func TestTrainTestSplit_Invalid(t *testing.T) {
+	ts := imbalancedSet()
+	for _, frac := range []float64{0, 1, -0.5, 1.5, math.NaN()} {
+		if _, _, err := TrainTestSplit(ts, frac, 1); err == nil {
+			t.Errorf("expected error for testFrac %v", frac)
+		}
+	}
+	if _, _, err := TrainTestSplit(nil, 0.2, 1); err == nil {
+		t.Error("expected error for empty set")
+	}
+}
+
+func TestStratifiedSplit_PreservesRatio(t *testing.T) {
+	ts := imbalancedSet()
+	train, test, err := StratifiedSplit(ts, "label", 0.3, 7)
+	if err != nil {
+		t.Fatalf("split failed: %v", err)
+	}
+	ratio := func(s TrainingSet) float64 {
+		b := 0
+		for _, item := range s {
+			if item["label"] == "b" {
+				b++
+			}
+		}
+		return float64(b) / float64(len(s))
+	}
+	for name, s := range map[string]TrainingSet{"train": train, "test": test} {
+		if r := ratio(s); math.Abs(r-0.2) > 0.02 {
+			t.Errorf("%s minority ratio %.3f, want 0.2", name, r)
+		}
+	}
+}
+
+func TestStratifiedSplit_Deterministic(t *testing.T) {
+	ts := imbalancedSet()
+	train1, test1, _ := StratifiedSplit(ts, "label", 0.3, 42)
+	train2, test2, _ := StratifiedSplit(ts, "label", 0.3, 42)
+	if !reflect.DeepEqual(train1, train2) || !reflect.DeepEqual(test1, test2) {
+		t.Fatal("expected identical splits for the same seed")
+	}
+	_, test3, _ := StratifiedSplit(ts, "label", 0.3, 43)
+	if fmt.Sprint(test1) == fmt.Sprint(test3) {
+		t.Error("expected a different split for a different seed")
+	}
+}
+
+func TestStratifiedSplit_TinyClass(t *testing.T) {
+	ts := imbalancedSet()
+	ts = append(ts, TrainingItem{"id": 100.0, "label": "rare"})
+	train, test, err := StratifiedSplit(ts, "label", 0.3, 1)
+	if err != nil {
+		t.Fatalf("split failed: %v", err)
+	}
+	for _, item := range test {
+		if item["label"] == "rare" {
+			t.Fatal("single-item class should stay in the training subset")
+		}
+	}
+	if len(train)+len(test) != len(ts) {
+		t.Fatalf("lost items: %d+%d != %d", len(train), len(test), len(ts))
+	}
+}
@@ -1,5 +1,10 @@
 package dtree
 
+import (
+	"sort"
+	"unsafe"
+)
+
 // Stats computes and returns statistics about the model's tree structure.
 // This is useful for understanding model complexity and debugging.
 func (m *Model) Stats() ModelStats {
@@ -17,6 +22,7 @@ func (m *Model) Stats() ModelStats {
 	for class := range classSet {
 		stats.Classes = append(stats.Classes, class)
 	}
+	sort.Strings(stats.Classes)
 
 	return stats
 }
@@ -34,12 +40,16 @@ func collectStats(node *TreeItem, depth int, stats *ModelStats, classSet map[str
 	if depth > stats.TreeDepth {
 		stats.TreeDepth = depth
 	}
+	for len(stats.NodesPerDepth) <= depth {
+		stats.NodesPerDepth = append(stats.NodesPerDepth, 0)
+		stats.LeavesPerDepth = append(stats.LeavesPerDepth, 0)
+	}
+	stats.NodesPerDepth[depth]++
 
 	// Check if it's a leaf
-	isLeaf := node.Match == nil && node.NoMatch == nil
-
-	if isLeaf {
+	if node.isLeaf() {
 		stats.LeafNodes++
+		stats.LeavesPerDepth[depth]++
 		// Collect class from leaf
 		if node.Category != "" {
 			classSet[node.Category] = true
@@ -47,7 +57,80 @@ func collectStats(node *TreeItem, depth int, stats *ModelStats, classSet map[str
 	} else {
 		stats.InternalNodes++
 		// Recurse to children
-		collectStats(node.Match, depth+1, stats, classSet)
-		collectStats(node.NoMatch, depth+1, stats, classSet)
+		for _, b := range node.branches() {
+			collectStats(b.node, depth+1, stats, classSet)
+		}
+	}
+}
+
+// Sizes used by MemoryFootprint. A map entry is costed as its key and value
+// plus mapEntryOverhead bytes for the bucket slot and tophash byte.
+const (
+	stringHeaderSize = int(unsafe.Sizeof(""))
+	treeItemSize     = int(unsafe.Sizeof(TreeItem{}))
+	obliqueSize      = int(unsafe.Sizeof(ObliqueSplit{}))
+	pointerSize      = int(unsafe.Sizeof((*TreeItem)(nil)))
+	intSize          = int(unsafe.Sizeof(0))
+	mapEntryOverhead = 8
+)
+
+// MemoryFootprint estimates the bytes held by the model's tree: every node
+// struct, its ClassCounts, OutputCounts and Children entries, and the bytes
+// of its strings (attribute, category, pivot and value lists). Strings
+// shared between nodes are counted at every node, so the estimate errs high.
+// It is meant for capacity planning, such as how many models fit in a
+// serving process, not exact accounting. It returns 0 for a nil model.
+func (m *Model) MemoryFootprint() int {
+	if m == nil {
+		return 0
+	}
+	return nodeFootprint(m.Root)
+}
+
+// nodeFootprint returns the estimated bytes of node and its subtree.
+func nodeFootprint(node *TreeItem) int {
+	if node == nil {
+		return 0
+	}
+	n := treeItemSize + len(node.Category) + len(node.Attribute) + len(node.PredicateName)
+	switch v := node.Pivot.(type) {
+	case nil:
+	case string:
+		n += stringHeaderSize + len(v)
+	default:
+		n += 8
+	}
+	n += stringsFootprint(node.PivotSet) + stringsFootprint(node.Categories)
+	n += countsFootprint(node.ClassCounts)
+	for key, counts := range node.OutputCounts {
+		n += stringHeaderSize + len(key) + pointerSize + mapEntryOverhead + countsFootprint(counts)
+	}
+	if o := node.Oblique; o != nil {
+		n += obliqueSize + stringsFootprint(o.Attributes) + 8*len(o.Weights)
+	}
+	for key := range node.Children {
+		n += stringHeaderSize + len(key) + pointerSize + mapEntryOverhead
+	}
+	for _, b := range node.branches() {
+		n += nodeFootprint(b.node)
+	}
+	return n
+}
+
+// stringsFootprint returns the estimated bytes of a string slice's contents.
+func stringsFootprint(values []string) int {
+	n := 0
+	for _, v := range values {
+		n += stringHeaderSize + len(v)
+	}
+	return n
+}
+
+// countsFootprint returns the estimated bytes of a class count map.
+func countsFootprint(counts map[string]int) int {
+	n := 0
+	for class := range counts {
+		n += stringHeaderSize + len(class) + intSize + mapEntryOverhead
 	}
+	return n
 }
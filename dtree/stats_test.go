@@ -1,6 +1,8 @@
 package dtree
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -162,3 +164,98 @@ func TestStats_WithMaxDepth(t *testing.T) {
 		t.Errorf("expected depth <= 1 with MaxDepth=1, got %d", stats.TreeDepth)
 	}
 }
+
+func TestStats_NumericLabels(t *testing.T) {
+	ts := TrainingSet{
+		TrainingItem{"x": 1.0, "label": 1.0},
+		TrainingItem{"x": 2.0, "label": 1},
+		TrainingItem{"x": 3.0, "label": 2.5},
+		TrainingItem{"x": 4.0, "label": 2.5000001},
+		TrainingItem{"x": 5.0, "label": 1e7},
+		TrainingItem{"x": 6.0, "label": int64(-3)},
+	}
+	model, err := Train(ts, Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+
+	want := []string{"-3", "1", "10000000", "2.5", "2.5000001"}
+	stats := model.Stats()
+	if strings.Join(stats.Classes, ",") != strings.Join(want, ",") {
+		t.Fatalf("Stats().Classes = %v, want %v", stats.Classes, want)
+	}
+
+	predicted := make(map[string]bool)
+	for _, item := range ts {
+		pred, err := model.Predict(item)
+		if err != nil {
+			t.Fatalf("predict failed: %v", err)
+		}
+		predicted[pred] = true
+	}
+	for _, c := range stats.Classes {
+		if !predicted[c] {
+			t.Errorf("class %q in stats never returned by Predict (got %v)", c, predicted)
+		}
+		if !strings.Contains(model.ToDOT(), `"`+c+`"`) {
+			t.Errorf("class %q not rendered in DOT output", c)
+		}
+	}
+}
+
+func TestStats_PerDepth(t *testing.T) {
+	// root -> (leaf, split -> (leaf, leaf))
+	model := redundantModel()
+	stats := model.Stats()
+	if want := []int{1, 2, 2}; !reflect.DeepEqual(stats.NodesPerDepth, want) {
+		t.Errorf("NodesPerDepth = %v, want %v", stats.NodesPerDepth, want)
+	}
+	if want := []int{0, 1, 2}; !reflect.DeepEqual(stats.LeavesPerDepth, want) {
+		t.Errorf("LeavesPerDepth = %v, want %v", stats.LeavesPerDepth, want)
+	}
+
+	trained, err := Train(syntheticSet(200), Config{CategoryAttr: "label"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	stats = trained.Stats()
+	if len(stats.NodesPerDepth) != stats.TreeDepth+1 || len(stats.LeavesPerDepth) != stats.TreeDepth+1 {
+		t.Fatalf("per-depth lengths %d/%d, want depth+1 = %d",
+			len(stats.NodesPerDepth), len(stats.LeavesPerDepth), stats.TreeDepth+1)
+	}
+	nodes, leaves := 0, 0
+	for d := range stats.NodesPerDepth {
+		nodes += stats.NodesPerDepth[d]
+		leaves += stats.LeavesPerDepth[d]
+	}
+	if nodes != stats.TotalNodes || leaves != stats.LeafNodes {
+		t.Errorf("per-depth sums %d nodes, %d leaves; want %d, %d", nodes, leaves, stats.TotalNodes, stats.LeafNodes)
+	}
+}
+
+func TestMemoryFootprint(t *testing.T) {
+	var nilModel *Model
+	if got := nilModel.MemoryFootprint(); got != 0 {
+		t.Fatalf("nil model footprint = %d, want 0", got)
+	}
+	if got := (&Model{}).MemoryFootprint(); got != 0 {
+		t.Fatalf("nil root footprint = %d, want 0", got)
+	}
+
+	set := syntheticSet(400)
+	prev := 0
+	for _, depth := range []int{1, 3, 6} {
+		model, err := Train(set, Config{CategoryAttr: "label", MaxDepth: depth})
+		if err != nil {
+			t.Fatalf("training failed: %v", err)
+		}
+		got := model.MemoryFootprint()
+		if min := model.Stats().TotalNodes * treeItemSize; got < min {
+			t.Fatalf("depth %d: footprint %d is below %d bytes of node structs", depth, got, min)
+		}
+		if got <= prev {
+			t.Fatalf("depth %d: footprint %d did not grow from %d", depth, got, prev)
+		}
+		prev = got
+	}
+}
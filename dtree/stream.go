@@ -0,0 +1,100 @@
+package dtree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// streamProgressEvery is how many rows PredictStream handles between
+// StreamOptions.Progress calls.
+const streamProgressEvery = 1000
+
+// StreamOptions controls PredictStream.
+type StreamOptions struct {
+	// Proba adds the item's class probabilities to each result under "proba".
+	Proba bool
+	// OrderedProba adds "proba" as a PredictProbaOrdered list instead of a
+	// map, so every result lists the same classes in the same order.
+	OrderedProba bool
+	// Progress, if set, is called with the number of rows predicted so far
+	// every 1000 rows and once with the final count when the input ends.
+	Progress func(done int)
+}
+
+// PredictStream reads JSONL items from r and writes one JSONL result per
+// item to w, in input order: {"input": item, "prediction": class}, plus
+// "proba" when opts.Proba or opts.OrderedProba is set. Items are handled one
+// at a time, so memory use does not grow with the input. Blank lines are
+// skipped. Errors name the 1-based input line they occurred on; results
+// before it have been written.
+func (m *Model) PredictStream(r io.Reader, w io.Writer, opts StreamOptions) error {
+	if m == nil {
+		return errors.New("model is nil")
+	}
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	line, done := 0, 0
+	err := func() error {
+		for {
+			raw, readErr := br.ReadBytes('\n')
+			if len(raw) > 0 {
+				line++
+			}
+			if raw = bytes.TrimSpace(raw); len(raw) > 0 {
+				if err := m.predictLine(raw, enc, opts); err != nil {
+					return fmt.Errorf("line %d: %w", line, err)
+				}
+				done++
+				if opts.Progress != nil && done%streamProgressEvery == 0 {
+					opts.Progress(done)
+				}
+			}
+			if readErr == io.EOF {
+				return nil
+			}
+			if readErr != nil {
+				return readErr
+			}
+		}
+	}()
+	if flushErr := bw.Flush(); err == nil {
+		err = flushErr
+	}
+	if err == nil && opts.Progress != nil && (done == 0 || done%streamProgressEvery != 0) {
+		opts.Progress(done)
+	}
+	return err
+}
+
+// predictLine predicts the JSON item in raw and encodes the result.
+func (m *Model) predictLine(raw []byte, enc *json.Encoder, opts StreamOptions) error {
+	var item TrainingItem
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	pred, err := m.Predict(item)
+	if err != nil {
+		return err
+	}
+	out := map[string]interface{}{"input": item, "prediction": pred}
+	switch {
+	case opts.OrderedProba:
+		pb, err := m.PredictProbaOrdered(item)
+		if err != nil {
+			return err
+		}
+		out["proba"] = pb
+	case opts.Proba:
+		pb, err := m.PredictProba(item)
+		if err != nil {
+			return err
+		}
+		out["proba"] = pb
+	}
+	return enc.Encode(out)
+}
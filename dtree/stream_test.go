@@ -0,0 +1,78 @@
+package dtree
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPredictStream(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	var in strings.Builder
+	for i, item := range playTennisSet() {
+		b, _ := json.Marshal(item)
+		in.Write(b)
+		in.WriteString("\n")
+		if i == 2 {
+			in.WriteString("\n") // blank lines are skipped
+		}
+	}
+
+	var out strings.Builder
+	var progress []int
+	opts := StreamOptions{Proba: true, Progress: func(done int) { progress = append(progress, done) }}
+	if err := model.PredictStream(strings.NewReader(in.String()), &out, opts); err != nil {
+		t.Fatalf("PredictStream failed: %v", err)
+	}
+	sc := bufio.NewScanner(strings.NewReader(out.String()))
+	n := 0
+	for sc.Scan() {
+		var res struct {
+			Input      TrainingItem       `json:"input"`
+			Prediction string             `json:"prediction"`
+			Proba      map[string]float64 `json:"proba"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &res); err != nil {
+			t.Fatalf("output line %d is not JSON: %v", n+1, err)
+		}
+		want, _ := model.Predict(playTennisSet()[n])
+		if res.Prediction != want {
+			t.Errorf("line %d: prediction %q, want %q", n+1, res.Prediction, want)
+		}
+		if res.Input["Outlook"] != playTennisSet()[n]["Outlook"] || len(res.Proba) == 0 {
+			t.Errorf("line %d: unexpected result %+v", n+1, res)
+		}
+		n++
+	}
+	if n != len(playTennisSet()) {
+		t.Fatalf("got %d output lines, want %d", n, len(playTennisSet()))
+	}
+	if len(progress) != 1 || progress[0] != n {
+		t.Errorf("progress calls = %v, want [%d]", progress, n)
+	}
+}
+
+func TestPredictStream_ReportsLine(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play", StrictPredict: true})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	var out strings.Builder
+	in := `{"Outlook":"rain","Temperature":70,"Humidity":96,"Wind":false}` + "\n{oops}\n"
+	err = model.PredictStream(strings.NewReader(in), &out, StreamOptions{})
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2: invalid JSON") {
+		t.Fatalf("expected an invalid JSON error on line 2, got %v", err)
+	}
+	if strings.Count(out.String(), "\n") != 1 {
+		t.Errorf("results before the error should be written, got %q", out.String())
+	}
+
+	err = model.PredictStream(strings.NewReader("\n{\"Outlook\":\"rain\"}"), &out, StreamOptions{})
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2: ") {
+		t.Fatalf("expected a prediction error on line 2, got %v", err)
+	}
+}
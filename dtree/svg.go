@@ -0,0 +1,136 @@
+package dtree
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// SVG layout, in pixels.
+const (
+	svgMinNodeWidth = 80
+	svgCharWidth    = 7
+	svgNodeHeight   = 30
+	svgLevelHeight  = 80
+	svgMargin       = 20
+)
+
+// ToSVG renders the tree as a self-contained SVG document, with boxes for
+// decision nodes and ovals for leaves, labeled like ToDOT. Leaves are placed
+// left to right in equal slots and each decision node is centered over its
+// children, so the canvas grows with the number of leaves and the depth.
+func (m *Model) ToSVG() (string, error) {
+	if m == nil || m.Root == nil {
+		return "", errors.New("model is nil")
+	}
+	l := &svgLayout{slot: svgMinNodeWidth}
+	l.measure(m.Root)
+	l.slot += svgMargin
+	l.place(m.Root, 0)
+
+	width := l.leaves*l.slot + 2*svgMargin
+	height := l.depth*svgLevelHeight + svgNodeHeight + 2*svgMargin
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		width, height, width, height)
+	b.WriteString(`<style>.decision{fill:#e3f2fd;stroke:#1976d2}.leaf{fill:#e8f5e9;stroke:#388e3c}.edge{stroke:#666;fill:none}text{text-anchor:middle;dominant-baseline:middle}</style>` + "\n")
+	// Edges first so nodes are drawn over them.
+	for _, e := range l.edges {
+		fmt.Fprintf(&b, `<line class="edge" x1="%d" y1="%d" x2="%d" y2="%d"/>`+"\n",
+			e.from.x, e.from.y+svgNodeHeight/2, e.to.x, e.to.y-svgNodeHeight/2)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="#666">%s</text>`+"\n",
+			(e.from.x+e.to.x)/2, (e.from.y+e.to.y)/2, html.EscapeString(e.label))
+	}
+	for _, n := range l.nodes {
+		if n.leaf {
+			fmt.Fprintf(&b, `<ellipse class="leaf" cx="%d" cy="%d" rx="%d" ry="%d"/>`+"\n",
+				n.x, n.y, n.width/2, svgNodeHeight/2)
+		} else {
+			fmt.Fprintf(&b, `<rect class="decision" x="%d" y="%d" width="%d" height="%d" rx="4"/>`+"\n",
+				n.x-n.width/2, n.y-svgNodeHeight/2, n.width, svgNodeHeight)
+		}
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", n.x, n.y, html.EscapeString(n.label))
+	}
+	b.WriteString("</svg>\n")
+	return b.String(), nil
+}
+
+type svgNode struct {
+	x, y, width int
+	label       string
+	leaf        bool
+}
+
+type svgEdge struct {
+	from, to *svgNode
+	label    string
+}
+
+type svgLayout struct {
+	slot   int // horizontal space per leaf
+	leaves int // leaves placed so far
+	depth  int // deepest level seen
+	nodes  []*svgNode
+	edges  []svgEdge
+}
+
+// svgLabel returns the text drawn inside a node.
+func svgLabel(n *TreeItem) string {
+	if n.isLeaf() {
+		return n.Category
+	}
+	return n.condition()
+}
+
+func svgTextWidth(s string) int {
+	return len([]rune(s))*svgCharWidth + svgMargin
+}
+
+// measure widens the slot to fit the longest label.
+func (l *svgLayout) measure(n *TreeItem) {
+	if n == nil {
+		return
+	}
+	if w := svgTextWidth(svgLabel(n)); w > l.slot {
+		l.slot = w
+	}
+	for _, b := range n.branches() {
+		l.measure(b.node)
+	}
+}
+
+// place positions n and its subtree, returning n's layout node.
+func (l *svgLayout) place(n *TreeItem, depth int) *svgNode {
+	if depth > l.depth {
+		l.depth = depth
+	}
+	label := svgLabel(n)
+	sn := &svgNode{
+		y:     svgMargin + svgNodeHeight/2 + depth*svgLevelHeight,
+		width: svgTextWidth(label),
+		label: label,
+		leaf:  n.isLeaf(),
+	}
+	if sn.width < svgMinNodeWidth {
+		sn.width = svgMinNodeWidth
+	}
+	l.nodes = append(l.nodes, sn)
+
+	var children []*svgNode
+	for _, b := range n.branches() {
+		if b.node == nil {
+			continue
+		}
+		child := l.place(b.node, depth+1)
+		children = append(children, child)
+		l.edges = append(l.edges, svgEdge{from: sn, to: child, label: b.label})
+	}
+	if len(children) == 0 {
+		sn.x = svgMargin + l.leaves*l.slot + l.slot/2
+		l.leaves++
+	} else {
+		sn.x = (children[0].x + children[len(children)-1].x) / 2
+	}
+	return sn
+}
@@ -0,0 +1,83 @@
+package dtree
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// svgShapes parses doc as XML and counts its rect and ellipse elements.
+func svgShapes(t *testing.T, doc string) (rects, ellipses int) {
+	t.Helper()
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return rects, ellipses
+		}
+		if err != nil {
+			t.Fatalf("SVG is not well-formed XML: %v\n%s", err, doc)
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			switch el.Name.Local {
+			case "rect":
+				rects++
+			case "ellipse":
+				ellipses++
+			}
+		}
+	}
+}
+
+func TestToSVG_NodeCount(t *testing.T) {
+	for name, tc := range map[string]struct {
+		set TrainingSet
+		cfg Config
+	}{
+		"playTennis": {playTennisSet(), Config{CategoryAttr: "Play"}},
+		"synthetic":  {syntheticSet(300), Config{CategoryAttr: "label"}},
+		"multiway":   {playTennisSet(), Config{CategoryAttr: "Play", MultiwaySplits: true}},
+	} {
+		model, err := Train(tc.set, tc.cfg)
+		if err != nil {
+			t.Fatalf("%s: training failed: %v", name, err)
+		}
+		doc, err := model.ToSVG()
+		if err != nil {
+			t.Fatalf("%s: ToSVG failed: %v", name, err)
+		}
+		rects, ellipses := svgShapes(t, doc)
+		stats := model.Stats()
+		if rects != stats.InternalNodes || ellipses != stats.LeafNodes || rects+ellipses != stats.TotalNodes {
+			t.Errorf("%s: got %d boxes and %d ovals, want %d and %d (total %d)",
+				name, rects, ellipses, stats.InternalNodes, stats.LeafNodes, stats.TotalNodes)
+		}
+	}
+}
+
+func TestToSVG_EscapesLabels(t *testing.T) {
+	set := TrainingSet{
+		{"shape": "<square>", "label": "a&b"},
+		{"shape": "round", "label": "c\"d"},
+	}
+	model, err := Train(set, Config{CategoryAttr: "label", MinSamples: 1})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	doc, err := model.ToSVG()
+	if err != nil {
+		t.Fatalf("ToSVG failed: %v", err)
+	}
+	svgShapes(t, doc)
+	if !strings.Contains(doc, "a&amp;b") {
+		t.Errorf("leaf label not escaped:\n%s", doc)
+	}
+}
+
+func TestToSVG_NilModel(t *testing.T) {
+	var m *Model
+	if _, err := m.ToSVG(); err == nil {
+		t.Fatal("expected error for nil model")
+	}
+}
@@ -1,37 +1,167 @@
 package dtree
 
 import (
+	"context"
 	"errors"
+	"io"
 	"math"
+	"math/rand"
+	"os"
+	"path"
 	"reflect"
 	"sort"
+	"strconv"
+	"time"
 )
 
+// minGain is the smallest information gain considered a real improvement.
+// Gains at or below it are treated as floating-point noise.
+const minGain = 1e-12
+
 // Internal helpers
 
+// predicateEq compares with interface equality, so strings, bools and nil
+// match only values of the same dynamic type.
 func predicateEq(a, b interface{}) bool { return a == b }
 
 func predicateGte(a, b interface{}) bool {
 	switch av := a.(type) {
 	case float64:
-		bv, ok := b.(float64)
-		if !ok {
-			return false
-		}
-		return av >= bv
-	case int:
-		bv, ok := b.(float64)
-		if !ok {
-			return false
+		if bv, ok := b.(float64); ok {
+			return av >= bv
 		}
-		return float64(av) >= bv
+	case string:
+		// Strings only order against strings (LexicographicFeatures).
+		bv, ok := b.(string)
+		return ok && av >= bv
 	case nil:
 		// treat missing as unknown; handled at predict time
 		return false
 	}
+	if nonFinite(a) {
+		// NaN and infinities are missing values too.
+		return false
+	}
+	// Other numeric types on either side, such as the integer pivots of
+	// hand-built models, compare as float64.
+	return isNumeric(a) && isNumeric(b) && toFloat(a) >= toFloat(b)
+}
+
+// validCriterion reports whether s names a supported split criterion.
+// The empty string is accepted and means CriterionEntropy.
+func validCriterion(s string) bool {
+	switch s {
+	case "", CriterionEntropy, CriterionGini:
+		return true
+	}
+	return false
+}
+
+// validMissingStrategy reports whether s is a known missing-value strategy.
+// The empty string is accepted and means MissingMajority.
+func validMissingStrategy(s string) bool {
+	switch s {
+	case "", MissingMajority, MissingMatch, MissingNoMatch, MissingFail:
+		return true
+	}
 	return false
 }
 
+// validLeafStrategy reports whether s is a known leaf prediction strategy.
+// The empty string is accepted and means LeafMajority.
+func validLeafStrategy(s string) bool {
+	switch s {
+	case "", LeafMajority, LeafProportional:
+		return true
+	}
+	return false
+}
+
+// validTieBreak reports whether s is a known tie-break mode. The empty
+// string is accepted and keeps the first split found.
+func validTieBreak(s string) bool {
+	switch s {
+	case "", TieBreakDeterministic, TieBreakRandom:
+		return true
+	}
+	return false
+}
+
+func validMonotone(constraints map[string]int) bool {
+	for _, v := range constraints {
+		if v < -1 || v > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// validOrdinal reports whether every ordinal feature lists at least one
+// level and no level twice.
+func validOrdinal(features map[string][]string) bool {
+	for _, levels := range features {
+		if len(levels) == 0 {
+			return false
+		}
+		seen := make(map[string]bool, len(levels))
+		for _, l := range levels {
+			if seen[l] {
+				return false
+			}
+			seen[l] = true
+		}
+	}
+	return true
+}
+
+// validIgnored reports whether patterns are non-empty, well-formed globs.
+func validIgnored(patterns []string) bool {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); p == "" || err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// ignoreMatches reports whether the IgnoredAttributes entry pattern covers
+// attr, by name or as a glob.
+func ignoreMatches(pattern, attr string) bool {
+	if pattern == attr {
+		return true
+	}
+	ok, _ := path.Match(pattern, attr)
+	return ok
+}
+
+// validLexicographic reports whether names are non-empty, unique and not
+// also ordinal features.
+func validLexicographic(names []string, ordinal map[string][]string) bool {
+	seen := make(map[string]bool, len(names))
+	for _, n := range names {
+		if _, ok := ordinal[n]; n == "" || seen[n] || ok {
+			return false
+		}
+		seen[n] = true
+	}
+	return true
+}
+
+// ordinalIndex returns the position of v among levels, or -1 if v is not
+// one of them.
+func ordinalIndex(levels []string, v interface{}) int {
+	if v == nil {
+		return -1
+	}
+	key := valueKey(v)
+	for i, l := range levels {
+		if l == key {
+			return i
+		}
+	}
+	return -1
+}
+
 func stringInSlice(a string, list []string) bool {
 	for _, b := range list {
 		if b == a {
@@ -43,46 +173,118 @@ func stringInSlice(a string, list []string) bool {
 
 // entropy calculates Shannon entropy (natural log base is fine for comparisons).
 func entropy(set TrainingSet, attr string) float64 {
-	counter := counterUniqueValues(set, attr)
-	var e float64
-	total := float64(len(set))
+	return entropyFromCounts(counterUniqueValues(set, attr), len(set))
+}
+
+// entropyFromCounts computes Shannon entropy from precomputed class counts.
+func entropyFromCounts(counts map[string]int, total int) float64 {
 	if total == 0 {
 		return 0
 	}
-	for _, cnt := range counter {
-		p := float64(cnt) / total
+	var e float64
+	t := float64(total)
+	for _, cnt := range counts {
+		if cnt == 0 {
+			continue
+		}
+		p := float64(cnt) / t
 		e += -p * math.Log(p)
 	}
 	return e
 }
 
+// entropyFromWeights computes Shannon entropy from per-class weight sums.
+func entropyFromWeights(weights map[string]float64, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	var e float64
+	for _, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		p := w / total
+		e += -p * math.Log(p)
+	}
+	return e
+}
+
+// giniFromWeights computes Gini impurity from per-class weight sums.
+func giniFromWeights(weights map[string]float64, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	g := 1.0
+	for _, w := range weights {
+		p := w / total
+		g -= p * p
+	}
+	return g
+}
+
+// giniFromCounts computes Gini impurity from precomputed class counts.
+func giniFromCounts(counts map[string]int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	g := 1.0
+	t := float64(total)
+	for _, cnt := range counts {
+		p := float64(cnt) / t
+		g -= p * p
+	}
+	return g
+}
+
 func counterUniqueValues(set TrainingSet, attr string) map[string]int {
 	res := make(map[string]int)
 	for _, item := range set {
-		v := item[attr]
-		switch vv := v.(type) {
-		case string:
-			res[vv] += 1
-		case float64:
-			res[formatFloatKey(vv)] += 1
-		case int:
-			res[formatFloatKey(float64(vv))] += 1
-		default:
-			res["<nil>"] += 1
-		}
+		res[valueKey(item[attr])]++
 	}
 	return res
 }
 
+// valueKey formats a value as a count key; numbers share one canonical form.
+func valueKey(v interface{}) string {
+	switch vv := v.(type) {
+	case string:
+		return vv
+	case float32:
+		// Format at 32-bit precision so 0.1 stays "0.1".
+		return strconv.FormatFloat(float64(vv), 'f', -1, 32)
+	case float64, int, int32, int64:
+		return formatFloatKey(toFloat(vv))
+	case bool:
+		if vv {
+			return "true"
+		}
+		return "false"
+	default:
+		return "<nil>"
+	}
+}
+
 // Split groups items according to predicate on attr.
 type splitResult struct {
 	Match         TrainingSet
 	NoMatch       TrainingSet
+	MatchCounts   map[string]int
+	NoMatchCounts map[string]int
 	Gain          float64
 	Attribute     string
 	Predicate     *Predicate
 	PredicateName string
 	Pivot         interface{}
+	// PivotSet is set instead of Pivot for subset splits.
+	PivotSet []string
+	// Groups and GroupCounts are set instead of Match/NoMatch for multiway
+	// splits; items with no usable value go to Missing and MissingCounts.
+	Groups        map[string]TrainingSet
+	GroupCounts   map[string]map[string]int
+	Missing       TrainingSet
+	MissingCounts map[string]int
+	// Oblique is set instead of Attribute and Pivot for oblique splits.
+	Oblique *ObliqueSplit
 }
 
 func split(set TrainingSet, attr string, predicate Predicate, pivot interface{}) splitResult {
@@ -97,121 +299,1314 @@ func split(set TrainingSet, attr string, predicate Predicate, pivot interface{})
 	return res
 }
 
+// splitCounted partitions like split while tallying labelAttr on each side,
+// so impurity can be derived without another pass over the partitions.
+func splitCounted(set TrainingSet, attr, labelAttr string, predicate Predicate, pivot interface{}) splitResult {
+	res := splitResult{
+		MatchCounts:   make(map[string]int),
+		NoMatchCounts: make(map[string]int),
+	}
+	for _, item := range set {
+		if predicate(item[attr], pivot) {
+			res.Match = append(res.Match, item)
+			res.MatchCounts[valueKey(item[labelAttr])]++
+		} else {
+			res.NoMatch = append(res.NoMatch, item)
+			res.NoMatchCounts[valueKey(item[labelAttr])]++
+		}
+	}
+	return res
+}
+
+// splitMultiway partitions set by the value of attr, one group per distinct
+// value key, tallying labelAttr within each group.
+func splitMultiway(set TrainingSet, attr, labelAttr string) splitResult {
+	res := splitResult{
+		Groups:      make(map[string]TrainingSet),
+		GroupCounts: make(map[string]map[string]int),
+	}
+	for _, item := range set {
+		v := item[attr]
+		if v == nil || nonFinite(v) {
+			if res.MissingCounts == nil {
+				res.MissingCounts = make(map[string]int)
+			}
+			res.Missing = append(res.Missing, item)
+			res.MissingCounts[valueKey(item[labelAttr])]++
+			continue
+		}
+		k := valueKey(v)
+		res.Groups[k] = append(res.Groups[k], item)
+		if res.GroupCounts[k] == nil {
+			res.GroupCounts[k] = make(map[string]int)
+		}
+		res.GroupCounts[k][valueKey(item[labelAttr])]++
+	}
+	return res
+}
+
+// candidateKey identifies an (attribute, pivot) pair already evaluated at a node.
+type candidateKey struct {
+	attr  string
+	pivot interface{}
+}
+
 // Train builds a decision tree model. Returns an error if the input is invalid.
+// The model's Config is the configuration actually used, with every default
+// resolved (see Config.resolved): for example an empty Criterion is stored as
+// CriterionEntropy. Observer, Verbose and LogOutput are cleared.
 func Train(set TrainingSet, cfg Config) (*Model, error) {
+	return TrainContext(context.Background(), set, cfg)
+}
+
+// TrainContext is like Train but stops early when ctx is cancelled or its
+// deadline passes, returning ctx.Err() and no model.
+func TrainContext(ctx context.Context, set TrainingSet, cfg Config) (*Model, error) {
+	return train(ctx, set, cfg, false)
+}
+
+// TrainBaseline builds a single-leaf model that always predicts the majority
+// class of set (by weight, if WeightAttr is set), with the class counts of the
+// whole set. It validates set and cfg like Train and carries the same
+// metadata, so it can be saved, evaluated and cross-validated like any model
+// as a baseline for real trees. Stopping and split options are ignored.
+func TrainBaseline(set TrainingSet, cfg Config) (*Model, error) {
+	return train(context.Background(), set, cfg, true)
+}
+
+// BestSplit returns the split Train(set, cfg) would make at the root
+// without growing the tree, for exploring features. It validates set and
+// cfg like Train and evaluates candidates the same way, honoring
+// IgnoredAttributes, Criterion and the other split options. It fails when
+// Train would make the root a leaf: a pure set, too few samples, no
+// candidate with positive gain or, with ChiSquarePValue, no significant one.
+func BestSplit(set TrainingSet, cfg Config) (SplitInfo, error) {
+	b, err := newTrainingBuilder(context.Background(), set, cfg, false)
+	if err != nil {
+		return SplitInfo{}, err
+	}
+	cfg = b.cfg
+	counts := counterUniqueValues(set, cfg.CategoryAttr)
+	initImpurity, size := b.sideImpurity(set, counts)
+	switch {
+	case initImpurity <= 0.00001:
+		return SplitInfo{}, errors.New("training set is pure; there is no split to make")
+	case cfg.MinSamples > 0 && len(set) < cfg.MinSamples:
+		return SplitInfo{}, errors.New("training set has fewer than MinSamples items")
+	}
+	best, found := b.findSplit(set, counts, initImpurity, size, fullBounds)
+	if !found || best.Gain <= minGain {
+		return SplitInfo{}, errors.New("no split has positive gain")
+	}
+	if cfg.ChiSquarePValue > 0 && !b.significant(best) {
+		return SplitInfo{}, errors.New("no split is significant at ChiSquarePValue")
+	}
+	info := SplitInfo{
+		Attribute:     best.Attribute,
+		PredicateName: best.PredicateName,
+		Pivot:         best.Pivot,
+		PivotSet:      best.PivotSet,
+		Oblique:       best.Oblique,
+		Gain:          best.Gain,
+		MatchCount:    len(best.Match),
+		NoMatchCount:  len(best.NoMatch),
+	}
+	if best.Groups != nil {
+		info.GroupCounts = make(map[string]int, len(best.Groups))
+		for k, g := range best.Groups {
+			info.GroupCounts[k] = len(g)
+		}
+		info.MissingCount = len(best.Missing)
+	}
+	return info, nil
+}
+
+// train implements TrainContext, or TrainBaseline when baseline is set.
+func train(ctx context.Context, set TrainingSet, cfg Config, baseline bool) (*Model, error) {
+	b, err := newTrainingBuilder(ctx, set, cfg, baseline)
+	if err != nil {
+		return nil, err
+	}
+	cfg = b.cfg
+
+	// Build the tree
+	var root *TreeItem
+	if baseline {
+		root = b.leaf(set, counterUniqueValues(set, cfg.CategoryAttr))
+	} else {
+		root = b.makeTrainingTree(set, 0, fullBounds)
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	if root == nil {
+		return nil, errors.New("failed to build tree: root node is nil")
+	}
+
+	model := &Model{Root: root, Config: cfg}
+	model.Config.Observer = nil
+	model.Config.LogOutput = nil
+	model.Config.Verbose = false
+	model.AssignIDs()
+	model.Metadata = &Metadata{
+		FeatureNames: featureNames(set, cfg),
+		TrainedAt:    time.Now().UTC(),
+		LibVersion:   Version,
+		NumSamples:   len(set),
+		Classes:      sortedKeys(classSet(set, cfg.CategoryAttr)),
+		Warnings:     featureWarnings(set, cfg),
+	}
+	if cfg.WarnLeakage {
+		model.Metadata.Warnings = append(model.Metadata.Warnings, b.leakageWarnings(set)...)
+	}
+	wide := make([]string, 0, len(b.wide))
+	for attr := range b.wide {
+		wide = append(wide, attr)
+	}
+	sort.Strings(wide)
+	for _, attr := range wide {
+		model.Metadata.Warnings = append(model.Metadata.Warnings, "feature "+strconv.Quote(attr)+" has "+
+			strconv.Itoa(b.wide[attr])+" categories, more than MaxCategories; it is not split on")
+	}
+	return model, nil
+}
+
+// wideCategoricals returns the distinct categorical value count of every
+// splittable attribute with more than cfg.MaxCategories of them. Numeric
+// values, and ordinal and lexicographic attributes, are not counted.
+func wideCategoricals(set TrainingSet, cfg Config) map[string]int {
+	values := make(map[string]map[string]bool)
+	for _, item := range set {
+		for attr, v := range item {
+			if v == nil || isNumeric(v) || cfg.excluded(attr) {
+				continue
+			}
+			if _, ok := cfg.OrdinalFeatures[attr]; ok || stringInSlice(attr, cfg.LexicographicFeatures) {
+				continue
+			}
+			if values[attr] == nil {
+				values[attr] = make(map[string]bool)
+			}
+			values[attr][valueKey(v)] = true
+		}
+	}
+	wide := make(map[string]int)
+	for attr, vs := range values {
+		if len(vs) > cfg.MaxCategories {
+			wide[attr] = len(vs)
+		}
+	}
+	return wide
+}
+
+// newTrainingBuilder validates set and cfg, fills in cfg's defaults and
+// returns a builder ready to grow a tree on set. A baseline builder skips
+// the setup only split search needs.
+func newTrainingBuilder(ctx context.Context, set TrainingSet, cfg Config, baseline bool) (*builder, error) {
 	// Validate inputs
 	if len(set) == 0 {
 		return nil, errors.New("training set cannot be empty")
 	}
 
-	if cfg.CategoryAttr == "" {
-		return nil, errors.New("config.CategoryAttr is required")
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.resolved()
+
+	// Validate that category attribute exists in at least one item, and that
+	// every item has a usable label: a nil label would otherwise be counted
+	// as a class of its own.
+	for _, label := range cfg.outputs() {
+		foundCategory := false
+		invalid, firstInvalid := 0, -1
+		for i, item := range set {
+			v, ok := item[label]
+			if ok {
+				foundCategory = true
+			}
+			if !validLabel(v) {
+				if invalid == 0 {
+					firstInvalid = i
+				}
+				invalid++
+			}
+		}
+		if !foundCategory && len(cfg.CategoryAttrs) == 0 {
+			return nil, errors.New("categoryAttr not found in any training items")
+		}
+		if !foundCategory {
+			return nil, errors.New("label " + strconv.Quote(label) + " not found in any training items")
+		}
+		if invalid > 0 {
+			return nil, errors.New(strconv.Itoa(invalid) + " of " + strconv.Itoa(len(set)) +
+				" training items have no valid " + strconv.Quote(label) +
+				" label (missing, nil, NaN, infinite or not a string, number or bool); first is item " + strconv.Itoa(firstInvalid))
+		}
+	}
+
+	if cfg.WeightAttr != "" {
+		if err := checkWeights(set, cfg.WeightAttr); err != nil {
+			return nil, err
+		}
+	}
+
+	b := newBuilder(cfg)
+	b.ctx = ctx
+	if len(cfg.MonotoneConstraints) > 0 {
+		for c := range counterUniqueValues(set, cfg.CategoryAttr) {
+			if c > b.positive {
+				b.positive = c
+			}
+		}
+	}
+	if cfg.HistogramBins > 0 && !baseline {
+		b.hist = histogramThresholds(set, cfg)
+	}
+	if cfg.MaxCategories > 0 && !baseline {
+		b.wide = wideCategoricals(set, cfg)
+	}
+	return b, nil
+}
+
+// resolved returns c with its defaults filled in, as training uses it:
+// CategoryAttr from CategoryAttrs, Criterion, MissingStrategy and
+// LeafStrategy. An empty TieBreak is a mode of its own and stays empty.
+func (c Config) resolved() Config {
+	if len(c.CategoryAttrs) > 0 {
+		c.CategoryAttr = c.CategoryAttrs[0]
+	}
+	if c.Criterion == "" {
+		c.Criterion = CriterionEntropy
+	}
+	if c.MissingStrategy == "" {
+		c.MissingStrategy = MissingMajority
+	}
+	if c.LeafStrategy == "" {
+		c.LeafStrategy = LeafMajority
+	}
+	return c
+}
+
+// classSet returns the distinct labels of set.
+func classSet(set TrainingSet, label string) map[string]bool {
+	classes := make(map[string]bool)
+	for _, item := range set {
+		classes[valueKey(item[label])] = true
+	}
+	return classes
+}
+
+// validLabel reports whether v can serve as a class label.
+func validLabel(v interface{}) bool {
+	if nonFinite(v) {
+		return false
+	}
+	switch v.(type) {
+	case string, bool, float32, float64, int, int32, int64:
+		return true
+	}
+	return false
+}
+
+// featureWarnings flags features that can never produce a split: those
+// holding the same value in every item, and those with only missing values.
+// Ignored attributes are not reported. With cfg.WarnUnusedIgnores,
+// IgnoredAttributes entries matching no attribute are flagged too.
+func featureWarnings(set TrainingSet, cfg Config) []string {
+	type diversity struct {
+		values  map[string]bool
+		present int
+	}
+	seen := make(map[string]*diversity)
+	attrs := make(map[string]bool)
+	for _, item := range set {
+		for attr, v := range item {
+			attrs[attr] = true
+			if cfg.excluded(attr) {
+				continue
+			}
+			d := seen[attr]
+			if d == nil {
+				d = &diversity{values: make(map[string]bool)}
+				seen[attr] = d
+			}
+			if v != nil && !nonFinite(v) {
+				d.values[valueKey(v)] = true
+				d.present++
+			}
+		}
+	}
+	var warnings []string
+	for _, attr := range featureNames(set, cfg) {
+		d := seen[attr]
+		switch {
+		case d == nil:
+		case len(d.values) == 0:
+			warnings = append(warnings, "feature "+strconv.Quote(attr)+" has only missing values")
+		case len(d.values) == 1 && d.present == len(set):
+			warnings = append(warnings, "feature "+strconv.Quote(attr)+" is constant")
+		}
 	}
+	if cfg.WarnUnusedIgnores {
+		for _, p := range cfg.IgnoredAttributes {
+			used := false
+			for attr := range attrs {
+				if ignoreMatches(p, attr) {
+					used = true
+					break
+				}
+			}
+			if !used {
+				warnings = append(warnings, "ignored attribute "+strconv.Quote(p)+" matches no attribute")
+			}
+		}
+	}
+	return warnings
+}
 
-	// Validate that category attribute exists in at least one item
-	foundCategory := false
+// checkWeights verifies every weight value in set is a finite, non-negative number.
+func checkWeights(set TrainingSet, attr string) error {
+	for i, item := range set {
+		v, ok := item[attr]
+		if !ok {
+			continue
+		}
+		if !isNumeric(v) {
+			return errors.New("training item " + strconv.Itoa(i) + " has a non-numeric weight")
+		}
+		if w := toFloat(v); w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return errors.New("training item " + strconv.Itoa(i) + " has a negative or non-finite weight")
+		}
+	}
+	return nil
+}
+
+// outputs returns the label attributes: CategoryAttrs for multi-output
+// training, otherwise just CategoryAttr.
+func (c Config) outputs() []string {
+	if len(c.CategoryAttrs) > 0 {
+		return c.CategoryAttrs
+	}
+	return []string{c.CategoryAttr}
+}
+
+// isLabel reports whether attr is CategoryAttr or one of CategoryAttrs.
+func (c Config) isLabel(attr string) bool {
+	return attr == c.CategoryAttr || stringInSlice(attr, c.CategoryAttrs)
+}
+
+// excluded reports whether attr may never be split on: a label, the weight
+// or an ignored attribute.
+func (c Config) excluded(attr string) bool {
+	return c.isLabel(attr) || attr == c.WeightAttr || c.ignored(attr)
+}
+
+// ignored reports whether attr matches an IgnoredAttributes entry.
+func (c Config) ignored(attr string) bool {
+	for _, p := range c.IgnoredAttributes {
+		if ignoreMatches(p, attr) {
+			return true
+		}
+	}
+	return false
+}
+
+// featureNames returns the sorted attributes present in set, excluding the
+// label and weight attributes.
+func featureNames(set TrainingSet, cfg Config) []string {
+	seen := make(map[string]bool)
 	for _, item := range set {
-		if _, ok := item[cfg.CategoryAttr]; ok {
-			foundCategory = true
-			break
+		for attr := range item {
+			if !cfg.isLabel(attr) && attr != cfg.WeightAttr {
+				seen[attr] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for attr := range seen {
+		names = append(names, attr)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that the configuration values are usable for training.
+func (c Config) Validate() error {
+	if c.CategoryAttr == "" && len(c.CategoryAttrs) == 0 {
+		return errors.New("config.CategoryAttr is required")
+	}
+
+	if len(c.CategoryAttrs) > 0 {
+		seen := make(map[string]bool, len(c.CategoryAttrs))
+		for _, attr := range c.CategoryAttrs {
+			if attr == "" || seen[attr] {
+				return errors.New("config.CategoryAttrs names must be non-empty and unique")
+			}
+			seen[attr] = true
+		}
+		if c.CategoryAttr != "" && c.CategoryAttr != c.CategoryAttrs[0] {
+			return errors.New("config.CategoryAttr must be empty or the first of config.CategoryAttrs")
+		}
+		if c.HistogramBins > 0 || c.AllowObliqueSplits || len(c.MonotoneConstraints) > 0 {
+			return errors.New("config.CategoryAttrs cannot be combined with HistogramBins, AllowObliqueSplits or MonotoneConstraints")
 		}
 	}
-	if !foundCategory {
-		return nil, errors.New("categoryAttr not found in any training items")
+
+	if c.MaxDepth < 0 {
+		return errors.New("config.MaxDepth cannot be negative")
 	}
 
-	// Validate configuration values
-	if cfg.MaxDepth < 0 {
-		return nil, errors.New("config.MaxDepth cannot be negative")
+	if c.MinSamples < 0 {
+		return errors.New("config.MinSamples cannot be negative")
 	}
 
-	if cfg.MinSamples < 0 {
-		return nil, errors.New("config.MinSamples cannot be negative")
+	if c.MinSamplesLeaf < 0 {
+		return errors.New("config.MinSamplesLeaf cannot be negative")
 	}
 
-	// Set default criterion if not specified
-	if cfg.Criterion == "" {
-		cfg.Criterion = "entropy"
+	if c.MaxFeatures < 0 {
+		return errors.New("config.MaxFeatures cannot be negative")
 	}
 
-	// Build the tree
-	root := makeTrainingTree(set, cfg, 0)
-	if root == nil {
-		return nil, errors.New("failed to build tree: root node is nil")
+	if c.MaxThresholds < 0 {
+		return errors.New("config.MaxThresholds cannot be negative")
+	}
+
+	if c.MaxCategories < 0 {
+		return errors.New("config.MaxCategories cannot be negative")
+	}
+
+	if c.HistogramBins < 0 || c.HistogramBins == 1 {
+		return errors.New("config.HistogramBins must be 0 or at least 2")
+	}
+
+	if !validMonotone(c.MonotoneConstraints) {
+		return errors.New("config.MonotoneConstraints values must be -1, 0 or 1")
 	}
 
-	return &Model{Root: root, Config: cfg}, nil
+	if !validOrdinal(c.OrdinalFeatures) {
+		return errors.New("config.OrdinalFeatures levels must be non-empty and unique")
+	}
+
+	if !validLeafStrategy(c.LeafStrategy) {
+		return errors.New("config.LeafStrategy must be one of majority, proportional")
+	}
+
+	if !validTieBreak(c.TieBreak) {
+		return errors.New("config.TieBreak must be one of deterministic, random")
+	}
+
+	if c.TieBreak == TieBreakRandom && c.Seed == 0 {
+		return errors.New("config.TieBreak random requires config.Seed")
+	}
+
+	if !validIgnored(c.IgnoredAttributes) {
+		return errors.New("config.IgnoredAttributes entries must be non-empty names or valid glob patterns")
+	}
+
+	if !validLexicographic(c.LexicographicFeatures, c.OrdinalFeatures) {
+		return errors.New("config.LexicographicFeatures names must be non-empty, unique and not ordinal")
+	}
+
+	if !validCriterion(c.Criterion) {
+		return errors.New("config.Criterion must be one of entropy, gini")
+	}
+
+	if c.LaplaceAlpha < 0 || math.IsNaN(c.LaplaceAlpha) {
+		return errors.New("config.LaplaceAlpha cannot be negative")
+	}
+
+	if !(c.ChiSquarePValue >= 0 && c.ChiSquarePValue <= 1) {
+		return errors.New("config.ChiSquarePValue must be between 0 and 1")
+	}
+
+	if !validMissingStrategy(c.MissingStrategy) {
+		return errors.New("config.MissingStrategy must be one of majority, match, nomatch, fail")
+	}
+
+	return nil
+}
+
+// builder holds the state shared by the recursive calls of one training run.
+type builder struct {
+	cfg Config
+	rng *rand.Rand
+	// tieRng breaks ties for TieBreakRandom, apart from rng so it does not
+	// shift the draws of other randomized options.
+	tieRng *rand.Rand
+	// ctx is checked as the tree grows; the first error it reports is kept
+	// in err and unwinds the remaining recursion.
+	ctx context.Context
+	err error
+	// positive is the class whose probability MonotoneConstraints govern.
+	positive string
+	// hist holds the bin boundaries of attributes split by histogram.
+	hist map[string][]float64
+	// wide holds the categorical attributes over MaxCategories, with their
+	// category counts; they get no categorical splits.
+	wide map[string]int
+	// log receives Verbose output; nil when Verbose is off.
+	log io.Writer
+	// exhaustive turns off presorted numeric split search, so tests can
+	// check it against the plain scan.
+	exhaustive bool
+}
+
+// bounds limits the positive-class probability allowed in a subtree so that
+// monotone constraints hold across the whole tree, not just at each split.
+type bounds struct{ lo, hi float64 }
+
+var fullBounds = bounds{0, 1}
+
+func (bd bounds) contains(p float64) bool { return p >= bd.lo && p <= bd.hi }
+
+// positiveRate returns the share (by weight, if configured) of the positive
+// class in a partition with the given counts.
+func (b *builder) positiveRate(side TrainingSet, counts map[string]int) float64 {
+	if b.cfg.WeightAttr != "" {
+		weights, total := b.tally(side)
+		if total == 0 {
+			return 0
+		}
+		return weights[b.positive] / total
+	}
+	return float64(counts[b.positive]) / float64(len(side))
+}
+
+// monotoneChildBounds checks a binary split against the monotone constraints
+// and, if it is allowed, returns the bounds for its Match and NoMatch
+// children. Splits that are unconstrained pass bnd down unchanged.
+func (b *builder) monotoneChildBounds(s splitResult, bnd bounds) (bounds, bounds, bool) {
+	pm := b.positiveRate(s.Match, s.MatchCounts)
+	pn := b.positiveRate(s.NoMatch, s.NoMatchCounts)
+	if !bnd.contains(pm) || !bnd.contains(pn) {
+		return bnd, bnd, false
+	}
+	dir := 0
+	if s.PredicateName == ">=" {
+		dir = b.cfg.MonotoneConstraints[s.Attribute]
+	}
+	mid := (pm + pn) / 2
+	switch {
+	case dir > 0 && pm >= pn:
+		return bounds{mid, bnd.hi}, bounds{bnd.lo, mid}, true
+	case dir < 0 && pm <= pn:
+		return bounds{bnd.lo, mid}, bounds{mid, bnd.hi}, true
+	case dir == 0:
+		return bnd, bnd, true
+	}
+	return bnd, bnd, false
+}
+
+func newBuilder(cfg Config) *builder {
+	b := &builder{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed)), ctx: context.Background()}
+	if cfg.TieBreak == TieBreakRandom {
+		b.tieRng = rand.New(rand.NewSource(cfg.Seed))
+	}
+	if cfg.Verbose {
+		b.log = cfg.LogOutput
+		if b.log == nil {
+			b.log = os.Stderr
+		}
+	}
+	return b
+}
+
+// usablePartition reports whether a binary split sending nMatch of n samples
+// to Match may be a candidate. A split that sends every sample to one side
+// cannot separate anything and would leave an empty child with no category,
+// so both sides must be non-empty and hold at least MinSamplesLeaf samples.
+func (b *builder) usablePartition(nMatch, n int) bool {
+	return nMatch > 0 && nMatch < n && nMatch >= b.cfg.MinSamplesLeaf && n-nMatch >= b.cfg.MinSamplesLeaf
+}
+
+// significant reports whether split passes the Config.ChiSquarePValue test.
+func (b *builder) significant(split splitResult) bool {
+	tables := []map[string]int{split.MatchCounts, split.NoMatchCounts}
+	if split.Groups != nil {
+		keys := make([]string, 0, len(split.GroupCounts))
+		for k := range split.GroupCounts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		tables = tables[:0]
+		for _, k := range keys {
+			tables = append(tables, split.GroupCounts[k])
+		}
+		if split.Missing != nil {
+			tables = append(tables, split.MissingCounts)
+		}
+	}
+	return splitPValue(tables) < b.cfg.ChiSquarePValue
+}
+
+// logNode writes one Verbose line for a node, indented by depth.
+func (b *builder) logNode(depth, samples int, msg string) {
+	if b.log == nil {
+		return
+	}
+	indent := ""
+	for i := 0; i < depth; i++ {
+		indent += "  "
+	}
+	io.WriteString(b.log, indent+"[depth "+strconv.Itoa(depth)+"] "+strconv.Itoa(samples)+" samples: "+msg+"\n")
+}
+
+// logLeaf logs a node that became a leaf and why.
+func (b *builder) logLeaf(depth, samples int, n *TreeItem, reason string) *TreeItem {
+	b.logNode(depth, samples, "leaf "+n.Category+" ("+reason+")")
+	return n
+}
+
+// cancelled reports whether training should stop, recording the context error.
+func (b *builder) cancelled() bool {
+	if b.err == nil {
+		b.err = b.ctx.Err()
+	}
+	return b.err != nil
+}
+
+// impurity scores class counts with the configured criterion.
+func (b *builder) impurity(counts map[string]int, total int) float64 {
+	if b.cfg.Criterion == CriterionGini {
+		return giniFromCounts(counts, total)
+	}
+	return entropyFromCounts(counts, total)
+}
+
+// weight returns item's sample weight: its WeightAttr value, or 1.
+func (b *builder) weight(item TrainingItem) float64 {
+	if v, ok := item[b.cfg.WeightAttr]; ok && isNumeric(v) {
+		return toFloat(v)
+	}
+	return 1
 }
 
-func makeTrainingTree(set TrainingSet, cfg Config, depth int) *TreeItem {
+// tally sums sample weights per class over set.
+func (b *builder) tally(set TrainingSet) (map[string]float64, float64) {
+	weights := make(map[string]float64)
+	total := 0.0
+	for _, item := range set {
+		w := b.weight(item)
+		weights[valueKey(item[b.cfg.CategoryAttr])] += w
+		total += w
+	}
+	return weights, total
+}
+
+// sideImpurity scores one partition and returns its impurity and size. The
+// size is the item count, or the total sample weight when WeightAttr is set.
+func (b *builder) sideImpurity(side TrainingSet, counts map[string]int) (float64, float64) {
+	if len(b.cfg.CategoryAttrs) > 0 {
+		return b.outputsImpurity(side)
+	}
+	if b.cfg.WeightAttr == "" {
+		return b.impurity(counts, len(side)), float64(len(side))
+	}
+	weights, total := b.tally(side)
+	if b.cfg.Criterion == CriterionGini {
+		return giniFromWeights(weights, total), total
+	}
+	return entropyFromWeights(weights, total), total
+}
+
+// outputsImpurity sums the impurity of every label of a multi-output model
+// over side, and returns it with the size of side as for sideImpurity.
+func (b *builder) outputsImpurity(side TrainingSet) (float64, float64) {
+	sum, size := 0.0, 0.0
+	for _, attr := range b.cfg.CategoryAttrs {
+		weights := make(map[string]float64)
+		size = 0
+		for _, item := range side {
+			w := b.weight(item)
+			weights[valueKey(item[attr])] += w
+			size += w
+		}
+		sum += b.weightedImpurity(weights, size)
+	}
+	return sum, size
+}
+
+// outputCounts returns the class counts of every label over set for
+// multi-output models, or nil.
+func (b *builder) outputCounts(set TrainingSet) map[string]map[string]int {
+	if len(b.cfg.CategoryAttrs) == 0 {
+		return nil
+	}
+	out := make(map[string]map[string]int, len(b.cfg.CategoryAttrs))
+	for _, attr := range b.cfg.CategoryAttrs {
+		out[attr] = counterUniqueValues(set, attr)
+	}
+	return out
+}
+
+// leaf builds a leaf for set. With WeightAttr set the category is the class
+// with the largest total weight rather than the most frequent one.
+func (b *builder) leaf(set TrainingSet, counts map[string]int) *TreeItem {
+	n := leafFromCounts(counts)
+	n.OutputCounts = b.outputCounts(set)
+	if b.cfg.WeightAttr != "" {
+		weights, _ := b.tally(set)
+		n.Category = heaviestClass(weights)
+	}
+	return n
+}
+
+// heaviestClass returns the key with the largest weight, breaking ties by
+// the lexicographically smallest key.
+func heaviestClass(weights map[string]float64) string {
+	keys := make([]string, 0, len(weights))
+	for k := range weights {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	best := ""
+	for i, k := range keys {
+		if i == 0 || weights[k] > weights[best] {
+			best = k
+		}
+	}
+	return best
+}
+
+// candidateAttributes returns the attributes eligible for splitting at a node,
+// or nil when every attribute may be considered. With MaxFeatures set, a
+// seeded random subset of that size is drawn.
+func (b *builder) candidateAttributes(set TrainingSet) map[string]bool {
+	if b.cfg.MaxFeatures <= 0 {
+		return nil
+	}
+	present := make(map[string]bool)
+	for _, item := range set {
+		for attr := range item {
+			if b.cfg.excluded(attr) {
+				continue
+			}
+			present[attr] = true
+		}
+	}
+	if len(present) <= b.cfg.MaxFeatures {
+		return nil
+	}
+	// Sort before sampling so a given seed always picks the same attributes.
+	attrs := make([]string, 0, len(present))
+	for attr := range present {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+	chosen := make(map[string]bool, b.cfg.MaxFeatures)
+	for _, i := range b.rng.Perm(len(attrs))[:b.cfg.MaxFeatures] {
+		chosen[attrs[i]] = true
+	}
+	return chosen
+}
+
+// splittableAttributes returns the sorted attributes present in set that may
+// be split on: not the label, weight or ignored attributes, and in allowed
+// when it is non-nil.
+func (b *builder) splittableAttributes(set TrainingSet, allowed map[string]bool) []string {
+	present := make(map[string]bool)
+	for _, item := range set {
+		for attr := range item {
+			present[attr] = true
+		}
+	}
+	attrs := make([]string, 0, len(present))
+	for attr := range present {
+		if b.cfg.excluded(attr) {
+			continue
+		}
+		if allowed != nil && !allowed[attr] {
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+// quantilePivots picks, for each numeric attribute, at most n candidate
+// pivots at evenly spaced quantiles of its values in set. Attributes with no
+// more than n distinct values keep all of them.
+func quantilePivots(set TrainingSet, attrs []string, n int) map[string]map[float64]bool {
+	out := make(map[string]map[float64]bool, len(attrs))
+	for _, attr := range attrs {
+		var values []float64
+		distinct := make(map[float64]bool)
+		for _, item := range set {
+			if v, ok := item[attr]; ok && finiteNumber(v) {
+				f := toFloat(v)
+				values = append(values, f)
+				distinct[f] = true
+			}
+		}
+		if len(distinct) <= n {
+			out[attr] = distinct
+			continue
+		}
+		sort.Float64s(values)
+		chosen := make(map[float64]bool, n)
+		for k := 1; k <= n; k++ {
+			chosen[values[k*len(values)/(n+1)]] = true
+		}
+		out[attr] = chosen
+	}
+	return out
+}
+
+func (b *builder) makeTrainingTree(set TrainingSet, depth int, bnd bounds) *TreeItem {
+	cfg := b.cfg
+	if b.cancelled() {
+		return nil
+	}
+	if cfg.Observer != nil {
+		cfg.Observer.OnNodeCreated(depth, len(set))
+	}
 	// stopping conditions
 	if len(set) == 0 {
-		return &TreeItem{Category: ""}
+		return b.logLeaf(depth, 0, &TreeItem{Category: ""}, "empty node")
 	}
+	counts := counterUniqueValues(set, cfg.CategoryAttr)
+	initImpurity, size := b.sideImpurity(set, counts)
 	// If pure or thresholds reached -> leaf
-	if entropy(set, cfg.CategoryAttr) <= 0.00001 ||
-		(cfg.MaxDepth > 0 && depth >= cfg.MaxDepth) ||
-		(cfg.MinSamples > 0 && len(set) < cfg.MinSamples) {
-		return leafFromSet(set, cfg.CategoryAttr)
+	switch {
+	case initImpurity <= 0.00001:
+		return b.logLeaf(depth, len(set), b.leaf(set, counts), "pure node")
+	case cfg.MaxDepth > 0 && depth >= cfg.MaxDepth:
+		return b.logLeaf(depth, len(set), b.leaf(set, counts), "max depth")
+	case cfg.MinSamples > 0 && len(set) < cfg.MinSamples:
+		return b.logLeaf(depth, len(set), b.leaf(set, counts), "min samples")
+	}
+
+	best, found := b.findSplit(set, counts, initImpurity, size, bnd)
+	if b.err != nil {
+		return nil
 	}
 
-	initEntropy := entropy(set, cfg.CategoryAttr)
+	// No candidate, or only candidates with (numerically) zero gain -> leaf.
+	if !found || best.Gain <= minGain {
+		return b.logLeaf(depth, len(set), b.leaf(set, counts), "no gain")
+	}
+	if cfg.ChiSquarePValue > 0 && !b.significant(best) {
+		return b.logLeaf(depth, len(set), b.leaf(set, counts), "not significant")
+	}
+	if b.log != nil {
+		split := (&TreeItem{Attribute: best.Attribute, PredicateName: best.PredicateName, Pivot: best.Pivot, PivotSet: best.PivotSet, Oblique: best.Oblique}).condition()
+		if best.Groups != nil {
+			n := len(best.Groups)
+			if best.Missing != nil {
+				n++
+			}
+			split = best.Attribute + " into " + strconv.Itoa(n) + " branches"
+		}
+		b.logNode(depth, len(set), "split on "+split+" (gain "+strconv.FormatFloat(best.Gain, 'f', 4, 64)+")")
+	}
+
+	if best.Groups != nil {
+		children := make(map[string]*TreeItem, len(best.Groups))
+		for k, group := range best.Groups {
+			children[k] = b.makeTrainingTree(group, depth+1, bnd)
+		}
+		var missing *TreeItem
+		if best.Missing != nil {
+			missing = b.makeTrainingTree(best.Missing, depth+1, bnd)
+		}
+		return &TreeItem{
+			Children:      children,
+			Missing:       missing,
+			Attribute:     best.Attribute,
+			PredicateName: best.PredicateName,
+			ClassCounts:   counts,
+			OutputCounts:  b.outputCounts(set),
+			Gain:          best.Gain,
+		}
+	}
+
+	matchBounds, noMatchBounds := bnd, bnd
+	if len(cfg.MonotoneConstraints) > 0 {
+		matchBounds, noMatchBounds, _ = b.monotoneChildBounds(best, bnd)
+	}
+	var categories []string
+	if cfg.UnseenAsMissing && (best.PredicateName == "==" || best.PredicateName == "subset") {
+		categories = categoryValues(set, best.Attribute)
+	}
+	return &TreeItem{
+		Match:          b.makeTrainingTree(best.Match, depth+1, matchBounds),
+		NoMatch:        b.makeTrainingTree(best.NoMatch, depth+1, noMatchBounds),
+		MatchedCount:   len(best.Match),
+		NoMatchedCount: len(best.NoMatch),
+		Attribute:      best.Attribute,
+		PredicateName:  best.PredicateName,
+		Pivot:          best.Pivot,
+		PivotSet:       best.PivotSet,
+		Categories:     categories,
+		Oblique:        best.Oblique,
+		ClassCounts:    counts,
+		OutputCounts:   b.outputCounts(set),
+		Gain:           best.Gain,
+	}
+}
+
+// findSplit evaluates every candidate split of set, which has the given
+// class counts and impurity initImpurity over size samples, and returns the
+// best, or false when no candidate is usable or training was cancelled.
+func (b *builder) findSplit(set TrainingSet, counts map[string]int, initImpurity, size float64, bnd bounds) (splitResult, bool) {
+	cfg := b.cfg
 	var best splitResult
+	// found tracks whether any usable candidate was evaluated, so a zero-valued
+	// best is never mistaken for a real split.
+	found := false
+	// ties counts the candidates tied with best, for TieBreakRandom.
+	ties := 0
+	// Presorted numeric candidates carry only their gain; lazy marks a best
+	// whose items are partitioned once the search is over.
+	presort, lazy := b.presorts(), false
+	var sweeps map[string]map[float64]numericGain
+	if presort {
+		sweeps = make(map[string]map[float64]numericGain)
+	}
+	// Identical (attribute, pivot) pairs produce identical splits; evaluate each once.
+	seen := make(map[candidateKey]bool)
+	multiwaySeen := make(map[string]bool)
+	subsetSeen := make(map[string]bool)
+	allowed := b.candidateAttributes(set)
+	// Visit attributes in sorted order so ties in gain always resolve the
+	// same way, independent of map iteration order.
+	attrs := b.splittableAttributes(set, allowed)
+	var pivots map[string]map[float64]bool
+	if cfg.MaxThresholds > 0 {
+		pivots = quantilePivots(set, attrs, cfg.MaxThresholds)
+	}
 
 	for _, item := range set {
-		for attr, pivot := range item {
-			if attr == cfg.CategoryAttr || stringInSlice(attr, cfg.IgnoredAttributes) {
+		// Large nodes can take a while; check between items too.
+		if b.cancelled() {
+			return splitResult{}, false
+		}
+		for _, attr := range attrs {
+			pivot, ok := item[attr]
+			if !ok || b.hist[attr] != nil || nonFinite(pivot) {
 				continue
 			}
 
 			var pred Predicate
 			var predName string
 			// auto-detect numeric vs categorical by pivot type
-			if isNumeric(pivot) {
+			if levels, ok := cfg.OrdinalFeatures[attr]; ok {
+				// Ordinal: threshold on the level order; unknown levels
+				// never match, like missing numeric values.
+				if ordinalIndex(levels, pivot) < 0 {
+					continue
+				}
+				pivot = valueKey(pivot)
+				pred = func(a, p interface{}) bool {
+					ia := ordinalIndex(levels, a)
+					return ia >= 0 && ia >= ordinalIndex(levels, p)
+				}
+				predName = ">="
+			} else if stringInSlice(attr, cfg.LexicographicFeatures) {
+				// Lexicographic: threshold on string order; other values
+				// never match, like missing numeric values.
+				if _, ok := pivot.(string); !ok {
+					continue
+				}
+				pred = predicateGte
+				predName = ">="
+			} else if isNumeric(pivot) {
+				if cfg.RandomSplits {
+					continue
+				}
 				pred = predicateGte
 				predName = ">="
 				pivot = toFloat(pivot)
+				if pivots != nil && !pivots[attr][pivot.(float64)] {
+					continue
+				}
+			} else if _, ok := b.wide[attr]; ok {
+				continue
+			} else if cfg.MultiwaySplits {
+				if multiwaySeen[attr] {
+					continue
+				}
+				multiwaySeen[attr] = true
+				if cfg.Observer != nil {
+					cfg.Observer.OnSplitEvaluated(attr)
+				}
+				if curr, ok := b.evalMultiway(set, attr, initImpurity, size, bnd); ok && b.better(curr, best, found, &ties) {
+					best = curr
+					found, lazy = true, false
+				}
+				continue
 			} else {
+				if cfg.SubsetSplits && !subsetSeen[attr] {
+					subsetSeen[attr] = true
+					if cfg.Observer != nil {
+						cfg.Observer.OnSplitEvaluated(attr)
+					}
+					if curr, ok := b.evalSubset(set, attr, counts, initImpurity, size, bnd); ok && b.better(curr, best, found, &ties) {
+						best = curr
+						found, lazy = true, false
+					}
+				}
 				pred = predicateEq
 				predName = "=="
 			}
 
-			curr := split(set, attr, pred, pivot)
-			// information gain
-			matchE := entropy(curr.Match, cfg.CategoryAttr)
-			noMatchE := entropy(curr.NoMatch, cfg.CategoryAttr)
-			newE := (matchE*float64(len(curr.Match)) + noMatchE*float64(len(curr.NoMatch))) / float64(len(set))
-			curr.Gain = initEntropy - newE
+			key := candidateKey{attr: attr, pivot: pivot}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if cfg.Observer != nil {
+				cfg.Observer.OnSplitEvaluated(attr)
+			}
+
+			if presort && predName == ">=" && isNumeric(pivot) {
+				if sweeps[attr] == nil {
+					sweeps[attr] = b.numericGains(set, attr, initImpurity, size)
+				}
+				g := sweeps[attr][pivot.(float64)]
+				if !b.usablePartition(g.nMatch, len(set)) {
+					continue
+				}
+				curr := splitResult{Gain: g.gain, Attribute: attr, Pivot: pivot, Predicate: &pred, PredicateName: predName}
+				if b.better(curr, best, found, &ties) {
+					best = curr
+					found, lazy = true, true
+				}
+				continue
+			}
+
+			curr := splitCounted(set, attr, cfg.CategoryAttr, pred, pivot)
+			if !b.usablePartition(len(curr.Match), len(set)) {
+				continue
+			}
+			// impurity decrease (information gain for entropy)
+			matchI, matchN := b.sideImpurity(curr.Match, curr.MatchCounts)
+			noMatchI, noMatchN := b.sideImpurity(curr.NoMatch, curr.NoMatchCounts)
+			newI := (matchI*matchN + noMatchI*noMatchN) / size
+			curr.Gain = initImpurity - newI
 			curr.Attribute = attr
 			curr.Pivot = pivot
 			curr.Predicate = &pred
 			curr.PredicateName = predName
-			if curr.Gain > best.Gain {
+			if len(cfg.MonotoneConstraints) > 0 {
+				if _, _, ok := b.monotoneChildBounds(curr, bnd); !ok {
+					continue
+				}
+			}
+			if b.better(curr, best, found, &ties) {
 				best = curr
+				found, lazy = true, false
 			}
 		}
 	}
 
-	if best.Gain <= 0 {
-		return leafFromSet(set, cfg.CategoryAttr)
+	for _, attr := range attrs {
+		if th := b.hist[attr]; th != nil {
+			if curr, ok := b.evalHistogram(set, attr, th, counts, initImpurity, size, bnd); ok && b.better(curr, best, found, &ties) {
+				best = curr
+				found, lazy = true, false
+			}
+		} else if cfg.RandomSplits && b.randomSplittable(attr) {
+			if curr, ok := b.evalRandomThreshold(set, attr, initImpurity, size, bnd); ok && b.better(curr, best, found, &ties) {
+				best = curr
+				found, lazy = true, false
+			}
+		}
 	}
 
-	return &TreeItem{
-		Match:          makeTrainingTree(best.Match, cfg, depth+1),
-		NoMatch:        makeTrainingTree(best.NoMatch, cfg, depth+1),
-		MatchedCount:   len(best.Match),
-		NoMatchedCount: len(best.NoMatch),
-		Attribute:      best.Attribute,
-		PredicateName:  best.PredicateName,
-		Pivot:          best.Pivot,
-		ClassCounts:    counterUniqueValues(set, cfg.CategoryAttr),
+	// Oblique splits must beat the best axis-aligned split outright, so
+	// they never replace an equally good simpler one.
+	if cfg.AllowObliqueSplits {
+		if curr, ok := b.evalOblique(set, attrs, initImpurity, size, bnd); ok && (!found || curr.Gain > best.Gain+minGain) {
+			best = curr
+			found, lazy = true, false
+		}
+	}
+	if lazy {
+		part := splitCounted(set, best.Attribute, cfg.CategoryAttr, predicateGte, best.Pivot)
+		best.Match, best.NoMatch = part.Match, part.NoMatch
+		best.MatchCounts, best.NoMatchCounts = part.MatchCounts, part.NoMatchCounts
+	}
+	return best, found
+}
+
+// better reports whether curr should replace best, the best split so far
+// at a node, applying Config.TieBreak to equal gains. ties counts the
+// candidates tied with best, including it.
+func (b *builder) better(curr, best splitResult, found bool, ties *int) bool {
+	if !found {
+		*ties = 1
+		return true
+	}
+	if b.cfg.TieBreak == "" {
+		return curr.Gain > best.Gain
+	}
+	switch {
+	case curr.Gain > best.Gain+minGain:
+		*ties = 1
+		return true
+	case curr.Gain < best.Gain-minGain:
+		return false
+	case b.cfg.TieBreak == TieBreakRandom:
+		// Reservoir sampling keeps each tied candidate with equal chance.
+		*ties++
+		return b.tieRng.Intn(*ties) == 0
+	}
+	if curr.Attribute != best.Attribute {
+		return curr.Attribute < best.Attribute
+	}
+	return pivotLess(curr.Pivot, best.Pivot)
+}
+
+// pivotLess orders split pivots: none (multiway splits) first, then
+// numbers by value, then other values by their text.
+func pivotLess(a, b interface{}) bool {
+	switch {
+	case a == nil || b == nil:
+		return a == nil && b != nil
+	case isNumeric(a) && isNumeric(b):
+		return toFloat(a) < toFloat(b)
+	case isNumeric(a) != isNumeric(b):
+		return isNumeric(a)
+	}
+	return valueKey(a) < valueKey(b)
+}
+
+// evalMultiway scores a one-child-per-value split on attr. It reports false
+// when the split is unusable: fewer than two groups, or a group smaller
+// than MinSamplesLeaf.
+func (b *builder) evalMultiway(set TrainingSet, attr string, initImpurity, size float64, bnd bounds) (splitResult, bool) {
+	curr := splitMultiway(set, attr, b.cfg.CategoryAttr)
+	if len(curr.Groups) == 0 || (len(curr.Groups) < 2 && curr.Missing == nil) {
+		return curr, false
+	}
+	newI := 0.0
+	score := func(group TrainingSet, counts map[string]int) bool {
+		if len(group) < b.cfg.MinSamplesLeaf {
+			return false
+		}
+		if len(b.cfg.MonotoneConstraints) > 0 && !bnd.contains(b.positiveRate(group, counts)) {
+			return false
+		}
+		groupI, groupN := b.sideImpurity(group, counts)
+		newI += groupI * groupN
+		return true
+	}
+	for k, group := range curr.Groups {
+		if !score(group, curr.GroupCounts[k]) {
+			return curr, false
+		}
 	}
+	if curr.Missing != nil && !score(curr.Missing, curr.MissingCounts) {
+		return curr, false
+	}
+	curr.Gain = initImpurity - newI/size
+	curr.Attribute = attr
+	curr.PredicateName = "in"
+	return curr, true
 }
 
-func leafFromSet(set TrainingSet, labelAttr string) *TreeItem {
-	counts := counterUniqueValues(set, labelAttr)
-	mostVal := mostFrequentValue(counts)
-	return &TreeItem{Category: mostVal, ClassCounts: counts}
+// maxSubsetCategories is the most distinct values an attribute may have at
+// a node for Config.SubsetSplits to group them.
+const maxSubsetCategories = 32
+
+// evalSubset scores splits of attr that send a group of its categorical
+// values to Match. The values are ordered by their share of the node's
+// majority class (counts gives the node's classes) and every prefix of two
+// or more values, short of all of them, is tried; single values are left to
+// == splits. It reports false unless attr has 3 to maxSubsetCategories
+// distinct values and some prefix is usable.
+func (b *builder) evalSubset(set TrainingSet, attr string, counts map[string]int, initImpurity, size float64, bnd bounds) (splitResult, bool) {
+	cfg := b.cfg
+	majority := mostFrequentValue(counts)
+	hits := make(map[string]int)
+	seen := make(map[string]int)
+	for _, item := range set {
+		v := item[attr]
+		if v == nil || isNumeric(v) {
+			continue
+		}
+		k := valueKey(v)
+		seen[k]++
+		if valueKey(item[cfg.CategoryAttr]) == majority {
+			hits[k]++
+		}
+	}
+	if len(seen) < 3 || len(seen) > maxSubsetCategories {
+		return splitResult{}, false
+	}
+	values := make([]string, 0, len(seen))
+	for k := range seen {
+		values = append(values, k)
+	}
+	share := func(k string) float64 { return float64(hits[k]) / float64(seen[k]) }
+	sort.Slice(values, func(i, j int) bool {
+		si, sj := share(values[i]), share(values[j])
+		if si != sj {
+			return si < sj
+		}
+		return values[i] < values[j]
+	})
+
+	var best splitResult
+	found := false
+	for n := 2; n < len(values); n++ {
+		group := cloneStrings(values[:n])
+		sort.Strings(group)
+		pred := Predicate(predicateIn)
+		curr := splitCounted(set, attr, cfg.CategoryAttr, pred, group)
+		if !b.usablePartition(len(curr.Match), len(set)) {
+			continue
+		}
+		matchI, matchN := b.sideImpurity(curr.Match, curr.MatchCounts)
+		noMatchI, noMatchN := b.sideImpurity(curr.NoMatch, curr.NoMatchCounts)
+		curr.Gain = initImpurity - (matchI*matchN+noMatchI*noMatchN)/size
+		curr.Attribute = attr
+		curr.PivotSet = group
+		curr.Predicate = &pred
+		curr.PredicateName = "subset"
+		if len(cfg.MonotoneConstraints) > 0 {
+			if _, _, ok := b.monotoneChildBounds(curr, bnd); !ok {
+				continue
+			}
+		}
+		if !found || curr.Gain > best.Gain {
+			best = curr
+			found = true
+		}
+	}
+	return best, found
+}
+
+// categoryValues returns the sorted distinct categorical values of attr in
+// set, leaving out missing and numeric values.
+func categoryValues(set TrainingSet, attr string) []string {
+	seen := make(map[string]bool)
+	for _, item := range set {
+		if v := item[attr]; v != nil && !isNumeric(v) {
+			seen[valueKey(v)] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// predicateIn reports whether a is a categorical value listed in group, a
+// sorted []string. Missing and numeric values never match.
+func predicateIn(a, group interface{}) bool {
+	if a == nil || isNumeric(a) {
+		return false
+	}
+	g := group.([]string)
+	k := valueKey(a)
+	i := sort.SearchStrings(g, k)
+	return i < len(g) && g[i] == k
+}
+
+// leafFromCounts builds a leaf predicting the most frequent class in counts.
+func leafFromCounts(counts map[string]int) *TreeItem {
+	return &TreeItem{Category: mostFrequentValue(counts), ClassCounts: counts}
 }
 
 // mostFrequentValue returns the most common key in counts.
@@ -228,12 +1623,11 @@ func mostFrequentValue(counts map[string]int) string {
 	}
 	sort.Strings(keys)
 
-	var bestK string
-	var bestV int
-	for _, k := range keys {
-		v := counts[k]
-		// Pick this key if it has a higher count, or if it's the first valid key
-		if v > bestV || bestK == "" {
+	// Keys are sorted, so only a strictly higher count displaces the
+	// smallest key among equals.
+	bestK, bestV := keys[0], counts[keys[0]]
+	for _, k := range keys[1:] {
+		if v := counts[k]; v > bestV {
 			bestK, bestV = k, v
 		}
 	}
@@ -248,6 +1642,24 @@ func isNumeric(v interface{}) bool {
 	return t == reflect.Int || t == reflect.Int32 || t == reflect.Int64 || t == reflect.Float32 || t == reflect.Float64
 }
 
+// nonFinite reports whether v is a NaN or infinite float. Training and
+// prediction treat such values as missing.
+func nonFinite(v interface{}) bool {
+	switch vv := v.(type) {
+	case float32:
+		f := float64(vv)
+		return math.IsNaN(f) || math.IsInf(f, 0)
+	case float64:
+		return math.IsNaN(vv) || math.IsInf(vv, 0)
+	}
+	return false
+}
+
+// finiteNumber reports whether v is a number other than NaN or an infinity.
+func finiteNumber(v interface{}) bool {
+	return isNumeric(v) && !nonFinite(v)
+}
+
 func toFloat(v interface{}) float64 {
 	switch vv := v.(type) {
 	case int:
@@ -264,68 +1676,10 @@ func toFloat(v interface{}) float64 {
 	return 0
 }
 
+// formatFloatKey is the canonical text form of a number, used for class
+// labels, count keys and rendered pivots: the shortest decimal that
+// round-trips, without exponent, so distinct values never share a key and
+// integral values print without a fraction (1.0 -> "1").
 func formatFloatKey(f float64) string {
-	// coarse formatting to stabilize keys in counts; avoid scientific notation for integers
-	if f == math.Trunc(f) {
-		return fmtInt(int64(f))
-	}
-	return trimFloat(f)
-}
-
-// minimal helpers to avoid importing fmt
-func fmtInt(i int64) string { return itoa(i) }
-
-func itoa(i int64) string {
-	// simple base10 itoa
-	if i == 0 {
-		return "0"
-	}
-	neg := i < 0
-	if neg {
-		i = -i
-	}
-	buf := make([]byte, 0, 20)
-	for i > 0 {
-		d := i % 10
-		buf = append([]byte{'0' + byte(d)}, buf...)
-		i /= 10
-	}
-	if neg {
-		buf = append([]byte{'-'}, buf...)
-	}
-	return string(buf)
-}
-
-func trimFloat(f float64) string {
-	// round to 6 decimals for keys
-	const p = 1e6
-	v := math.Round(f*p) / p
-	// simple formatter: integer-like if whole
-	if v == math.Trunc(v) {
-		return fmtInt(int64(v))
-	}
-	// manually craft with limited decimals
-	s := []byte{}
-	if v < 0 {
-		s = append(s, '-')
-		v = -v
-	}
-	ip := int64(math.Trunc(v))
-	fp := v - float64(ip)
-	s = append(s, []byte(fmtInt(ip))...)
-	s = append(s, '.')
-	for k := 0; k < 6; k++ {
-		fp *= 10
-		d := int(fp)
-		s = append(s, byte('0'+d))
-		fp -= float64(d)
-	}
-	// trim trailing zeros
-	for len(s) > 0 && s[len(s)-1] == '0' {
-		s = s[:len(s)-1]
-	}
-	if len(s) > 0 && s[len(s)-1] == '.' {
-		s = s[:len(s)-1]
-	}
-	return string(s)
+	return strconv.FormatFloat(f, 'f', -1, 64)
 }
@@ -1,7 +1,20 @@
 package dtree
 
+import (
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is the library version recorded in the metadata of trained models.
+const Version = "0.2.0"
+
 // TrainingItem represents a single row with arbitrary attributes.
 // Values may be string or numeric (int/float64). Numeric detection is automatic.
+// NaN and infinite floats are treated as missing values.
 type TrainingItem map[string]interface{}
 
 // TrainingSet is a collection of training items.
@@ -11,34 +24,286 @@ type TrainingSet []TrainingItem
 type Config struct {
 	// CategoryAttr is the label/target attribute to predict (required).
 	CategoryAttr string `json:"categoryAttr"`
-	// IgnoredAttributes will be excluded when searching for splits.
+	// CategoryAttrs, when set, trains one tree for several labels at once
+	// (multi-output classification). Splits minimize the summed impurity of
+	// all of them and every node records OutputCounts per label; use
+	// PredictMulti to predict them all. CategoryAttr is set to the first
+	// entry, so Predict, ClassCounts and ChiSquarePValue refer to that label.
+	// It cannot be combined with HistogramBins, AllowObliqueSplits or
+	// MonotoneConstraints.
+	CategoryAttrs []string `json:"categoryAttrs,omitempty"`
+	// IgnoredAttributes will be excluded when searching for splits. Entries
+	// may be glob patterns in path.Match syntax, such as "tmp_*", matched
+	// against each attribute name.
 	IgnoredAttributes []string `json:"ignoredAttributes,omitempty"`
-	// Criterion selects the split criterion. Currently supports "entropy" only.
+	// WarnUnusedIgnores adds a Metadata.Warnings entry for every
+	// IgnoredAttributes entry that matches no attribute of the training
+	// data, which usually means a typo.
+	WarnUnusedIgnores bool `json:"warnUnusedIgnores,omitempty"`
+	// WarnLeakage adds a Metadata.Warnings entry for every feature that
+	// alone separates the classes almost perfectly, as DetectLeakage finds,
+	// which usually means it was derived from the label.
+	WarnLeakage bool `json:"warnLeakage,omitempty"`
+	// Criterion selects the split criterion: "entropy" (default) or "gini".
 	Criterion string `json:"criterion,omitempty"`
 	// MaxDepth limits the depth of the tree. 0 means unlimited.
 	MaxDepth int `json:"maxDepth,omitempty"`
 	// MinSamples stops splitting when a node has fewer than MinSamples. 0 means no limit.
 	MinSamples int `json:"minSamples,omitempty"`
+	// MinSamplesLeaf rejects splits that leave either child with fewer samples. 0 means no limit.
+	MinSamplesLeaf int `json:"minSamplesLeaf,omitempty"`
+	// MaxFeatures is the number of attributes randomly considered at each node. 0 means all.
+	MaxFeatures int `json:"maxFeatures,omitempty"`
+	// Seed seeds the random number generator used by randomized training options.
+	Seed int64 `json:"seed,omitempty"`
+	// TieBreak picks between candidate splits whose gains are equal (within
+	// floating-point noise): TieBreakDeterministic takes the smallest
+	// attribute name, then the smallest pivot; TieBreakRandom picks one
+	// uniformly at random from its own generator seeded with Seed, which it
+	// requires, so other randomized options draw the same numbers either
+	// way. Empty keeps the first candidate found, visiting items in order
+	// and attributes sorted.
+	TieBreak string `json:"tieBreak,omitempty"`
+	// StrictPredict makes Predict and PredictProba return an error when an item
+	// lacks an attribute the tree splits on, instead of following the child
+	// that saw more training samples.
+	StrictPredict bool `json:"strictPredict,omitempty"`
+	// MissingStrategy controls how prediction routes an item whose split
+	// attribute is absent, nil, NaN or infinite. One of MissingMajority
+	// (default), MissingMatch, MissingNoMatch or MissingFail.
+	MissingStrategy string `json:"missingStrategy,omitempty"`
+	// UnseenAsMissing records at each == and subset node the categorical
+	// values that reached it in training (TreeItem.Categories), and routes
+	// items whose value is not among them by MissingStrategy instead of to
+	// NoMatch, which stands for the other training values rather than for
+	// every possible one.
+	UnseenAsMissing bool `json:"unseenAsMissing,omitempty"`
+	// MaxThresholds caps the candidate pivots per numeric attribute at each
+	// node to that many sample quantiles. 0 evaluates every distinct value.
+	MaxThresholds int `json:"maxThresholds,omitempty"`
+	// MaxCategories, when positive, skips categorical splits on attributes
+	// with more distinct non-numeric values in the training set than this,
+	// such as ID-like columns, which would otherwise cost one candidate
+	// split per value at every node. Skipped attributes are listed in
+	// Metadata.Warnings. Ordinal and lexicographic attributes are not
+	// limited. 0 means no limit.
+	MaxCategories int `json:"maxCategories,omitempty"`
+	// HistogramBins, when at least 2, bins each numeric attribute once
+	// before training into that many quantile bins and only tries pivots at
+	// bin boundaries, scoring them from per-bin class histograms. This makes
+	// split search on large numeric data much cheaper at a small cost in
+	// accuracy. It takes precedence over MaxThresholds. 0 disables it.
+	HistogramBins int `json:"histogramBins,omitempty"`
+	// RandomSplits scores a single threshold per numeric attribute at each
+	// node, drawn uniformly from the attribute's range there with Seed,
+	// instead of every pivot (the Extra-Trees strategy). Trees train faster
+	// and vary more between seeds, which suits forests. Histogram-binned,
+	// ordinal and lexicographic attributes keep their usual search.
+	RandomSplits bool `json:"randomSplits,omitempty"`
+	// WeightAttr names a numeric, non-negative attribute holding each item's
+	// sample weight. It is never split on; items without it weigh 1. Weights
+	// drive split selection and leaf classes, while ClassCounts stay raw counts.
+	WeightAttr string `json:"weightAttr,omitempty"`
+	// MultiwaySplits makes categorical splits branch into one child per
+	// distinct value instead of a binary ==/!= pair.
+	MultiwaySplits bool `json:"multiwaySplits,omitempty"`
+	// SubsetSplits also considers splitting a categorical attribute with 3
+	// to 32 distinct values at a node on a group of values (value in S vs
+	// not), which a single == split cannot express. Values are ordered by
+	// their share of the node's majority class and each prefix of that
+	// order is tried as S, so the search stays linear in the number of
+	// values. Ignored with MultiwaySplits.
+	SubsetSplits bool `json:"subsetSplits,omitempty"`
+	// AllowObliqueSplits also considers splits on a linear combination of
+	// two numeric attributes, a*x + b*y >= t, trying a fixed set of directions
+	// per attribute pair. They can fit diagonal boundaries with far fewer
+	// nodes than axis-aligned splits, at extra training cost.
+	AllowObliqueSplits bool `json:"allowObliqueSplits,omitempty"`
+	// MonotoneConstraints maps numeric attributes to +1 (the positive class
+	// probability may not decrease as the value grows), -1 (may not increase)
+	// or 0 (unconstrained). The positive class is the lexicographically largest
+	// label. Constraints on non-numeric attributes are ignored.
+	MonotoneConstraints map[string]int `json:"monotoneConstraints,omitempty"`
+	// OrdinalFeatures maps categorical attributes to their levels in
+	// ascending order, e.g. {"size": {"low", "medium", "high"}}. Such
+	// attributes get ">=" splits on the level order, with a level as the
+	// pivot, instead of "==" splits. Values that are not listed levels are
+	// treated as missing.
+	OrdinalFeatures map[string][]string `json:"ordinalFeatures,omitempty"`
+	// LexicographicFeatures lists string attributes that get ">=" splits on
+	// byte-wise string order, with a value as the pivot, instead of "=="
+	// splits; useful for codes and timestamps that sort as text. Note that
+	// "1.2.10" sorts before "1.2.9". Values that are not strings are treated
+	// as missing. An attribute cannot be both ordinal and lexicographic.
+	LexicographicFeatures []string `json:"lexicographicFeatures,omitempty"`
+	// ChiSquarePValue, when positive, pre-prunes the tree: the best split
+	// at a node is kept only if a chi-square test of independence between
+	// its branches and the class, on their class counts, gives a p-value
+	// below this significance level (e.g. 0.05); otherwise the node becomes
+	// a leaf. 0 disables the test.
+	ChiSquarePValue float64 `json:"chiSquarePValue,omitempty"`
+	// LaplaceAlpha applies additive smoothing in PredictProba so every class
+	// the model knows gets a non-zero probability. 0 disables smoothing.
+	LaplaceAlpha float64 `json:"laplaceAlpha,omitempty"`
+	// LeafStrategy selects the class Predict returns at a leaf:
+	// LeafMajority (default), the most frequent class with ties going to
+	// the smallest class name, or LeafProportional, a class drawn at random
+	// with probability proportional to the leaf's ClassCounts, for
+	// simulation. Draws come from one generator per model seeded with
+	// Seed, so a fresh model replays the same sequence of draws.
+	// PredictProba is unaffected.
+	LeafStrategy string `json:"leafStrategy,omitempty"`
+	// DefaultClass is what Predict returns when the node it reaches has no
+	// ClassCounts or would predict the empty class, as an empty leaf left
+	// by a degenerate split does. When unset such nodes predict "".
+	DefaultClass string `json:"defaultClass,omitempty"`
+	// Observer, if set, is notified as training progresses. It is not saved
+	// with the model and is cleared from the trained model's Config.
+	Observer Observer `json:"-"`
+	// Verbose logs one line per node as the tree grows: the chosen split and
+	// its gain, or why the node became a leaf (pure node, max depth, min
+	// samples, no gain or not significant). Like Observer it is not saved
+	// with the model.
+	Verbose bool `json:"-"`
+	// LogOutput receives Verbose logs. nil means os.Stderr.
+	LogOutput io.Writer `json:"-"`
+}
+
+// Observer receives training callbacks, for profiling or progress reporting.
+// Callbacks run synchronously on the training goroutine and should be cheap.
+type Observer interface {
+	// OnNodeCreated is called once for every node of the tree, parents
+	// before children, with the node's depth (0 for the root) and the number
+	// of training samples reaching it.
+	OnNodeCreated(depth int, samples int)
+	// OnSplitEvaluated is called each time a candidate split on attr is
+	// scored. For oblique splits attr is the comma-separated attribute pair,
+	// reported once per direction tried.
+	OnSplitEvaluated(attr string)
 }
 
+// Split criteria for Config.Criterion.
+const (
+	// CriterionEntropy scores splits by information gain (Shannon entropy).
+	CriterionEntropy = "entropy"
+	// CriterionGini scores splits by decrease in Gini impurity.
+	CriterionGini = "gini"
+)
+
+// Tie-break modes for Config.TieBreak.
+const (
+	// TieBreakDeterministic prefers the smallest attribute, then pivot.
+	TieBreakDeterministic = "deterministic"
+	// TieBreakRandom picks among equal splits at random, seeded by Config.Seed.
+	TieBreakRandom = "random"
+)
+
+// Leaf prediction strategies for Config.LeafStrategy.
+const (
+	// LeafMajority predicts the most frequent class at the leaf.
+	LeafMajority = "majority"
+	// LeafProportional draws the class in proportion to the leaf's counts.
+	LeafProportional = "proportional"
+)
+
+// Missing-value strategies for Config.MissingStrategy.
+const (
+	// MissingMajority follows the child that received more training samples.
+	MissingMajority = "majority"
+	// MissingMatch always follows the Match child.
+	MissingMatch = "match"
+	// MissingNoMatch always follows the NoMatch child.
+	MissingNoMatch = "nomatch"
+	// MissingFail makes prediction return an error.
+	MissingFail = "fail"
+)
+
 // Model wraps a trained tree and training configuration.
 type Model struct {
-	Root   *TreeItem `json:"root"`
-	Config Config    `json:"config"`
+	Root *TreeItem `json:"root"`
+	// Config is the configuration the tree was trained with, defaults
+	// resolved.
+	Config Config `json:"config"`
+	// Metadata records training provenance. It is nil for models saved
+	// before metadata was introduced.
+	Metadata *Metadata `json:"metadata,omitempty"`
+
+	// classes caches the class universe collected from the leaves.
+	classesOnce sync.Once
+	classes     []string
+
+	// drawRng serves LeafProportional draws; it is created on first use and
+	// guarded by drawMu.
+	drawMu  sync.Mutex
+	drawRng *rand.Rand
+}
+
+// Metadata describes how and on what data a model was trained.
+type Metadata struct {
+	// FeatureNames lists, sorted, every attribute seen in training except the
+	// label and weight attributes.
+	FeatureNames []string `json:"featureNames,omitempty"`
+	// TrainedAt is when training finished (UTC).
+	TrainedAt time.Time `json:"trainedAt"`
+	// LibVersion is the library Version that trained the model.
+	LibVersion string `json:"libVersion,omitempty"`
+	// NumSamples is the number of training items.
+	NumSamples int `json:"numSamples"`
+	// Classes lists, sorted, every label seen in training.
+	Classes []string `json:"classes,omitempty"`
+	// Warnings lists training diagnostics that did not stop training, such
+	// as features that can never be split on.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ClassProba is one class's probability, as returned by PredictProbaOrdered.
+type ClassProba struct {
+	Class string  `json:"class"`
+	Proba float64 `json:"proba"`
+}
+
+// SplitInfo describes the split Train would make at the root, as returned
+// by BestSplit.
+type SplitInfo struct {
+	// Attribute is the split attribute; empty for oblique splits.
+	Attribute string
+	// PredicateName is ">=", "==", "in" for multiway splits, "subset" or
+	// "oblique".
+	PredicateName string
+	// Pivot is the value compared against; nil for multiway, subset and
+	// oblique splits.
+	Pivot interface{}
+	// PivotSet holds the values sent to Match by a subset split.
+	PivotSet []string
+	// Oblique is set instead of Attribute and Pivot for oblique splits.
+	Oblique *ObliqueSplit
+	// Gain is the impurity decrease, in the units of Config.Criterion.
+	Gain float64
+	// MatchCount and NoMatchCount are the partition sizes of a binary split.
+	MatchCount   int
+	NoMatchCount int
+	// GroupCounts maps each branch of a multiway split to its size, and
+	// MissingCount is the size of its branch for missing values.
+	GroupCounts  map[string]int
+	MissingCount int
 }
 
 // ModelStats contains statistics about a trained model.
 type ModelStats struct {
 	// TreeDepth is the maximum depth of the tree (distance from root to deepest leaf)
-	TreeDepth int
+	TreeDepth int `json:"treeDepth"`
 	// TotalNodes is the total number of nodes (internal + leaf)
-	TotalNodes int
+	TotalNodes int `json:"totalNodes"`
 	// LeafNodes is the number of leaf nodes
-	LeafNodes int
+	LeafNodes int `json:"leafNodes"`
 	// InternalNodes is the number of internal (decision) nodes
-	InternalNodes int
+	InternalNodes int `json:"internalNodes"`
 	// Classes is the set of unique class labels found in leaf nodes
-	Classes []string
+	Classes []string `json:"classes"`
+	// NodesPerDepth[d] is the number of nodes at depth d (the root is at 0)
+	NodesPerDepth []int `json:"nodesPerDepth,omitempty"`
+	// LeavesPerDepth[d] is the number of leaves at depth d
+	LeavesPerDepth []int `json:"leavesPerDepth,omitempty"`
 }
 
 // Predicate compares an item's value against the pivot, returning true to go to Match branch.
@@ -46,14 +311,30 @@ type Predicate func(interface{}, interface{}) bool
 
 // TreeItem is a node in the decision tree.
 type TreeItem struct {
+	// ID identifies the node within its tree; see Model.AssignIDs.
+	ID int `json:"id,omitempty"`
+
 	// Tree structure
 	Match   *TreeItem `json:"match,omitempty"`
 	NoMatch *TreeItem `json:"noMatch,omitempty"`
+	// Children holds one subtree per category value for multiway splits
+	// (PredicateName "in"); Match and NoMatch are unused on such nodes.
+	Children map[string]*TreeItem `json:"children,omitempty"`
+	// Missing is the subtree of a multiway node for items whose value is
+	// absent, nil, NaN or infinite, when training saw such items there. It
+	// is kept apart from Children so no real value can be mistaken for it.
+	Missing *TreeItem `json:"missing,omitempty"`
+	// Oblique is set on nodes that split on a linear combination of
+	// attributes (PredicateName "oblique"); Attribute and Pivot are unused.
+	Oblique *ObliqueSplit `json:"oblique,omitempty"`
 
 	// Predicted category at leaf (most frequent label)
 	Category string `json:"category,omitempty"`
 	// ClassCounts at leaf for probability output
 	ClassCounts map[string]int `json:"classCounts,omitempty"`
+	// OutputCounts holds the class counts of every label of a multi-output
+	// model (see Config.CategoryAttrs), keyed by label attribute.
+	OutputCounts map[string]map[string]int `json:"outputCounts,omitempty"`
 
 	// Split metadata
 	MatchedCount   int         `json:"matchedCount,omitempty"`
@@ -61,4 +342,93 @@ type TreeItem struct {
 	Attribute      string      `json:"attribute,omitempty"`
 	PredicateName  string      `json:"predicateName,omitempty"`
 	Pivot          interface{} `json:"pivot,omitempty"`
+	// PivotSet holds, sorted, the categorical values that go to Match on a
+	// subset split (PredicateName "subset"); Pivot is unused on such nodes.
+	PivotSet []string `json:"pivotSet,omitempty"`
+	// Categories holds, sorted, the categorical values of Attribute that
+	// reached an == or subset node in training; set with
+	// Config.UnseenAsMissing.
+	Categories []string `json:"categories,omitempty"`
+	// Gain is the impurity decrease of the split chosen at an internal
+	// node, in the units of Config.Criterion, as measured on the training
+	// samples reaching it (weighted if WeightAttr is set). It is 0 on
+	// leaves and in models saved before it was recorded.
+	Gain float64 `json:"gain,omitempty"`
+}
+
+// ObliqueSplit sends an item to Match when
+// sum(Weights[i] * item[Attributes[i]]) >= Threshold.
+type ObliqueSplit struct {
+	Attributes []string  `json:"attributes"`
+	Weights    []float64 `json:"weights"`
+	Threshold  float64   `json:"threshold"`
+}
+
+// branch is a child node together with the label of the edge leading to it.
+type branch struct {
+	label string
+	node  *TreeItem
+}
+
+// isLeaf reports whether n has no children; labels may be empty strings,
+// so leaf detection is structural only.
+func (n *TreeItem) isLeaf() bool {
+	return n.Match == nil && n.NoMatch == nil && len(n.Children) == 0
+}
+
+// missingBranch labels the Missing child of a multiway node in branches.
+const missingBranch = "(missing)"
+
+// branches returns the non-nil children of n in a stable order: "yes" then
+// "no" for binary nodes, or sorted by category value for multiway nodes,
+// followed by the Missing child.
+func (n *TreeItem) branches() []branch {
+	if len(n.Children) > 0 {
+		keys := make([]string, 0, len(n.Children))
+		for k := range n.Children {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make([]branch, 0, len(keys))
+		for _, k := range keys {
+			if n.Children[k] != nil {
+				out = append(out, branch{k, n.Children[k]})
+			}
+		}
+		if n.Missing != nil {
+			out = append(out, branch{missingBranch, n.Missing})
+		}
+		return out
+	}
+	var out []branch
+	if n.Match != nil {
+		out = append(out, branch{"yes", n.Match})
+	}
+	if n.NoMatch != nil {
+		out = append(out, branch{"no", n.NoMatch})
+	}
+	return out
+}
+
+// condition renders the split of an internal node for display, e.g.
+// "Humidity >= 75" or "Color in {blue, red}". Multiway nodes show just
+// their attribute.
+func (n *TreeItem) condition() string {
+	switch {
+	case n.Oblique != nil:
+		return n.Oblique.String()
+	case len(n.Children) > 0:
+		return n.Attribute
+	case n.PredicateName == "subset":
+		return n.Attribute + " in {" + strings.Join(n.PivotSet, ", ") + "}"
+	}
+	return n.Attribute + " " + n.PredicateName + " " + formatValue(n.Pivot)
+}
+
+// attributes returns the attributes an internal node splits on.
+func (n *TreeItem) attributes() []string {
+	if n.Oblique != nil {
+		return n.Oblique.Attributes
+	}
+	return []string{n.Attribute}
 }
@@ -1,9 +1,13 @@
 package dtree
 
 import (
+	"bytes"
 	"fmt"
 	"html/template"
 	"os"
+	"os/exec"
+	"sort"
+	"strings"
 )
 
 const enhancedHTMLTemplate = `<html>
@@ -209,6 +213,38 @@ const enhancedHTMLTemplate = `<html>
     line-height: 1.4;
   }
   
+  /* Collapsible subtrees */
+  .tree li .node.toggle::after {
+    content: ' \25BE';
+    color: #718096;
+  }
+
+  .tree li.collapsed > .node.toggle::after { content: ' \25B8'; }
+  .tree li.collapsed > ul { display: none; }
+
+  .counts-panel {
+    position: fixed;
+    bottom: 20px;
+    right: 20px;
+    background: white;
+    border-radius: 8px;
+    padding: 15px;
+    box-shadow: 0 4px 12px rgba(0,0,0,0.15);
+    min-width: 250px;
+    font-size: 12px;
+    color: #4a5568;
+    display: none;
+    z-index: 1000;
+  }
+
+  .counts-panel.visible { display: block; }
+
+  .counts-panel h3 {
+    margin: 0 0 10px 0;
+    color: #2d3748;
+    font-size: 14px;
+  }
+
   .title {
     text-align: center;
     margin-bottom: 30px;
@@ -233,6 +269,11 @@ const enhancedHTMLTemplate = `<html>
   </div>
 </div>
 
+<div class="counts-panel" id="counts-panel">
+  <h3>Class Counts</h3>
+  <div id="counts-info"></div>
+</div>
+
 <script>
 document.addEventListener('DOMContentLoaded', function() {
   const tree = document.getElementById('tree');
@@ -260,7 +301,25 @@ document.addEventListener('DOMContentLoaded', function() {
     // Add hover listeners
     node.addEventListener('mouseenter', () => highlightPath(id));
     node.addEventListener('mouseleave', clearHighlight);
+    node.addEventListener('click', event => toggleNode(event, node));
   });
+
+  const countsPanel = document.getElementById('counts-panel');
+  const countsInfo = document.getElementById('counts-info');
+
+  // Clicking a split folds or unfolds its subtree; clicking any tree node
+  // shows its training class counts.
+  function toggleNode(event, node) {
+    event.preventDefault();
+    if (node.classList.contains('toggle')) {
+      node.parentNode.classList.toggle('collapsed');
+    }
+    const counts = node.getAttribute('data-counts');
+    if (counts !== null) {
+      countsInfo.textContent = node.textContent.trim() + ': ' + counts;
+      countsPanel.classList.add('visible');
+    }
+  }
   
   function highlightPath(nodeId) {
     clearHighlight();
@@ -346,7 +405,10 @@ document.addEventListener('DOMContentLoaded', function() {
 </body>
 </html>`
 
-// ToHTML writes an enhanced interactive HTML rendering of the tree with path highlighting.
+// ToHTML writes an enhanced interactive HTML rendering of the tree with path
+// highlighting, as a single self-contained file. Clicking a split collapses
+// or expands its subtree, and clicking any node shows its class counts,
+// which each node carries in a data-counts attribute.
 func (m *Model) ToHTML(path string) error {
 	tmpl, err := template.New("tree").Parse(enhancedHTMLTemplate)
 	if err != nil {
@@ -366,17 +428,34 @@ func enhancedTreeToHTML(node *TreeItem) string {
 		return ""
 	}
 
-	if node.Category != "" && node.Match == nil && node.NoMatch == nil {
+	if node.Category != "" && node.isLeaf() {
 		// Leaf node
-		return `<ul><li><a href="#" class="node leaf"><b>` + node.Category + `</b></a></li></ul>`
+		return `<ul><li><a href="#" class="node leaf"` + htmlCounts(node) + `><b>` + template.HTMLEscapeString(node.Category) + `</b></a></li></ul>`
+	}
+
+	if len(node.Children) > 0 {
+		items := ""
+		for _, b := range node.branches() {
+			items += `
+          <li>
+            <div class="branch-label">` + template.HTMLEscapeString(b.label) + `</div>` + enhancedTreeToHTML(b.node) + `
+          </li>`
+		}
+		return `<ul>
+      <li>
+        <a href="#" class="node toggle"` + htmlCounts(node) + `><b>` + template.HTMLEscapeString(node.Attribute) + `</b></a>
+        <ul>` + items + `
+        </ul>
+      </li>
+    </ul>`
 	}
 
 	// Internal node with enhanced structure
-	condition := fmt.Sprintf("%s %s %v", node.Attribute, node.PredicateName, node.Pivot)
+	condition := template.HTMLEscapeString(node.condition())
 
 	return `<ul>
       <li>
-        <a href="#" class="node"><b>` + condition + `</b></a>
+        <a href="#" class="node toggle"` + htmlCounts(node) + `><b>` + condition + `</b></a>
         <ul>
           <li>
             <div class="branch-label branch-yes">yes</div>
@@ -391,6 +470,12 @@ func enhancedTreeToHTML(node *TreeItem) string {
     </ul>`
 }
 
+// htmlCounts renders node's class counts as a data-counts attribute for the
+// ToHTML script, e.g. ` data-counts="(no=2, yes=3)"`.
+func htmlCounts(node *TreeItem) string {
+	return ` data-counts="` + template.HTMLEscapeString(formatCounts(node.ClassCounts)) + `"`
+}
+
 // ToDOT writes a Graphviz DOT representation.
 func (m *Model) ToDOT() string {
 	b := &dotBuilder{next: 0}
@@ -401,6 +486,32 @@ func (m *Model) ToDOT() string {
 	return b.buf
 }
 
+// RenderDOT renders the ToDOT graph to an image at path by running the
+// Graphviz dot program, which must be on the PATH. format is png, svg or
+// pdf. ToSVG draws an SVG without Graphviz.
+func (m *Model) RenderDOT(path, format string) error {
+	switch format {
+	case "png", "svg", "pdf":
+	default:
+		return fmt.Errorf("unsupported render format %q (must be png, svg or pdf)", format)
+	}
+	dot, err := exec.LookPath("dot")
+	if err != nil {
+		return fmt.Errorf("graphviz dot not found on PATH; install Graphviz from https://graphviz.org/download/: %w", err)
+	}
+	cmd := exec.Command(dot, "-T"+format, "-o", path)
+	cmd.Stdin = strings.NewReader(m.ToDOT())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("dot failed: %w: %s", err, msg)
+		}
+		return fmt.Errorf("dot failed: %w", err)
+	}
+	return nil
+}
+
 type dotBuilder struct {
 	next int
 	buf  string
@@ -414,18 +525,116 @@ func (d *dotBuilder) walk(n *TreeItem) int {
 		return -1
 	}
 	id := d.id()
-	if n.Category != "" && n.Match == nil && n.NoMatch == nil {
+	if n.Category != "" && n.isLeaf() {
 		d.line(fmt.Sprintf("  n%d [label=\"%s\", shape=oval];", id, n.Category))
 		return id
 	}
-	d.line(fmt.Sprintf("  n%d [label=\"%s %s %v\"];", id, n.Attribute, n.PredicateName, n.Pivot))
-	lm := d.walk(n.Match)
-	ln := d.walk(n.NoMatch)
-	if lm != -1 {
-		d.line(fmt.Sprintf("  n%d -> n%d [label=\"yes\"];", id, lm))
-	}
-	if ln != -1 {
-		d.line(fmt.Sprintf("  n%d -> n%d [label=\"no\"];", id, ln))
+	d.line(fmt.Sprintf("  n%d [label=\"%s\"];", id, n.condition()))
+	for _, b := range n.branches() {
+		d.line(fmt.Sprintf("  n%d -> n%d [label=\"%s\"];", id, d.walk(b.node), b.label))
 	}
 	return id
 }
+
+// ToNewick serializes the tree in Newick notation, terminated by ";".
+// Internal nodes are labeled with their split condition and leaves with their
+// category; children follow branches() order, so "yes" precedes "no". Labels
+// containing whitespace or Newick punctuation are single-quoted.
+func (m *Model) ToNewick() string {
+	if m == nil || m.Root == nil {
+		return ";"
+	}
+	var b strings.Builder
+	writeNewick(&b, m.Root)
+	b.WriteByte(';')
+	return b.String()
+}
+
+func writeNewick(b *strings.Builder, n *TreeItem) {
+	if n.isLeaf() {
+		b.WriteString(newickLabel(n.Category))
+		return
+	}
+	b.WriteByte('(')
+	for i, c := range n.branches() {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		writeNewick(b, c.node)
+	}
+	b.WriteByte(')')
+	b.WriteString(newickLabel(n.condition()))
+}
+
+// newickLabel quotes s if it contains characters special to Newick, doubling
+// any embedded single quotes.
+func newickLabel(s string) string {
+	if !strings.ContainsAny(s, "()[],:;' \t\n") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// ToText renders the tree as indented text with Unicode branch connectors,
+// one line per node. Leaves show their category and class counts.
+func (m *Model) ToText() string {
+	if m == nil || m.Root == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(textLabel(m.Root))
+	b.WriteByte('\n')
+	writeTextChildren(&b, m.Root, "")
+	return b.String()
+}
+
+// String implements fmt.Stringer using ToText.
+func (m *Model) String() string { return m.ToText() }
+
+func writeTextChildren(b *strings.Builder, n *TreeItem, prefix string) {
+	children := n.branches()
+	for i, c := range children {
+		connector, indent := "├─ ", "│  "
+		if i == len(children)-1 {
+			connector, indent = "└─ ", "   "
+		}
+		b.WriteString(prefix)
+		b.WriteString(connector)
+		b.WriteString(c.label)
+		b.WriteString(": ")
+		b.WriteString(textLabel(c.node))
+		b.WriteByte('\n')
+		writeTextChildren(b, c.node, prefix+indent)
+	}
+}
+
+// textLabel describes a single node: the split condition or the leaf outcome.
+func textLabel(n *TreeItem) string {
+	if n.isLeaf() {
+		return fmt.Sprintf("%s %s", n.Category, formatCounts(n.ClassCounts))
+	}
+	return n.condition()
+}
+
+// formatValue renders a pivot or label for display, formatting numbers the
+// same way class labels are (75, not 75.0 or 7.5e+01).
+func formatValue(v interface{}) string {
+	if isNumeric(v) {
+		return valueKey(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// formatCounts renders class counts in key order, e.g. "(no=2, yes=3)".
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%d", k, counts[k])
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
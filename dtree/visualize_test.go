@@ -0,0 +1,164 @@
+package dtree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// playTennisSet is the PlayTennis subset shared by several tests.
+func playTennisSet() TrainingSet {
+	return TrainingSet{
+		TrainingItem{"Outlook": "sunny", "Temperature": 85.0, "Humidity": 85.0, "Wind": false, "Play": "no"},
+		TrainingItem{"Outlook": "sunny", "Temperature": 80.0, "Humidity": 90.0, "Wind": true, "Play": "no"},
+		TrainingItem{"Outlook": "overcast", "Temperature": 83.0, "Humidity": 86.0, "Wind": false, "Play": "yes"},
+		TrainingItem{"Outlook": "rain", "Temperature": 70.0, "Humidity": 96.0, "Wind": false, "Play": "yes"},
+		TrainingItem{"Outlook": "rain", "Temperature": 68.0, "Humidity": 80.0, "Wind": false, "Play": "yes"},
+		TrainingItem{"Outlook": "rain", "Temperature": 65.0, "Humidity": 70.0, "Wind": true, "Play": "no"},
+		TrainingItem{"Outlook": "overcast", "Temperature": 64.0, "Humidity": 65.0, "Wind": true, "Play": "yes"},
+	}
+}
+
+func TestToText_OneLinePerNode(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	text := model.ToText()
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if got, want := len(lines), model.Stats().TotalNodes; got != want {
+		t.Fatalf("expected %d lines (one per node), got %d:\n%s", want, got, text)
+	}
+	if !strings.Contains(text, "└─ ") {
+		t.Errorf("expected branch connectors in output:\n%s", text)
+	}
+	if model.String() != text {
+		t.Error("String should match ToText")
+	}
+}
+
+func TestToText_SingleLeaf(t *testing.T) {
+	model, _ := Train(TrainingSet{
+		TrainingItem{"x": 1.0, "label": "A"},
+		TrainingItem{"x": 2.0, "label": "A"},
+	}, Config{CategoryAttr: "label"})
+	if got, want := model.ToText(), "A (A=2)\n"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	var nilModel *Model
+	if nilModel.ToText() != "" {
+		t.Error("expected empty text for nil model")
+	}
+}
+
+// newickBalanced reports whether the parentheses outside quoted labels in s
+// balance, and the text ends with ";".
+func newickBalanced(s string) bool {
+	depth, quoted := 0, false
+	for _, r := range s {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0 && !quoted && strings.HasSuffix(s, ";")
+}
+
+func TestToNewick(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	nw := model.ToNewick()
+	if !newickBalanced(nw) {
+		t.Fatalf("unbalanced Newick output: %s", nw)
+	}
+	for _, c := range model.Classes() {
+		if !strings.Contains(nw, c) {
+			t.Errorf("Newick output is missing leaf category %q: %s", c, nw)
+		}
+	}
+	if !strings.Contains(nw, "'"+model.Root.condition()+"';") {
+		t.Errorf("root should be labeled with its quoted condition: %s", nw)
+	}
+}
+
+func TestToNewick_Escaping(t *testing.T) {
+	model := &Model{Root: &TreeItem{
+		Attribute:     "size",
+		PredicateName: "==",
+		Pivot:         "big",
+		Match:         &TreeItem{Category: "a,b (c)"},
+		NoMatch:       &TreeItem{Category: "it's"},
+	}}
+	want := "('a,b (c)','it''s')'size == big';"
+	if got := model.ToNewick(); got != want {
+		t.Fatalf("ToNewick() = %s, want %s", got, want)
+	}
+	if !newickBalanced(want) {
+		t.Fatal("newickBalanced rejects a valid quoted tree")
+	}
+}
+
+func TestToHTML_Collapsible(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "tree.html")
+	if err := model.ToHTML(path); err != nil {
+		t.Fatalf("ToHTML failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	for _, want := range []string{"function toggleNode", "classList.toggle('collapsed')", `id="counts-panel"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML lacks %q", want)
+		}
+	}
+	stats := model.Stats()
+	if got := strings.Count(html, "data-counts="); got != stats.TotalNodes {
+		t.Errorf("got %d data-counts attributes, want one per node (%d)", got, stats.TotalNodes)
+	}
+	if got := strings.Count(html, `class="node toggle"`); got != stats.InternalNodes {
+		t.Errorf("got %d toggles, want one per split (%d)", got, stats.InternalNodes)
+	}
+	if want := `data-counts="` + "(no=3, yes=4)" + `"`; !strings.Contains(html, want) {
+		t.Errorf("HTML lacks the root counts %s", want)
+	}
+}
+
+func TestRenderDOT(t *testing.T) {
+	model, err := Train(playTennisSet(), Config{CategoryAttr: "Play"})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if err := model.RenderDOT(filepath.Join(t.TempDir(), "tree.gif"), "gif"); err == nil {
+		t.Error("expected error for an unsupported format")
+	}
+	if _, err := exec.LookPath("dot"); err != nil {
+		t.Skip("graphviz dot not installed")
+	}
+	for _, format := range []string{"png", "svg", "pdf"} {
+		path := filepath.Join(t.TempDir(), "tree."+format)
+		if err := model.RenderDOT(path, format); err != nil {
+			t.Fatalf("RenderDOT(%s) failed: %v", format, err)
+		}
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("%s output is missing or empty: %v", format, err)
+		}
+	}
+}